@@ -0,0 +1,56 @@
+package benchmarks
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"dmarro89.github.com/hnsw-go/dataset"
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// BenchmarkDatasetConstruction costruisce l'indice a partire da un dataset
+// reale su disco (.fvecs, .bvecs o .npy), ad esempio SIFT o GloVe.
+//
+// Il file si indica con la variabile di ambiente HNSW_DATASET_BASE; senza di
+// essa il benchmark viene saltato. HNSW_DATASET_LIMIT limita il numero di
+// vettori inseriti.
+func BenchmarkDatasetConstruction(b *testing.B) {
+	path := os.Getenv("HNSW_DATASET_BASE")
+	if path == "" {
+		b.Skip("HNSW_DATASET_BASE non impostata")
+	}
+
+	limit := 0
+	if limitStr := os.Getenv("HNSW_DATASET_LIMIT"); limitStr != "" {
+		val, err := strconv.Atoi(limitStr)
+		if err != nil {
+			b.Fatalf("HNSW_DATASET_LIMIT non valida: %v", err)
+		}
+		limit = val
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h, err := hnsw.NewHNSW(hnsw.Config{
+			M:              16,
+			Mmax:           16,
+			Mmax0:          32,
+			EfConstruction: 100,
+			MaxLevel:       16,
+			DistanceFunc:   hnsw.EuclideanDistance,
+		})
+		if err != nil {
+			b.Fatalf("Errore nella creazione dell'indice HNSW: %v", err)
+		}
+
+		// I vettori vengono letti dal file mentre vengono inseriti
+		start := time.Now()
+		n, err := dataset.InsertFile(h, path, limit)
+		if err != nil {
+			b.Fatalf("Errore nella lettura del dataset: %v", err)
+		}
+		b.ReportMetric(float64(n)/time.Since(start).Seconds(), "vectors/sec")
+	}
+}
@@ -0,0 +1,59 @@
+package benchmarks
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// BenchmarkKNNSearch misura la latenza di KNN_Search su un indice già
+// costruito, con una sola goroutine e con GOMAXPROCS goroutine in parallelo.
+func BenchmarkKNNSearch(b *testing.B) {
+	const (
+		numVecs    = 10000
+		numQueries = 1000
+		dimension  = 128
+		k          = 10
+		ef         = 100
+	)
+
+	rng := rand.New(rand.NewPCG(42, 42))
+	vectors := generateRandomVectorsWithRNG(numVecs, dimension, rng)
+	queries := generateRandomVectorsWithRNG(numQueries, dimension, rng)
+
+	index, err := hnsw.NewHNSW(hnsw.Config{
+		M:              16,
+		Mmax:           16,
+		Mmax0:          32,
+		EfConstruction: 100,
+		MaxLevel:       16,
+		DistanceFunc:   hnsw.EuclideanDistance,
+	})
+	if err != nil {
+		b.Fatalf("NewHNSW: %v", err)
+	}
+	index.RandFunc = rng.Float64
+	for i, v := range vectors {
+		index.Insert(v, i)
+	}
+
+	b.Run(fmt.Sprintf("serial_%dv_%dd", numVecs, dimension), func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			index.KNN_Search(queries[i%numQueries], k, ef)
+		}
+	})
+
+	b.Run(fmt.Sprintf("parallel_%dv_%dd", numVecs, dimension), func(b *testing.B) {
+		b.ReportAllocs()
+		var next atomic.Int64
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				index.KNN_Search(queries[next.Add(1)%numQueries], k, ef)
+			}
+		})
+	})
+}
@@ -0,0 +1,87 @@
+package benchmarks
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"testing"
+	"time"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// BenchmarkNodeStorage confronta l'allocazione per-nodo (structs.NewNode)
+// con l'allocazione da arena (structs.Storage), riportando dimensione
+// dell'heap, numero di oggetti e durata delle pause del GC.
+func BenchmarkNodeStorage(b *testing.B) {
+	const (
+		numNodes  = 200000
+		dimension = 128
+		mMax      = 16
+		mMax0     = 32
+	)
+
+	rng := rand.New(rand.NewPCG(42, 42))
+	vector := make([]float32, dimension)
+	for i := range vector {
+		vector[i] = rng.Float32()
+	}
+
+	// Livelli generati con la stessa distribuzione usata dall'indice
+	levels := make([]int, numNodes)
+	for i := range levels {
+		for rng.Float64() < 1.0/mMax {
+			levels[i]++
+		}
+	}
+
+	layouts := []struct {
+		name  string
+		build func() []*structs.Node
+	}{
+		{"per-node", func() []*structs.Node {
+			nodes := make([]*structs.Node, numNodes)
+			for i := range nodes {
+				v := make([]float32, dimension)
+				copy(v, vector)
+				nodes[i] = structs.NewNode(i, v, levels[i], 16, mMax, mMax0)
+			}
+			return nodes
+		}},
+		{"arena", func() []*structs.Node {
+			storage := structs.NewStorage(mMax, mMax0)
+			nodes := make([]*structs.Node, numNodes)
+			for i := range nodes {
+				nodes[i] = storage.NewNode(i, vector, levels[i])
+			}
+			return nodes
+		}},
+	}
+
+	for _, layout := range layouts {
+		b.Run(fmt.Sprintf("%s_%dn_%dd", layout.name, numNodes, dimension), func(b *testing.B) {
+			var before, after runtime.MemStats
+			var nodes []*structs.Node
+
+			for i := 0; i < b.N; i++ {
+				nodes = nil
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				nodes = layout.build()
+
+				// Misura il costo di un ciclo completo di GC con i nodi vivi
+				start := time.Now()
+				runtime.GC()
+				gcTime := time.Since(start)
+				runtime.ReadMemStats(&after)
+
+				b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/(1<<20), "heap-MB")
+				b.ReportMetric(float64(after.HeapObjects-before.HeapObjects), "heap-objects")
+				b.ReportMetric(float64(gcTime.Microseconds()), "gc-us")
+				b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/1e3, "gc-pause-us")
+			}
+			runtime.KeepAlive(nodes)
+		})
+	}
+}
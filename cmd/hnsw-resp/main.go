@@ -0,0 +1,53 @@
+// Command hnsw-resp serves in-memory vector sets over the Redis protocol,
+// with the vector commands of the resp package.
+//
+// Usage:
+//
+//	hnsw-resp [-addr :6379] [flags]
+//
+// Any Redis client can connect, for example:
+//
+//	redis-cli VADD points p1 0.1 0.2 0.3
+//	redis-cli VSIM points 10 100 0.1 0.2 0.3
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+	"dmarro89.github.com/hnsw-go/resp"
+)
+
+func main() {
+	defaults := hnsw.DefaultConfig()
+	addr := flag.String("addr", ":6379", "address to listen on")
+	m := flag.Int("M", defaults.M, "number of connections established per insertion")
+	efConstruction := flag.Int("ef-construction", defaults.EfConstruction, "size of the candidate list during construction")
+	flag.Parse()
+
+	cfg := hnsw.DefaultConfig()
+	cfg.M, cfg.Mmax, cfg.Mmax0 = *m, 2**m, 4**m
+	cfg.EfConstruction = *efConstruction
+	// Reject an invalid configuration now rather than on the first VADD
+	if _, err := hnsw.NewHNSW(cfg); err != nil {
+		log.Fatalf("hnsw-resp: %v", err)
+	}
+
+	s := resp.NewServer(cfg)
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		log.Printf("hnsw-resp: shutting down")
+		s.Close()
+	}()
+
+	log.Printf("hnsw-resp: listening on %s", *addr)
+	if err := s.ListenAndServe(*addr); err != resp.ErrServerClosed {
+		log.Fatalf("hnsw-resp: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Command hnsw-server serves an HNSW index over HTTP with the JSON API of
+// the server package.
+//
+// Usage:
+//
+//	hnsw-server -index index.hnsw [flags]
+//
+// The index is loaded from -index if the file exists, and created empty
+// otherwise. Index files do not record their metric, so -metric must be the
+// one the index was built with. On SIGINT or SIGTERM the server stops
+// accepting connections, waits for the requests in progress and saves the
+// index back to -index, or to -snapshot if given.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+	"dmarro89.github.com/hnsw-go/metrics"
+	"dmarro89.github.com/hnsw-go/server"
+)
+
+func main() {
+	defaults := hnsw.DefaultConfig()
+	addr := flag.String("addr", ":8080", "address to listen on")
+	index := flag.String("index", "", "index file, created if it does not exist")
+	snapshot := flag.String("snapshot", "", "file the index is saved to on shutdown (default -index)")
+	metric := flag.String("metric", hnsw.MetricL2, "distance metric the index was built with ("+strings.Join(hnsw.MetricNames(), ", ")+")")
+	m := flag.Int("M", defaults.M, "number of connections established per insertion, for a new index")
+	efConstruction := flag.Int("ef-construction", defaults.EfConstruction, "size of the candidate list during construction, for a new index")
+	maxBody := flag.Int64("max-body", server.DefaultMaxBodyBytes, "maximum request body size in bytes")
+	maxBatch := flag.Int("max-batch", server.DefaultMaxBatchSize, "maximum number of vectors in a batch insert")
+	maxK := flag.Int("max-k", server.DefaultMaxK, "maximum K of a search")
+	ef := flag.Int("ef", server.DefaultEf, "ef of searches that do not specify one")
+	serveMetrics := flag.Bool("metrics", true, "serve Prometheus metrics on /metrics")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "time allowed for requests in progress to complete on shutdown")
+	flag.Parse()
+
+	if *index == "" {
+		fmt.Fprintln(os.Stderr, "hnsw-server: -index is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *snapshot == "" {
+		*snapshot = *index
+	}
+
+	distanceFunc, err := hnsw.Metric(*metric)
+	if err != nil {
+		log.Fatalf("hnsw-server: %v", err)
+	}
+	h, err := openIndex(*index, distanceFunc, *m, *efConstruction)
+	if err != nil {
+		log.Fatalf("hnsw-server: %v", err)
+	}
+	log.Printf("hnsw-server: %d vectors loaded from %s", h.Len(), *index)
+
+	opts := server.Options{
+		SnapshotPath: *snapshot,
+		MaxBodyBytes: *maxBody,
+		MaxBatchSize: *maxBatch,
+		MaxK:         *maxK,
+		DefaultEf:    *ef,
+	}
+	if *serveMetrics {
+		exporter := metrics.NewExporter()
+		if err := exporter.Register(filepath.Base(*index), h); err != nil {
+			log.Fatalf("hnsw-server: %v", err)
+		}
+		opts.Metrics = exporter
+	}
+	s := server.New(h, opts)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	done := make(chan error, 1)
+	go func() { done <- s.ListenAndServe(*addr) }()
+	log.Printf("hnsw-server: listening on %s", *addr)
+
+	select {
+	case err := <-done:
+		log.Fatalf("hnsw-server: %v", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("hnsw-server: shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("hnsw-server: %v", err)
+	}
+	if err := <-done; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("hnsw-server: %v", err)
+	}
+	log.Printf("hnsw-server: index saved to %s", *snapshot)
+}
+
+// openIndex loads the index at path, or creates an empty one if the file
+// does not exist
+func openIndex(path string, distanceFunc func([]float32, []float32) float32, m, efConstruction int) (*hnsw.HNSW, error) {
+	h, err := hnsw.LoadFile(path, distanceFunc)
+	if !errors.Is(err, os.ErrNotExist) {
+		return h, err
+	}
+
+	cfg := hnsw.DefaultConfig()
+	cfg.M, cfg.Mmax, cfg.Mmax0 = m, 2*m, 4*m
+	cfg.EfConstruction = efConstruction
+	cfg.DistanceFunc = distanceFunc
+	return hnsw.NewHNSW(cfg)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"dmarro89.github.com/hnsw-go/dataset"
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+func runBuild(args []string, stdout, stderr io.Writer) error {
+	defaults := hnsw.DefaultConfig()
+	fs := newFlagSet("build", "-input vectors.fvecs -output index.hnsw [flags]", stderr)
+	input := fs.String("input", "", "vector file (.fvecs, .bvecs, .npy or .csv)")
+	output := fs.String("output", "", "index file to write (.bin for the hnswlib format)")
+	m := fs.Int("M", defaults.M, "number of connections established per insertion")
+	mmax := fs.Int("mmax", 0, "maximum connections on layers above 0 (default 2*M)")
+	mmax0 := fs.Int("mmax0", 0, "maximum connections on layer 0 (default 4*M)")
+	efConstruction := fs.Int("ef-construction", defaults.EfConstruction, "size of the candidate list during construction")
+	maxLevel := fs.Int("max-level", defaults.MaxLevel, "maximum level of the graph")
+	metric := fs.String("metric", "l2", "distance metric ("+metricNames()+")")
+	limit := fs.Int("limit", 0, "maximum number of vectors to insert (0 for all)")
+	seed := fs.Uint64("seed", 0, "seed of the node levels, for reproducible builds (0 for random)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "input", "output"); err != nil {
+		return err
+	}
+
+	fn, err := distanceFunc(*metric)
+	if err != nil {
+		return err
+	}
+	if *mmax == 0 {
+		*mmax = 2 * *m
+	}
+	if *mmax0 == 0 {
+		*mmax0 = 4 * *m
+	}
+	h, err := hnsw.NewHNSW(hnsw.Config{
+		M:              *m,
+		Mmax:           *mmax,
+		Mmax0:          *mmax0,
+		EfConstruction: *efConstruction,
+		MaxLevel:       *maxLevel,
+		DistanceFunc:   fn,
+		Seed:           *seed,
+	})
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	n, err := dataset.InsertFile(h, *input, *limit)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+
+	if err := saveIndex(h, *output, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "built %s: %d vectors of dimension %d in %v (%.0f vectors/sec)\n",
+		*output, n, h.Dim(), elapsed.Round(time.Millisecond), float64(n)/elapsed.Seconds())
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+func runConvert(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("convert", "-input index.hnsw -output index.bin", stderr)
+	input := fs.String("input", "", "index file to read")
+	output := fs.String("output", "", "index file to write (.bin for the hnswlib format)")
+	metric := fs.String("metric", "l2", "distance metric ("+metricNames()+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "input", "output"); err != nil {
+		return err
+	}
+
+	h, labels, err := loadIndex(*input, *metric)
+	if err != nil {
+		return err
+	}
+
+	// The native format has no labels: nodes keep their IDs only
+	if labels != nil && !isHnswlib(*output) {
+		for id, l := range labels {
+			if l != uint64(id) {
+				fmt.Fprintf(stderr, "hnsw: warning: %s has labels that differ from node IDs; they are not kept in %s\n", *input, *output)
+				break
+			}
+		}
+	}
+
+	if err := saveIndex(h, *output, labels); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "converted %s to %s (%d nodes)\n", *input, *output, len(h.Nodes))
+	return nil
+}
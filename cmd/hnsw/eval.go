@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"dmarro89.github.com/hnsw-go/dataset"
+	"dmarro89.github.com/hnsw-go/eval"
+)
+
+func runEval(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("eval", "-index index.hnsw -queries queries.fvecs [-truth groundtruth.ivecs] [flags]", stderr)
+	index := fs.String("index", "", "index file")
+	queries := fs.String("queries", "", "query vector file (.fvecs, .bvecs, .npy or .csv)")
+	truth := fs.String("truth", "", "ground truth file (.ivecs, .npy or .csv); computed by brute force if empty")
+	k := fs.Int("k", 10, "number of neighbors per query")
+	efList := fs.String("ef", "", "comma-separated ef values (default "+joinInts(eval.DefaultEfValues)+")")
+	metric := fs.String("metric", "l2", "distance metric ("+metricNames()+")")
+	limit := fs.Int("limit", 0, "maximum number of queries (0 for all)")
+	jsonPath := fs.String("json", "", "write the report as JSON to this file")
+	csvPath := fs.String("csv", "", "write the report as CSV to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "index", "queries"); err != nil {
+		return err
+	}
+	efValues, err := parseInts(*efList)
+	if err != nil {
+		return fmt.Errorf("eval: -ef: %v", err)
+	}
+
+	h, labels, err := loadIndex(*index, *metric)
+	if err != nil {
+		return err
+	}
+	vectors, err := dataset.LoadVectors(*queries, *limit)
+	if err != nil {
+		return err
+	}
+
+	opts := eval.Options{K: *k, EfValues: efValues}
+	if *truth != "" {
+		if opts.Truth, err = dataset.LoadGroundTruth(*truth, len(vectors)); err != nil {
+			return err
+		}
+		// Ground truth files refer to the hnswlib labels, not to node IDs
+		if labels != nil {
+			ids := make(map[int]int, len(labels))
+			for id, l := range labels {
+				ids[int(l)] = id
+			}
+			for _, list := range opts.Truth {
+				for i, l := range list {
+					if id, ok := ids[l]; ok {
+						list[i] = id
+					} else {
+						list[i] = -1
+					}
+				}
+			}
+		}
+	}
+
+	report, err := eval.Run(h, vectors, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%d nodes, %d queries, K=%d\n\n", report.Nodes, report.Queries, report.K)
+	fmt.Fprintln(stdout, "ef\trecall\tqps\tp50 µs\tp99 µs\tdistances")
+	for _, r := range report.Results {
+		fmt.Fprintf(stdout, "%d\t%.4f\t%.0f\t%.1f\t%.1f\t%.1f\n",
+			r.Ef, r.Recall, r.QPS, r.LatencyP50, r.LatencyP99, r.DistanceComputations)
+	}
+
+	if *jsonPath != "" {
+		if err := writeReport(*jsonPath, report.WriteJSON); err != nil {
+			return err
+		}
+	}
+	if *csvPath != "" {
+		if err := writeReport(*csvPath, report.WriteCSV); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReport creates the file at path and writes a report into it
+func writeReport(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseInts parses a comma-separated list of positive integers
+func parseInts(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var values []int
+	for _, field := range strings.Split(s, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		if v <= 0 {
+			return nil, fmt.Errorf("%d is not positive", v)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// joinInts formats values as a comma-separated list
+func joinInts(values []int) string {
+	fields := make([]string, len(values))
+	for i, v := range values {
+		fields[i] = strconv.Itoa(v)
+	}
+	return strings.Join(fields, ",")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+func runExport(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("export", "-index index.hnsw -output graph.dot", stderr)
+	index := fs.String("index", "", "index file")
+	output := fs.String("output", "", "graph file (.dot, .gv, .graphml or .csv), or - for stdout")
+	format := fs.String("format", "", "graph format (dot, graphml, csv); default from the -output extension")
+	metric := fs.String("metric", "l2", "distance metric ("+metricNames()+")")
+	level := fs.Int("level", -1, "layer to export, or -1 for every layer")
+	sample := fs.Int("sample", 0, "maximum number of nodes sampled per layer, 0 for all")
+	seed := fs.Uint64("seed", 1, "seed of the sample")
+	distances := fs.Bool("distances", false, "write the length of every edge")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "index", "output"); err != nil {
+		return err
+	}
+
+	f, err := graphFormat(*format, *output)
+	if err != nil {
+		return err
+	}
+	opts := hnsw.ExportOptions{Sample: *sample, Seed: *seed, Distances: *distances}
+	if *level >= 0 {
+		opts.Levels = []int{*level}
+	}
+
+	h, _, err := loadIndex(*index, *metric)
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		return h.Export(stdout, f, opts)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := h.Export(file, f, opts); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "exported %s to %s\n", *index, *output)
+	return nil
+}
+
+// graphFormat returns the format named by the -format flag, or the one of
+// the output file
+func graphFormat(name, output string) (hnsw.GraphFormat, error) {
+	if name == "" {
+		if output == "-" {
+			return 0, fmt.Errorf("export: -format is required when writing to stdout")
+		}
+		return hnsw.GraphFormatOf(output)
+	}
+	for _, f := range []hnsw.GraphFormat{hnsw.DOT, hnsw.GraphML, hnsw.EdgeList} {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("export: unknown format %q", name)
+}
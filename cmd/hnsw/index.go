@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// metricNames returns the accepted metric names, for flag usage
+func metricNames() string {
+	return strings.Join(hnsw.MetricNames(), ", ")
+}
+
+// distanceFunc returns the distance function of a metric
+func distanceFunc(metric string) (func([]float32, []float32) float32, error) {
+	fn, err := hnsw.Metric(metric)
+	if err != nil {
+		return nil, fmt.Errorf("%v (available: %s)", err, metricNames())
+	}
+	return fn, nil
+}
+
+// isHnswlib reports whether an index path uses the hnswlib format
+func isHnswlib(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".bin")
+}
+
+// loadIndex reads an index in the format given by its extension. labels is
+// nil for the native format, which identifies nodes by ID only.
+func loadIndex(path, metric string) (h *hnsw.HNSW, labels []uint64, err error) {
+	fn, err := distanceFunc(metric)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isHnswlib(path) {
+		return hnsw.LoadHnswlibFile(path, fn)
+	}
+	h, err = hnsw.LoadFile(path, fn)
+	return h, nil, err
+}
+
+// saveIndex writes an index in the format given by its extension
+func saveIndex(h *hnsw.HNSW, path string, labels []uint64) error {
+	if isHnswlib(path) {
+		return h.SaveHnswlibFile(path, labels)
+	}
+	return h.SaveFile(path)
+}
+
+// label returns the external identifier of a node
+func label(labels []uint64, id int) uint64 {
+	if labels == nil {
+		return uint64(id)
+	}
+	return labels[id]
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func runStats(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("stats", "-index index.hnsw", stderr)
+	index := fs.String("index", "", "index file")
+	metric := fs.String("metric", "l2", "distance metric ("+metricNames()+")")
+	asJSON := fs.Bool("json", false, "print the statistics as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "index"); err != nil {
+		return err
+	}
+
+	h, _, err := loadIndex(*index, *metric)
+	if err != nil {
+		return err
+	}
+	stats := h.Stats()
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stats)
+	}
+
+	fmt.Fprintf(stdout, "nodes:           %d\n", stats.Nodes)
+	fmt.Fprintf(stdout, "deleted:         %d\n", stats.Deleted)
+	fmt.Fprintf(stdout, "dimension:       %d\n", stats.Dim)
+	fmt.Fprintf(stdout, "M:               %d\n", h.M)
+	fmt.Fprintf(stdout, "Mmax:            %d\n", h.Mmax)
+	fmt.Fprintf(stdout, "Mmax0:           %d\n", h.Mmax0)
+	fmt.Fprintf(stdout, "efConstruction:  %d\n", h.EfConstruction)
+	fmt.Fprintf(stdout, "max level:       %d\n", h.MaxLevel)
+	if h.EntryPoint != nil {
+		fmt.Fprintf(stdout, "entry point:     %d (level %d)\n", h.EntryPoint.ID, h.EntryPoint.Level)
+	}
+	if stats.Nodes == 0 {
+		return nil
+	}
+	fmt.Fprintf(stdout, "memory:          %d bytes (vectors %d, neighbors %d, overhead %d)\n",
+		stats.Memory.Total, stats.Memory.Vectors, stats.Memory.Neighbors, stats.Memory.Overhead)
+
+	fmt.Fprintln(stdout)
+	fmt.Fprintln(stdout, "level\tnodes\texpected\tmean degree\tfull")
+	for level := len(stats.Levels) - 1; level >= 0; level-- {
+		s := stats.Levels[level]
+		fmt.Fprintf(stdout, "%d\t%d\t%.1f\t%.2f\t%.1f%%\n", level, s.Nodes, s.Expected, s.MeanDegree, 100*s.Full)
+	}
+	return nil
+}
+
+func runVerify(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("verify", "-index index.hnsw [-repair [-output fixed.hnsw]]", stderr)
+	index := fs.String("index", "", "index file")
+	metric := fs.String("metric", "l2", "distance metric ("+metricNames()+")")
+	repair := fs.Bool("repair", false, "fix the problems that can be fixed and save the index")
+	output := fs.String("output", "", "file for the repaired index (default: overwrite -index)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "index"); err != nil {
+		return err
+	}
+
+	// Loading already validates the file format and the neighbor IDs
+	h, labels, err := loadIndex(*index, *metric)
+	if err != nil {
+		return err
+	}
+
+	if *repair {
+		fixed := h.Repair()
+		for _, issue := range fixed {
+			fmt.Fprintf(stdout, "fixed: %v\n", issue)
+		}
+		path := *output
+		if path == "" {
+			path = *index
+		}
+		if len(fixed) > 0 || path != *index {
+			if err := saveIndex(h, path, labels); err != nil {
+				return err
+			}
+		}
+	}
+
+	report := h.Verify()
+	for _, issue := range report.Issues {
+		fmt.Fprintln(stdout, issue)
+	}
+	if !report.OK() {
+		return fmt.Errorf("%s: %d problems found", *index, len(report.Issues))
+	}
+	fmt.Fprintf(stdout, "%s: ok (%d nodes)\n", *index, report.Nodes)
+	return nil
+}
@@ -0,0 +1,122 @@
+// Command hnsw builds, queries and inspects HNSW indexes from the command
+// line.
+//
+// Usage:
+//
+//	hnsw <command> [flags]
+//
+// The commands are:
+//
+//	build    build an index from a vector file (.fvecs, .bvecs, .npy, .csv)
+//	query    print the K nearest neighbors of the vectors in a file
+//	stats    print statistics about an index
+//	verify   check the integrity of an index, and optionally repair it
+//	eval     measure recall and latency against a ground truth
+//	convert  convert an index between the native and the hnswlib format
+//	export   export the graph to DOT, GraphML or an edge list
+//
+// Index files ending in .bin are read and written in the hnswlib format;
+// any other extension uses the native format of the hnsw package.
+// Run "hnsw <command> -h" for the flags of a command.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// command is a subcommand of the tool
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"build", "build an index from a vector file", runBuild},
+	{"query", "print the K nearest neighbors of the vectors in a file", runQuery},
+	{"stats", "print statistics about an index", runStats},
+	{"verify", "check the integrity of an index, and optionally repair it", runVerify},
+	{"eval", "measure recall and latency against a ground truth", runEval},
+	{"convert", "convert an index between the native and the hnswlib format", runConvert},
+	{"export", "export the graph to DOT, GraphML or an edge list", runExport},
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hnsw:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command named by the first argument
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		usage(stderr)
+		return flag.ErrHelp
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return nil
+	}
+
+	usage(stderr)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: hnsw <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: hnsw %s %s\n\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// positive reports an error for the first integer flag among names whose
+// value is not positive
+func positive(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if v, _ := strconv.Atoi(fs.Lookup(name).Value.String()); v <= 0 {
+			fs.Usage()
+			return fmt.Errorf("%s: -%s must be positive", fs.Name(), name)
+		}
+	}
+	return nil
+}
+
+// required reports an error for the first empty flag value among names
+func required(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if fs.Lookup(name).Value.String() == "" {
+			fs.Usage()
+			return fmt.Errorf("%s: -%s is required", fs.Name(), name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// writeCSV writes n random vectors of dimension dim to a CSV file in dir
+func writeCSV(t *testing.T, dir, name string, n, dim int, seed uint64) string {
+	t.Helper()
+	rng := rand.New(rand.NewPCG(seed, seed))
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		for j := 0; j < dim; j++ {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(&buf, "%g", rng.Float32())
+		}
+		buf.WriteByte('\n')
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	return path
+}
+
+// runCommand runs the tool and returns its standard output
+func runCommand(t *testing.T, args ...string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if err := run(args, &stdout, &stderr); err != nil {
+		t.Fatalf("hnsw %s failed: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestBuildQuery(t *testing.T) {
+	dir := t.TempDir()
+	base := writeCSV(t, dir, "base.csv", 300, 8, 1)
+	queries := writeCSV(t, dir, "queries.csv", 5, 8, 2)
+	index := filepath.Join(dir, "index.hnsw")
+
+	runCommand(t, "build", "-input", base, "-output", index, "-M", "8", "-ef-construction", "100")
+
+	h, err := hnsw.LoadFile(index, hnsw.EuclideanDistance)
+	if err != nil {
+		t.Fatalf("Failed to load the built index: %v", err)
+	}
+	if len(h.Nodes) != 300 || h.Dim() != 8 {
+		t.Errorf("Expected 300 nodes of dimension 8, got %d of dimension %d", len(h.Nodes), h.Dim())
+	}
+	if h.M != 8 || h.Mmax != 16 || h.Mmax0 != 32 || h.EfConstruction != 100 {
+		t.Errorf("Unexpected config M=%d Mmax=%d Mmax0=%d ef=%d", h.M, h.Mmax, h.Mmax0, h.EfConstruction)
+	}
+
+	out := runCommand(t, "query", "-index", index, "-queries", queries, "-k", "3")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 1+5*3 {
+		t.Fatalf("Expected a header and 15 rows, got %d lines:\n%s", len(lines), out)
+	}
+	if lines[0] != "query\trank\tid\tdistance" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "0\t1\t") {
+		t.Errorf("Expected the first row for query 0 rank 1, got %q", lines[1])
+	}
+}
+
+func TestBuildSeed(t *testing.T) {
+	dir := t.TempDir()
+	base := writeCSV(t, dir, "base.csv", 200, 4, 8)
+	first := filepath.Join(dir, "first.hnsw")
+	second := filepath.Join(dir, "second.hnsw")
+	runCommand(t, "build", "-input", base, "-output", first, "-seed", "7")
+	runCommand(t, "build", "-input", base, "-output", second, "-seed", "7")
+
+	a, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	b, err := os.ReadFile(second)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Errorf("Expected identical indexes for the same seed")
+	}
+}
+
+func TestStatsVerify(t *testing.T) {
+	dir := t.TempDir()
+	base := writeCSV(t, dir, "base.csv", 200, 4, 3)
+	index := filepath.Join(dir, "index.hnsw")
+	runCommand(t, "build", "-input", base, "-output", index)
+
+	out := runCommand(t, "stats", "-index", index)
+	for _, want := range []string{"nodes:           200", "dimension:       4", "level\tnodes\texpected\tmean degree\tfull"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected stats to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	var stats hnsw.Stats
+	if err := json.Unmarshal([]byte(runCommand(t, "stats", "-index", index, "-json")), &stats); err != nil {
+		t.Fatalf("Failed to decode the JSON stats: %v", err)
+	}
+	if stats.Nodes != 200 || stats.Dim != 4 || len(stats.Levels) == 0 {
+		t.Errorf("Expected 200 nodes of dimension 4, got %+v", stats)
+	}
+
+	if out := runCommand(t, "verify", "-index", index); !strings.Contains(out, "ok (200 nodes)") {
+		t.Errorf("Expected verify to succeed, got:\n%s", out)
+	}
+
+	// A self-loop is reported, then removed by -repair
+	h, err := hnsw.LoadFile(index, hnsw.EuclideanDistance)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	h.Nodes[5].Neighbors[0][0] = 5
+	corrupt := filepath.Join(dir, "corrupt.hnsw")
+	if err := h.SaveFile(corrupt); err != nil {
+		t.Fatalf("Failed to save index: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"verify", "-index", corrupt}, &stdout, &stderr); err == nil {
+		t.Errorf("Expected verify to fail on a self-loop")
+	}
+	if !strings.Contains(stdout.String(), "self-loop") {
+		t.Errorf("Expected a self-loop, got:\n%s", stdout.String())
+	}
+
+	fixed := filepath.Join(dir, "fixed.hnsw")
+	out = runCommand(t, "verify", "-index", corrupt, "-repair", "-output", fixed)
+	if !strings.Contains(out, "fixed: node 5 level 0: self-loop to 5") {
+		t.Errorf("Expected the self-loop to be fixed, got:\n%s", out)
+	}
+	if out := runCommand(t, "verify", "-index", fixed); !strings.Contains(out, "ok (200 nodes)") {
+		t.Errorf("Expected the repaired index to verify, got:\n%s", out)
+	}
+}
+
+func TestEval(t *testing.T) {
+	dir := t.TempDir()
+	base := writeCSV(t, dir, "base.csv", 500, 8, 4)
+	queries := writeCSV(t, dir, "queries.csv", 20, 8, 5)
+	index := filepath.Join(dir, "index.hnsw")
+	report := filepath.Join(dir, "report.json")
+	runCommand(t, "build", "-input", base, "-output", index)
+
+	out := runCommand(t, "eval", "-index", index, "-queries", queries, "-k", "5", "-ef", "5,50", "-json", report)
+	if !strings.Contains(out, "500 nodes, 20 queries, K=5") {
+		t.Errorf("Unexpected eval output:\n%s", out)
+	}
+
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+	var decoded struct {
+		Results []struct {
+			Ef     int     `json:"ef"`
+			Recall float64 `json:"recall"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Invalid JSON report: %v", err)
+	}
+	if len(decoded.Results) != 2 || decoded.Results[1].Ef != 50 {
+		t.Fatalf("Expected results for ef 5 and 50, got %+v", decoded.Results)
+	}
+	if decoded.Results[1].Recall < 0.9 {
+		t.Errorf("Expected recall ≥ 0.9 with ef 50, got %v", decoded.Results[1].Recall)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	base := writeCSV(t, dir, "base.csv", 100, 4, 6)
+	index := filepath.Join(dir, "index.hnsw")
+	bin := filepath.Join(dir, "index.bin")
+	back := filepath.Join(dir, "back.hnsw")
+	runCommand(t, "build", "-input", base, "-output", index)
+
+	runCommand(t, "convert", "-input", index, "-output", bin)
+	runCommand(t, "convert", "-input", bin, "-output", back)
+
+	original, err := hnsw.LoadFile(index, hnsw.EuclideanDistance)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	converted, err := hnsw.LoadFile(back, hnsw.EuclideanDistance)
+	if err != nil {
+		t.Fatalf("Failed to load converted index: %v", err)
+	}
+	if len(converted.Nodes) != len(original.Nodes) || converted.EntryPoint.ID != original.EntryPoint.ID {
+		t.Fatalf("Expected %d nodes with entry point %d, got %d with %d",
+			len(original.Nodes), original.EntryPoint.ID, len(converted.Nodes), converted.EntryPoint.ID)
+	}
+	for i, node := range original.Nodes {
+		for level := range node.Neighbors {
+			if fmt.Sprint(node.Neighbors[level]) != fmt.Sprint(converted.Nodes[i].Neighbors[level]) {
+				t.Fatalf("Node %d level %d: expected neighbors %v, got %v", i, level, node.Neighbors[level], converted.Nodes[i].Neighbors[level])
+			}
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	base := writeCSV(t, dir, "base.csv", 100, 4, 7)
+	index := filepath.Join(dir, "index.hnsw")
+	graph := filepath.Join(dir, "graph.dot")
+	runCommand(t, "build", "-input", base, "-output", index)
+
+	runCommand(t, "export", "-index", index, "-output", graph)
+	data, err := os.ReadFile(graph)
+	if err != nil {
+		t.Fatalf("Failed to read the graph: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "digraph hnsw {") {
+		t.Errorf("Expected a DOT graph, got:\n%s", data)
+	}
+
+	out := runCommand(t, "export", "-index", index, "-output", "-", "-format", "csv", "-level", "0", "-sample", "10")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if lines[0] != "layer,source,target" {
+		t.Errorf("Expected the edge list header, got %q", lines[0])
+	}
+	sources := make(map[string]bool)
+	for _, line := range lines[1:] {
+		fields := strings.Split(line, ",")
+		if fields[0] != "0" {
+			t.Errorf("Expected only layer 0 edges, got %q", line)
+		}
+		sources[fields[1]] = true
+	}
+	if len(sources) != 10 {
+		t.Errorf("Expected edges from 10 sampled nodes, got %d", len(sources))
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no command", nil},
+		{"unknown command", []string{"compact"}},
+		{"missing flag", []string{"build", "-input", "base.csv"}},
+		{"unknown metric", []string{"stats", "-index", "index.hnsw", "-metric", "hamming"}},
+		{"negative k", []string{"query", "-index", "index.hnsw", "-queries", "q.csv", "-k", "-1"}},
+		{"zero ef", []string{"query", "-index", "index.hnsw", "-queries", "q.csv", "-ef", "0"}},
+		{"invalid ef list", []string{"eval", "-index", "index.hnsw", "-queries", "q.csv", "-ef", "10,x"}},
+		{"missing file", []string{"stats", "-index", filepath.Join(t.TempDir(), "missing.hnsw")}},
+		{"unknown graph format", []string{"export", "-index", "index.hnsw", "-output", "graph.txt"}},
+		{"export to stdout without format", []string{"export", "-index", "index.hnsw", "-output", "-"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if err := run(tt.args, &stdout, &stderr); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"build", "-h"}, &stdout, &stderr); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Expected flag.ErrHelp for -h, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "-ef-construction") {
+		t.Errorf("Expected the flags in the usage, got:\n%s", stderr.String())
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"dmarro89.github.com/hnsw-go/dataset"
+)
+
+func runQuery(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("query", "-index index.hnsw -queries queries.fvecs [flags]", stderr)
+	index := fs.String("index", "", "index file")
+	queries := fs.String("queries", "", "query vector file (.fvecs, .bvecs, .npy or .csv)")
+	k := fs.Int("k", 10, "number of neighbors per query")
+	ef := fs.Int("ef", 100, "size of the candidate list during search")
+	metric := fs.String("metric", "l2", "distance metric ("+metricNames()+")")
+	limit := fs.Int("limit", 0, "maximum number of queries (0 for all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := required(fs, "index", "queries"); err != nil {
+		return err
+	}
+	if err := positive(fs, "k", "ef"); err != nil {
+		return err
+	}
+
+	h, labels, err := loadIndex(*index, *metric)
+	if err != nil {
+		return err
+	}
+	vectors, err := dataset.LoadVectors(*queries, *limit)
+	if err != nil {
+		return err
+	}
+	if len(vectors) > 0 && len(vectors[0]) != h.Dim() {
+		return fmt.Errorf("query dimension %d, index dimension %d", len(vectors[0]), h.Dim())
+	}
+
+	// One tab-separated row per result; IDs are hnswlib labels for .bin indexes
+	w := bufio.NewWriter(stdout)
+	fmt.Fprintln(w, "query\trank\tid\tdistance")
+	for q, vector := range vectors {
+		for rank, id := range h.KNN_Search(vector, *k, *ef) {
+			dist := h.DistanceFunc(vector, h.Nodes[id].Vector)
+			fmt.Fprintf(w, "%d\t%d\t%d\t%g\n", q, rank+1, label(labels, id), dist)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,137 @@
+package collection
+
+import (
+	"fmt"
+	"sync"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// Handle is a reference to a collection index returned by Acquire. The
+// index stays loaded, even if the collection is dropped, until Release.
+type Handle struct {
+	// Index is the collection index
+	Index *hnsw.HNSW
+
+	c    *Collections
+	s    *slot
+	once sync.Once
+}
+
+// Release gives the index back. The Handle must not be used afterwards.
+// Calling Release more than once has no effect.
+func (h *Handle) Release() {
+	h.once.Do(func() { h.c.release(h.s) })
+}
+
+// release drops a reference on a slot, unloading a dropped collection and
+// deleting its file when the last reference goes away
+func (c *Collections) release(s *slot) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	s.refs--
+	if s.refs == 0 && s.dropped {
+		s.mutex.Lock()
+		s.index = nil
+		s.mutex.Unlock()
+		c.removeFile(s.file)
+	}
+}
+
+// Acquire returns a handle on the index of a collection, loading it if
+// needed. name may be an alias, resolved once: switching the alias later
+// does not affect the handle, which keeps the collection it was acquired
+// with until Release.
+func (c *Collections) Acquire(name string) (*Handle, error) {
+	s, index, err := c.load(name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Handle{Index: index, c: c, s: s}, nil
+}
+
+// Search runs KNN_Search on a collection or alias, holding a handle for the
+// duration of the search.
+func (c *Collections) Search(name string, query []float32, K, ef int) ([]int, error) {
+	h, err := c.Acquire(name)
+	if err != nil {
+		return nil, err
+	}
+	defer h.Release()
+	return h.Index.KNN_Search(query, K, ef), nil
+}
+
+// CreateAlias creates an alias for a collection. Aliases and collections
+// share the same namespace.
+func (c *Collections) CreateAlias(alias, collection string) error {
+	if !validName(alias) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, alias)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.catalog.nameInUse(alias) {
+		return fmt.Errorf("%w: %q", ErrExists, alias)
+	}
+	if _, ok := c.catalog.Collections[collection]; !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, collection)
+	}
+
+	c.catalog.Aliases[alias] = collection
+	if err := c.catalog.write(c.dir); err != nil {
+		delete(c.catalog.Aliases, alias)
+		return err
+	}
+	return nil
+}
+
+// SwitchAlias atomically points an existing alias to another collection and
+// returns the collection it referred to before. Acquire calls that follow
+// SwitchAlias get the new collection; handles acquired before keep the old
+// one, which can then be dropped safely.
+func (c *Collections) SwitchAlias(alias, collection string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	previous, ok := c.catalog.Aliases[alias]
+	if !ok {
+		return "", fmt.Errorf("%w: alias %q", ErrNotFound, alias)
+	}
+	if _, ok := c.catalog.Collections[collection]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrNotFound, collection)
+	}
+
+	c.catalog.Aliases[alias] = collection
+	if err := c.catalog.write(c.dir); err != nil {
+		c.catalog.Aliases[alias] = previous
+		return "", err
+	}
+	return previous, nil
+}
+
+// DropAlias deletes an alias. The collection it refers to is not affected.
+func (c *Collections) DropAlias(alias string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	target, ok := c.catalog.Aliases[alias]
+	if !ok {
+		return fmt.Errorf("%w: alias %q", ErrNotFound, alias)
+	}
+
+	delete(c.catalog.Aliases, alias)
+	if err := c.catalog.write(c.dir); err != nil {
+		c.catalog.Aliases[alias] = target
+		return err
+	}
+	return nil
+}
+
+// Aliases returns a copy of the aliases, mapped to their collection.
+func (c *Collections) Aliases() map[string]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	aliases := make(map[string]string, len(c.catalog.Aliases))
+	for alias, target := range c.catalog.Aliases {
+		aliases[alias] = target
+	}
+	return aliases
+}
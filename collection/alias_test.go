@@ -0,0 +1,175 @@
+package collection
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// createFilled creates a collection whose vectors all have value as first component
+func createFilled(t *testing.T, c *Collections, name string, value float32) *hnsw.HNSW {
+	t.Helper()
+	h, err := c.Create(name, hnsw.MetricL2, hnsw.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Create(%q) failed: %v", name, err)
+	}
+	for i := 0; i < 20; i++ {
+		h.Insert([]float32{value, float32(i)}, i)
+	}
+	return h
+}
+
+func TestAlias(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	v1 := createFilled(t, c, "products_v1", 1)
+	v2 := createFilled(t, c, "products_v2", 2)
+
+	if err := c.CreateAlias("products", "products_v1"); err != nil {
+		t.Fatalf("CreateAlias failed: %v", err)
+	}
+	if h, err := c.Get("products"); err != nil || h != v1 {
+		t.Fatalf("Expected the alias to resolve to products_v1, got %v", err)
+	}
+
+	// A reader in flight keeps the old index across the switch
+	old, err := c.Acquire("products")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	previous, err := c.SwitchAlias("products", "products_v2")
+	if err != nil {
+		t.Fatalf("SwitchAlias failed: %v", err)
+	}
+	if previous != "products_v1" {
+		t.Errorf("Expected previous target products_v1, got %q", previous)
+	}
+	if old.Index != v1 {
+		t.Errorf("Expected the handle to keep products_v1")
+	}
+	if h, _ := c.Get("products"); h != v2 {
+		t.Errorf("Expected the alias to resolve to products_v2 after the switch")
+	}
+
+	// The old collection is released only when its reader is done
+	if err := c.Drop("products_v1"); err != nil {
+		t.Fatalf("Drop failed: %v", err)
+	}
+	file := filepath.Join(dir, "c000001.hnsw")
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Expected the file of a dropped collection in use to remain: %v", err)
+	}
+	if results := old.Index.KNN_Search([]float32{1, 3}, 1, 10); len(results) != 1 || results[0] != 3 {
+		t.Errorf("Expected the old index to remain searchable, got %v", results)
+	}
+	old.Release()
+	old.Release()
+	if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the file to be deleted after release, got %v", err)
+	}
+
+	// Aliases are persisted
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if aliases := reopened.Aliases(); len(aliases) != 1 || aliases["products"] != "products_v2" {
+		t.Errorf("Unexpected aliases after reopen: %v", aliases)
+	}
+}
+
+func TestAliasErrors(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	createFilled(t, c, "a", 1)
+	createFilled(t, c, "b", 2)
+	if err := c.CreateAlias("current", "a"); err != nil {
+		t.Fatalf("CreateAlias failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		op   func() error
+		err  error
+	}{
+		{"alias to unknown collection", func() error { return c.CreateAlias("x", "missing") }, ErrNotFound},
+		{"alias named like a collection", func() error { return c.CreateAlias("b", "a") }, ErrExists},
+		{"duplicate alias", func() error { return c.CreateAlias("current", "b") }, ErrExists},
+		{"collection named like an alias", func() error { _, err := c.Create("current", hnsw.MetricL2, hnsw.DefaultConfig()); return err }, ErrExists},
+		{"rename to an alias", func() error { return c.Rename("b", "current") }, ErrExists},
+		{"switch unknown alias", func() error { _, err := c.SwitchAlias("missing", "b"); return err }, ErrNotFound},
+		{"switch to unknown collection", func() error { _, err := c.SwitchAlias("current", "missing"); return err }, ErrNotFound},
+		{"drop aliased collection", func() error { return c.Drop("a") }, ErrAliased},
+		{"drop unknown alias", func() error { return c.DropAlias("missing") }, ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.op(); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	// Renaming a collection carries its aliases along
+	if err := c.Rename("a", "a2"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if target := c.Aliases()["current"]; target != "a2" {
+		t.Errorf("Expected the alias to follow the rename, got %q", target)
+	}
+	if err := c.DropAlias("current"); err != nil {
+		t.Fatalf("DropAlias failed: %v", err)
+	}
+	if err := c.Drop("a2"); err != nil {
+		t.Errorf("Expected Drop to succeed once the alias is gone, got %v", err)
+	}
+}
+
+// TestAliasSwitchConcurrent switches an alias back and forth while searches
+// run through it; every search must see one complete index
+func TestAliasSwitchConcurrent(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	createFilled(t, c, "a", 1)
+	createFilled(t, c, "b", 2)
+	if err := c.CreateAlias("live", "a"); err != nil {
+		t.Fatalf("CreateAlias failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				results, err := c.Search("live", []float32{1.5, 5}, 5, 20)
+				if err != nil || len(results) != 5 {
+					t.Errorf("Search failed: %v %v", results, err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		target := "b"
+		if i%2 == 1 {
+			target = "a"
+		}
+		if _, err := c.SwitchAlias("live", target); err != nil {
+			t.Fatalf("SwitchAlias failed: %v", err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,146 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// CatalogFile is the name of the catalog inside the data directory.
+const CatalogFile = "catalog.json"
+
+// catalogVersion is the version of the catalog format
+const catalogVersion = 1
+
+// catalog is the persistent description of every collection
+type catalog struct {
+	Version int `json:"version"`
+
+	// NextFile numbers the index file of the next collection, so that a
+	// file name is never reused, even after a drop
+	NextFile int `json:"next_file"`
+
+	Collections map[string]*entry `json:"collections"`
+
+	// Aliases maps every alias to the name of its collection
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// nameInUse reports whether name is taken by a collection or an alias
+func (c *catalog) nameInUse(name string) bool {
+	_, collection := c.Collections[name]
+	_, alias := c.Aliases[name]
+	return collection || alias
+}
+
+// resolve returns the name of the collection that name refers to, either
+// directly or through an alias
+func (c *catalog) resolve(name string) (string, *entry, bool) {
+	if target, ok := c.Aliases[name]; ok {
+		name = target
+	}
+	e, ok := c.Collections[name]
+	return name, e, ok
+}
+
+// entry describes a collection in the catalog. Dim and Len are updated
+// whenever the collection is saved, so that List does not load it.
+type entry struct {
+	File           string    `json:"file"`
+	Metric         string    `json:"metric"`
+	M              int       `json:"m"`
+	Mmax           int       `json:"mmax"`
+	Mmax0          int       `json:"mmax0"`
+	EfConstruction int       `json:"ef_construction"`
+	MaxLevel       int       `json:"max_level"`
+	Dim            int       `json:"dim"`
+	Len            int       `json:"len"`
+	Created        time.Time `json:"created"`
+}
+
+// config returns the configuration of the collection index
+func (e *entry) config() (hnsw.Config, error) {
+	fn, err := hnsw.Metric(e.Metric)
+	if err != nil {
+		return hnsw.Config{}, err
+	}
+	return hnsw.Config{
+		M:              e.M,
+		Mmax:           e.Mmax,
+		Mmax0:          e.Mmax0,
+		EfConstruction: e.EfConstruction,
+		MaxLevel:       e.MaxLevel,
+		DistanceFunc:   fn,
+	}, nil
+}
+
+// readCatalog reads the catalog of dir, or returns an empty one if dir has
+// none yet
+func readCatalog(dir string) (*catalog, error) {
+	data, err := os.ReadFile(filepath.Join(dir, CatalogFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &catalog{
+			Version:     catalogVersion,
+			NextFile:    1,
+			Collections: make(map[string]*entry),
+			Aliases:     make(map[string]string),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var c catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("collection: invalid catalog: %w", err)
+	}
+	if c.Version != catalogVersion {
+		return nil, fmt.Errorf("collection: unsupported catalog version %d", c.Version)
+	}
+	if c.Collections == nil {
+		c.Collections = make(map[string]*entry)
+	}
+	if c.Aliases == nil {
+		c.Aliases = make(map[string]string)
+	}
+	for alias, name := range c.Aliases {
+		if _, ok := c.Collections[name]; !ok {
+			return nil, fmt.Errorf("collection: invalid catalog: alias %q refers to unknown collection %q", alias, name)
+		}
+	}
+	return &c, nil
+}
+
+// write replaces the catalog of dir atomically
+func (c *catalog) write(dir string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, CatalogFile)
+	tmp, err := os.CreateTemp(dir, CatalogFile+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,56 @@
+package collection
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCatalogInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not JSON", "{"},
+		{"unknown version", `{"version": 2, "collections": {}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, CatalogFile), []byte(tt.data), 0o644); err != nil {
+				t.Fatalf("Failed to write catalog: %v", err)
+			}
+			if _, err := Open(dir); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestCatalogWrite(t *testing.T) {
+	dir := t.TempDir()
+	cat, err := readCatalog(dir)
+	if err != nil {
+		t.Fatalf("readCatalog failed: %v", err)
+	}
+	cat.Collections["a"] = &entry{File: "c000001.hnsw", Metric: "l2", M: 16, Dim: 8}
+	cat.NextFile = 2
+	if err := cat.write(dir); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	read, err := readCatalog(dir)
+	if err != nil {
+		t.Fatalf("readCatalog failed: %v", err)
+	}
+	if read.NextFile != 2 || read.Collections["a"] == nil || *read.Collections["a"] != *cat.Collections["a"] {
+		t.Errorf("Expected %+v, got %+v", cat, read)
+	}
+
+	// No temporary file is left behind
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Expected only the catalog in the directory, got %d files", len(files))
+	}
+}
@@ -0,0 +1,403 @@
+// Package collection manages named HNSW indexes stored in a data directory.
+//
+// Every collection has its own configuration and metric. The catalog of
+// collections is kept in catalog.json and every index in its own file;
+// indexes are loaded lazily, on the first access after Open. Distance
+// functions are stored by metric name, see hnsw.RegisterMetric.
+//
+// An alias is a second name for a collection that can be switched to
+// another collection atomically, for example to cut traffic over to an
+// index rebuilt offline. Readers that acquired the old collection keep
+// using it until they release it; a dropped collection is unloaded and its
+// file deleted once its last reader is done.
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+var (
+	// ErrNotFound is returned for operations on a collection that does not exist.
+	ErrNotFound = errors.New("collection not found")
+
+	// ErrExists is returned when creating or renaming to a name already
+	// used by a collection or an alias.
+	ErrExists = errors.New("collection already exists")
+
+	// ErrAliased is returned when dropping a collection that an alias refers to.
+	ErrAliased = errors.New("collection is the target of an alias")
+
+	// ErrInvalidName is returned for names that are empty, longer than
+	// MaxNameLength or contain characters other than letters, digits, '_',
+	// '-' and '.'.
+	ErrInvalidName = errors.New("invalid collection name")
+)
+
+// MaxNameLength is the maximum length of a collection name.
+const MaxNameLength = 128
+
+// Info describes a collection.
+type Info struct {
+	Name   string
+	Metric string
+
+	M              int
+	Mmax           int
+	Mmax0          int
+	EfConstruction int
+	MaxLevel       int
+
+	// Dim and Len are those of the loaded index, or those recorded when the
+	// collection was last saved if it is not loaded
+	Dim int
+	Len int
+
+	Loaded  bool
+	Created time.Time
+}
+
+// Collections is a set of named indexes persisted in a data directory. It
+// is safe for concurrent use by multiple goroutines.
+type Collections struct {
+	dir string
+
+	// mutex guards catalog and slots
+	mutex   sync.Mutex
+	catalog *catalog
+
+	// slots holds the loaded indexes, by file name, which does not change
+	// when a collection is renamed
+	slots map[string]*slot
+}
+
+// slot holds a collection index once it is loaded
+type slot struct {
+	// mutex guards index, so that a collection is loaded only once
+	mutex sync.Mutex
+	index *hnsw.HNSW
+
+	// file is the index file of the collection
+	file string
+
+	// refs counts the handles returned by Acquire and not yet released;
+	// dropped is set by Drop. Both are guarded by Collections.mutex.
+	refs    int
+	dropped bool
+}
+
+// Open opens the collections stored in dir, creating the directory if
+// needed. Only the catalog is read: indexes are loaded by Get.
+func Open(dir string) (*Collections, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	cat, err := readCatalog(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Collections{dir: dir, catalog: cat, slots: make(map[string]*slot)}, nil
+}
+
+// validName reports whether name can be used for a collection
+func validName(name string) bool {
+	if name == "" || len(name) > MaxNameLength || name[0] == '.' {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Create creates an empty collection with the distance function of metric.
+// cfg.DistanceFunc is ignored. The collection is persisted immediately.
+func (c *Collections) Create(name, metric string, cfg hnsw.Config) (*hnsw.HNSW, error) {
+	if !validName(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	fn, err := hnsw.Metric(metric)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DistanceFunc = fn
+	index, err := hnsw.NewHNSW(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.catalog.nameInUse(name) {
+		return nil, fmt.Errorf("%w: %q", ErrExists, name)
+	}
+
+	e := &entry{
+		File:           fmt.Sprintf("c%06d.hnsw", c.catalog.NextFile),
+		Metric:         metric,
+		M:              cfg.M,
+		Mmax:           cfg.Mmax,
+		Mmax0:          cfg.Mmax0,
+		EfConstruction: cfg.EfConstruction,
+		MaxLevel:       cfg.MaxLevel,
+		Created:        time.Now().UTC(),
+	}
+	// The index file is written before the catalog refers to it
+	if err := index.SaveFile(filepath.Join(c.dir, e.File)); err != nil {
+		return nil, err
+	}
+	c.catalog.NextFile++
+	c.catalog.Collections[name] = e
+	if err := c.catalog.write(c.dir); err != nil {
+		delete(c.catalog.Collections, name)
+		os.Remove(filepath.Join(c.dir, e.File))
+		return nil, err
+	}
+
+	c.slots[e.File] = &slot{index: index, file: e.File}
+	return index, nil
+}
+
+// Get returns the index of a collection, loading it on first access. name
+// may be an alias. The index is not protected against a later Drop or alias
+// switch: use Acquire to keep using a collection while an alias moves.
+func (c *Collections) Get(name string) (*hnsw.HNSW, error) {
+	_, index, err := c.load(name, false)
+	return index, err
+}
+
+// load returns the slot of a collection with its index, loading it if
+// needed. With ref, a reference is taken on the slot before loading.
+func (c *Collections) load(name string, ref bool) (*slot, *hnsw.HNSW, error) {
+	c.mutex.Lock()
+	target, e, ok := c.catalog.resolve(name)
+	if !ok {
+		c.mutex.Unlock()
+		return nil, nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	s := c.slots[e.File]
+	if s == nil {
+		s = &slot{file: e.File}
+		c.slots[e.File] = s
+	}
+	if ref {
+		s.refs++
+	}
+	cfg, err := e.config()
+	c.mutex.Unlock()
+
+	if err == nil {
+		// Loading holds only the slot, so other collections stay available
+		s.mutex.Lock()
+		if s.index == nil {
+			s.index, err = hnsw.LoadFile(filepath.Join(c.dir, s.file), cfg.DistanceFunc)
+		}
+		index := s.index
+		s.mutex.Unlock()
+		if err == nil {
+			return s, index, nil
+		}
+	}
+
+	if ref {
+		c.release(s)
+	}
+	return nil, nil, fmt.Errorf("collection %q: %w", target, err)
+}
+
+// List returns the collections sorted by name. Collections are not loaded.
+func (c *Collections) List() []Info {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	infos := make([]Info, 0, len(c.catalog.Collections))
+	for name, e := range c.catalog.Collections {
+		info := Info{
+			Name:           name,
+			Metric:         e.Metric,
+			M:              e.M,
+			Mmax:           e.Mmax,
+			Mmax0:          e.Mmax0,
+			EfConstruction: e.EfConstruction,
+			MaxLevel:       e.MaxLevel,
+			Dim:            e.Dim,
+			Len:            e.Len,
+			Created:        e.Created,
+		}
+		if s := c.slots[e.File]; s != nil && s.mutex.TryLock() {
+			if s.index != nil {
+				info.Dim, info.Len, info.Loaded = s.index.Dim(), s.index.Len(), true
+			}
+			s.mutex.Unlock()
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Rename changes the name of a collection; aliases follow it. Indexes
+// already returned by Get remain valid.
+func (c *Collections) Rename(oldName, newName string) error {
+	if !validName(newName) {
+		return fmt.Errorf("%w: %q", ErrInvalidName, newName)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.catalog.Collections[oldName]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, oldName)
+	}
+	if c.catalog.nameInUse(newName) {
+		return fmt.Errorf("%w: %q", ErrExists, newName)
+	}
+
+	var moved []string
+	for alias, target := range c.catalog.Aliases {
+		if target == oldName {
+			c.catalog.Aliases[alias] = newName
+			moved = append(moved, alias)
+		}
+	}
+	delete(c.catalog.Collections, oldName)
+	c.catalog.Collections[newName] = e
+	if err := c.catalog.write(c.dir); err != nil {
+		delete(c.catalog.Collections, newName)
+		c.catalog.Collections[oldName] = e
+		for _, alias := range moved {
+			c.catalog.Aliases[alias] = oldName
+		}
+		return err
+	}
+	return nil
+}
+
+// Drop deletes a collection and its index file. A collection that an alias
+// refers to cannot be dropped. If handles returned by Acquire are still in
+// use, the index is unloaded and its file deleted when the last one is
+// released. Indexes returned by Get can still be used, but are no longer
+// saved.
+func (c *Collections) Drop(name string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.catalog.Collections[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	for alias, target := range c.catalog.Aliases {
+		if target == name {
+			return fmt.Errorf("%w: %q is the target of %q", ErrAliased, name, alias)
+		}
+	}
+
+	// The catalog is updated first: a crash leaves an orphan file behind,
+	// never a catalog entry without its file
+	delete(c.catalog.Collections, name)
+	if err := c.catalog.write(c.dir); err != nil {
+		c.catalog.Collections[name] = e
+		return err
+	}
+	s := c.slots[e.File]
+	delete(c.slots, e.File)
+	if s != nil && s.refs > 0 {
+		s.dropped = true
+		return nil
+	}
+	return c.removeFile(e.File)
+}
+
+// removeFile deletes the index file of a dropped collection
+func (c *Collections) removeFile(file string) error {
+	if err := os.Remove(filepath.Join(c.dir, file)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Save writes a loaded collection to its file and records its size in the
+// catalog. Collections that are not loaded have nothing to save.
+func (c *Collections) Save(name string) error {
+	c.mutex.Lock()
+	e, ok := c.catalog.Collections[name]
+	if !ok {
+		c.mutex.Unlock()
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	s, file := c.slots[e.File], e.File
+	c.mutex.Unlock()
+
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	index := s.index
+	s.mutex.Unlock()
+	if index == nil {
+		return nil
+	}
+
+	if err := index.SaveFile(filepath.Join(c.dir, file)); err != nil {
+		return fmt.Errorf("collection %q: %w", name, err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	// The collection may have been renamed or dropped in the meantime
+	for _, e := range c.catalog.Collections {
+		if e.File == file {
+			e.Dim, e.Len = index.Dim(), index.Len()
+			return c.catalog.write(c.dir)
+		}
+	}
+	return nil
+}
+
+// SaveAll saves every loaded collection.
+func (c *Collections) SaveAll() error {
+	c.mutex.Lock()
+	names := make([]string, 0, len(c.catalog.Collections))
+	for name, e := range c.catalog.Collections {
+		if c.slots[e.File] != nil {
+			names = append(names, name)
+		}
+	}
+	c.mutex.Unlock()
+
+	var errs []error
+	for _, name := range names {
+		if err := c.Save(name); err != nil && !errors.Is(err, ErrNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close saves every loaded collection and unloads them. The indexes must
+// not be modified during Close. The Collections can still be used
+// afterwards, reloading indexes on access.
+func (c *Collections) Close() error {
+	if err := c.SaveAll(); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for file, s := range c.slots {
+		// Indexes held by handles stay loaded
+		if s.refs == 0 {
+			delete(c.slots, file)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package collection
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+func TestCreateGet(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	images, err := c.Create("images", hnsw.MetricCosine, hnsw.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		images.Insert([]float32{float32(i), 1, 0}, i)
+	}
+
+	cfg := hnsw.DefaultConfig()
+	cfg.M, cfg.Mmax, cfg.Mmax0 = 4, 8, 16
+	if _, err := c.Create("text", hnsw.MetricL2, cfg); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	got, err := c.Get("images")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got != images {
+		t.Errorf("Get returned a different index than Create")
+	}
+	if _, err := c.Get("audio"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	infos := c.List()
+	if len(infos) != 2 || infos[0].Name != "images" || infos[1].Name != "text" {
+		t.Fatalf("Unexpected list %+v", infos)
+	}
+	if infos[0].Metric != hnsw.MetricCosine || infos[0].Len != 20 || infos[0].Dim != 3 || !infos[0].Loaded {
+		t.Errorf("Unexpected info %+v", infos[0])
+	}
+	if infos[1].M != 4 || infos[1].Mmax0 != 16 || infos[1].Len != 0 {
+		t.Errorf("Unexpected info %+v", infos[1])
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	h, err := c.Create("points", hnsw.MetricInnerProduct, hnsw.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		h.Insert([]float32{float32(i % 7), float32(i % 5)}, i)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	// List reports the saved size without loading the collection
+	infos := reopened.List()
+	if len(infos) != 1 || infos[0].Loaded || infos[0].Len != 50 || infos[0].Dim != 2 {
+		t.Fatalf("Unexpected list %+v", infos)
+	}
+
+	loaded, err := reopened.Get("points")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if loaded.Len() != 50 {
+		t.Errorf("Expected 50 nodes, got %d", loaded.Len())
+	}
+	// The metric is restored from its name
+	if d := loaded.DistanceFunc([]float32{1, 2}, []float32{3, 4}); d != hnsw.InnerProductDistance([]float32{1, 2}, []float32{3, 4}) {
+		t.Errorf("Expected the inner product distance, got %v", d)
+	}
+	if !reopened.List()[0].Loaded {
+		t.Errorf("Expected the collection to be loaded after Get")
+	}
+}
+
+func TestRenameDrop(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := c.Create(name, hnsw.MetricL2, hnsw.DefaultConfig()); err != nil {
+			t.Fatalf("Create(%q) failed: %v", name, err)
+		}
+	}
+	h, _ := c.Get("a")
+	h.Insert([]float32{1, 2}, 0)
+
+	if err := c.Rename("a", "b"); !errors.Is(err, ErrExists) {
+		t.Errorf("Expected ErrExists, got %v", err)
+	}
+	if err := c.Rename("missing", "c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := c.Rename("a", "c"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if got, err := c.Get("c"); err != nil || got != h {
+		t.Errorf("Expected the renamed collection to keep its index, got %v", err)
+	}
+	if err := c.Save("c"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if err := c.Drop("b"); err != nil {
+		t.Fatalf("Drop failed: %v", err)
+	}
+	if err := c.Drop("b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// Renames and drops survive a reopen, and only live index files remain
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	infos := reopened.List()
+	if len(infos) != 1 || infos[0].Name != "c" || infos[0].Len != 1 {
+		t.Fatalf("Unexpected list %+v", infos)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.hnsw"))
+	if len(files) != 1 {
+		t.Errorf("Expected 1 index file, got %v", files)
+	}
+
+	// A new collection never reuses the file of a dropped one
+	if _, err := reopened.Create("b", hnsw.MetricL2, hnsw.DefaultConfig()); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c000003.hnsw")); err != nil {
+		t.Errorf("Expected a new index file: %v", err)
+	}
+}
+
+func TestCreateInvalid(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := c.Create("taken", hnsw.MetricL2, hnsw.DefaultConfig()); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	invalid := hnsw.DefaultConfig()
+	invalid.M = 0
+	tests := []struct {
+		name   string
+		metric string
+		cfg    hnsw.Config
+		err    error
+	}{
+		{"", hnsw.MetricL2, hnsw.DefaultConfig(), ErrInvalidName},
+		{"../escape", hnsw.MetricL2, hnsw.DefaultConfig(), ErrInvalidName},
+		{".hidden", hnsw.MetricL2, hnsw.DefaultConfig(), ErrInvalidName},
+		{string(make([]byte, MaxNameLength+1)), hnsw.MetricL2, hnsw.DefaultConfig(), ErrInvalidName},
+		{"taken", hnsw.MetricL2, hnsw.DefaultConfig(), ErrExists},
+		{"metric", "hamming", hnsw.DefaultConfig(), nil},
+		{"config", hnsw.MetricL2, invalid, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%.20q", tt.name), func(t *testing.T) {
+			_, err := c.Create(tt.name, tt.metric, tt.cfg)
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+	if n := len(c.List()); n != 1 {
+		t.Errorf("Expected 1 collection after failed creations, got %d", n)
+	}
+}
@@ -0,0 +1,108 @@
+package dataset
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvReader reads one vector per line of comma-separated numbers
+type csvReader struct {
+	r    *csv.Reader
+	dim  int
+	line int
+
+	// pending is the first vector, read in advance to learn the dimension
+	pending []string
+}
+
+// newCSVReader reads the first vector of a CSV file, skipping a header line
+// if the first line is not made of numbers
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	c := &csvReader{r: csv.NewReader(r)}
+	c.r.ReuseRecord = true
+
+	record, err := c.read()
+	if err == io.EOF {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := strconv.ParseFloat(strings.TrimSpace(record[0]), 32); err != nil {
+		if record, err = c.read(); err == io.EOF {
+			return c, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	c.pending = append([]string(nil), record...)
+	c.dim = len(record)
+	c.r.FieldsPerRecord = c.dim
+	return c, nil
+}
+
+// read returns the next record
+func (c *csvReader) read() ([]string, error) {
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	c.line++
+	return record, nil
+}
+
+// next returns the next vector as strings
+func (c *csvReader) next() ([]string, error) {
+	if c.pending != nil {
+		record := c.pending
+		c.pending = nil
+		return record, nil
+	}
+	if c.dim == 0 {
+		return nil, io.EOF
+	}
+	return c.read()
+}
+
+// nextFloats returns the next vector
+func (c *csvReader) nextFloats() ([]float32, error) {
+	record, err := c.next()
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]float32, len(record))
+	for i, s := range record {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidFile, c.line, err)
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
+}
+
+// nextInts returns the next vector of integers
+func (c *csvReader) nextInts() ([]int, error) {
+	record, err := c.next()
+	if err != nil {
+		return nil, err
+	}
+
+	v := make([]int, len(record))
+	for i, s := range record {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidFile, c.line, err)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
@@ -0,0 +1,90 @@
+package dataset
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected [][]float32
+	}{
+		{"plain", "1,2,3\n4,5,6\n", [][]float32{{1, 2, 3}, {4, 5, 6}}},
+		{"header", "x,y\n0.5,-1\n2e3, 4 \n", [][]float32{{0.5, -1}, {2000, 4}}},
+		{"no final newline", "7,8", [][]float32{{7, 8}}},
+		{"empty", "", nil},
+		{"header only", "a,b,c\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tt.data), CSV)
+			if err != nil {
+				t.Fatalf("NewReader failed: %v", err)
+			}
+			if got := readAll(t, r); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReadCSVInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"ragged", "1,2\n3,4,5\n"},
+		{"not a number", "1,2\n3,x\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tt.data), CSV)
+			if err != nil {
+				t.Fatalf("NewReader failed: %v", err)
+			}
+			if _, err := r.Next(); err != nil {
+				t.Fatalf("Next failed: %v", err)
+			}
+			if _, err := r.Next(); !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("Expected ErrInvalidFile, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadGroundTruthCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gt.csv")
+	if err := os.WriteFile(path, []byte("4,1\n0,2\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	lists, err := LoadGroundTruth(path, 0)
+	if err != nil {
+		t.Fatalf("LoadGroundTruth failed: %v", err)
+	}
+	if expected := [][]int{{4, 1}, {0, 2}}; !reflect.DeepEqual(lists, expected) {
+		t.Errorf("Expected %v, got %v", expected, lists)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer r.Close()
+	if r.Len() != -1 || r.Dim() != 2 {
+		t.Errorf("Expected unknown length and dimension 2, got %d and %d", r.Len(), r.Dim())
+	}
+	readAll(t, r)
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+// Package dataset reads the vector files used by standard ANN benchmarks,
+// such as SIFT, GIST and GloVe: the TEXMEX .fvecs, .ivecs and .bvecs formats
+// and NumPy .npy arrays, as well as plain CSV files.
+//
+// Files are streamed one vector at a time, so base sets larger than memory
+// can be inserted into an index directly.
+package dataset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// maxDim bounds the dimension read from a file header, so that a corrupted
+// header fails instead of allocating a huge vector
+const maxDim = 1 << 24
+
+// Format identifies the layout of a vector file.
+type Format int
+
+const (
+	// Fvecs stores each vector as an int32 dimension followed by float32 values
+	Fvecs Format = iota + 1
+	// Ivecs stores each vector as an int32 dimension followed by int32 values
+	Ivecs
+	// Bvecs stores each vector as an int32 dimension followed by uint8 values
+	Bvecs
+	// Npy is a two-dimensional NumPy array in C order
+	Npy
+	// CSV stores one vector per line as comma-separated numbers, with an
+	// optional header line
+	CSV
+)
+
+// String returns the file extension of the format, without the dot.
+func (f Format) String() string {
+	switch f {
+	case Fvecs:
+		return "fvecs"
+	case Ivecs:
+		return "ivecs"
+	case Bvecs:
+		return "bvecs"
+	case Npy:
+		return "npy"
+	case CSV:
+		return "csv"
+	}
+	return fmt.Sprintf("format(%d)", int(f))
+}
+
+// FormatOf returns the format of a file from its extension.
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".fvecs":
+		return Fvecs, nil
+	case ".ivecs":
+		return Ivecs, nil
+	case ".bvecs":
+		return Bvecs, nil
+	case ".npy":
+		return Npy, nil
+	case ".csv":
+		return CSV, nil
+	}
+	return 0, fmt.Errorf("dataset: unknown format for %s", path)
+}
+
+// ErrInvalidFile is returned when a file does not match its format.
+var ErrInvalidFile = errors.New("dataset: invalid file")
+
+// elemType is the type of the values stored in a file
+type elemType int
+
+const (
+	float32Elem elemType = iota
+	uint8Elem
+	int32Elem
+	int64Elem
+)
+
+// size returns the size in bytes of a value
+func (t elemType) size() int {
+	switch t {
+	case uint8Elem:
+		return 1
+	case int64Elem:
+		return 8
+	}
+	return 4
+}
+
+// Reader streams the vectors of a file.
+type Reader struct {
+	r      *bufio.Reader
+	closer io.Closer
+
+	format Format
+	elem   elemType
+	dim    int
+	count  int
+	read   int
+	buf    []byte
+	csv    *csvReader
+}
+
+// Open opens the vector file at path, detecting its format from the extension.
+func Open(path string) (*Reader, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := NewReader(f, format)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r.closer = f
+
+	// The vecs formats have no header with the number of vectors
+	if r.count < 0 && format != CSV {
+		if info, err := f.Stat(); err == nil {
+			record := int64(4 + r.dim*r.elem.size())
+			if info.Size()%record != 0 {
+				f.Close()
+				return nil, fmt.Errorf("%s: %w: size is not a multiple of the record size", path, ErrInvalidFile)
+			}
+			r.count = int(info.Size() / record)
+		}
+	}
+	return r, nil
+}
+
+// NewReader returns a Reader for vectors stored in format. The header, or the
+// first record for the vecs formats, is read immediately to learn the
+// dimension.
+func NewReader(r io.Reader, format Format) (*Reader, error) {
+	rd := &Reader{
+		r:      bufio.NewReaderSize(r, 1<<20),
+		format: format,
+		count:  -1,
+	}
+
+	switch format {
+	case Fvecs:
+		rd.elem = float32Elem
+	case Ivecs:
+		rd.elem = int32Elem
+	case Bvecs:
+		rd.elem = uint8Elem
+	case Npy:
+		header, err := readNpyHeader(rd.r)
+		if err != nil {
+			return nil, err
+		}
+		rd.elem, rd.count, rd.dim = header.elem, header.rows, header.cols
+		rd.buf = make([]byte, rd.dim*rd.elem.size())
+		return rd, nil
+	case CSV:
+		csv, err := newCSVReader(rd.r)
+		if err != nil {
+			return nil, err
+		}
+		rd.csv, rd.dim = csv, csv.dim
+		if rd.dim == 0 {
+			rd.count = 0
+		}
+		return rd, nil
+	default:
+		return nil, fmt.Errorf("dataset: unknown format %v", format)
+	}
+
+	b, err := rd.r.Peek(4)
+	if err == io.EOF {
+		rd.count = 0
+		return rd, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	rd.dim = int(int32(binary.LittleEndian.Uint32(b)))
+	if rd.dim <= 0 || rd.dim > maxDim {
+		return nil, fmt.Errorf("%w: invalid dimension %d", ErrInvalidFile, rd.dim)
+	}
+	rd.buf = make([]byte, rd.dim*rd.elem.size())
+	return rd, nil
+}
+
+// Format returns the format of the file.
+func (r *Reader) Format() Format {
+	return r.format
+}
+
+// Dim returns the dimension of the vectors, or 0 for an empty vecs or CSV file.
+func (r *Reader) Dim() int {
+	return r.dim
+}
+
+// Len returns the number of vectors in the file, or -1 if it is unknown,
+// which happens for CSV files and for vecs files not opened with Open.
+func (r *Reader) Len() int {
+	return r.count
+}
+
+// next reads the raw values of the next vector into r.buf
+func (r *Reader) next() error {
+	if r.count >= 0 && r.read >= r.count {
+		return io.EOF
+	}
+
+	if r.format != Npy {
+		var b [4]byte
+		if _, err := io.ReadFull(r.r, b[:]); err != nil {
+			if err == io.EOF && r.count < 0 {
+				return io.EOF
+			}
+			return fmt.Errorf("%w: vector %d: %v", ErrInvalidFile, r.read, err)
+		}
+		if dim := int(int32(binary.LittleEndian.Uint32(b[:]))); dim != r.dim {
+			return fmt.Errorf("%w: vector %d has dimension %d, expected %d", ErrInvalidFile, r.read, dim, r.dim)
+		}
+	}
+
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		return fmt.Errorf("%w: vector %d: %v", ErrInvalidFile, r.read, err)
+	}
+	r.read++
+	return nil
+}
+
+// Next returns the next vector, converted to float32. It returns io.EOF after
+// the last vector. The returned slice is newly allocated.
+func (r *Reader) Next() ([]float32, error) {
+	if r.csv != nil {
+		return r.csv.nextFloats()
+	}
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+
+	v := make([]float32, r.dim)
+	for i := range v {
+		switch r.elem {
+		case float32Elem:
+			v[i] = math.Float32frombits(binary.LittleEndian.Uint32(r.buf[4*i:]))
+		case uint8Elem:
+			v[i] = float32(r.buf[i])
+		case int32Elem:
+			v[i] = float32(int32(binary.LittleEndian.Uint32(r.buf[4*i:])))
+		case int64Elem:
+			v[i] = float32(int64(binary.LittleEndian.Uint64(r.buf[8*i:])))
+		}
+	}
+	return v, nil
+}
+
+// NextInts returns the next vector of an integer file, such as a ground
+// truth file. It returns io.EOF after the last vector.
+func (r *Reader) NextInts() ([]int, error) {
+	if r.csv != nil {
+		return r.csv.nextInts()
+	}
+	if r.elem == float32Elem {
+		return nil, fmt.Errorf("dataset: %v file does not contain integers", r.format)
+	}
+	if err := r.next(); err != nil {
+		return nil, err
+	}
+
+	v := make([]int, r.dim)
+	for i := range v {
+		switch r.elem {
+		case uint8Elem:
+			v[i] = int(r.buf[i])
+		case int32Elem:
+			v[i] = int(int32(binary.LittleEndian.Uint32(r.buf[4*i:])))
+		case int64Elem:
+			v[i] = int(int64(binary.LittleEndian.Uint64(r.buf[8*i:])))
+		}
+	}
+	return v, nil
+}
+
+// Close closes the underlying file, if the Reader was created by Open.
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Insert streams up to limit vectors from r into h, or all of them if limit
+// is not positive. The vectors receive consecutive IDs starting from the
+// current size of the index, so that vector i of the file gets ID i when h
+// starts empty. It returns the number of vectors inserted.
+func Insert(h *hnsw.HNSW, r *Reader, limit int) (int, error) {
+	if dim := h.Dim(); dim != 0 && r.Dim() != 0 && dim != r.Dim() {
+		return 0, fmt.Errorf("dataset: file dimension %d, index dimension %d", r.Dim(), dim)
+	}
+
+	n := 0
+	for limit <= 0 || n < limit {
+		v, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		h.Insert(v, len(h.Nodes))
+		n++
+	}
+	return n, nil
+}
+
+// InsertFile inserts up to limit vectors of the file at path into h.
+func InsertFile(h *hnsw.HNSW, path string, limit int) (int, error) {
+	r, err := Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return Insert(h, r, limit)
+}
+
+// LoadVectors reads up to limit vectors of the file at path into memory, or
+// all of them if limit is not positive. It is meant for query sets.
+func LoadVectors(path string, limit int) ([][]float32, error) {
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var vectors [][]float32
+	for limit <= 0 || len(vectors) < limit {
+		v, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+// LoadGroundTruth reads up to limit neighbor lists of a ground truth file
+// (.ivecs, or an integer .npy array), or all of them if limit is not
+// positive. List i holds the IDs of the exact nearest neighbors of query i,
+// closest first.
+func LoadGroundTruth(path string, limit int) ([][]int, error) {
+	r, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var lists [][]int
+	for limit <= 0 || len(lists) < limit {
+		v, err := r.NextInts()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, v)
+	}
+	return lists, nil
+}
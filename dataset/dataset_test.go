@@ -0,0 +1,279 @@
+package dataset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// writeVecs writes a vecs file where every vector is preceded by its dimension
+func writeVecs(t *testing.T, path string, vectors any) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	put := func(v any) {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			t.Fatalf("Failed to encode vector: %v", err)
+		}
+	}
+	switch vs := vectors.(type) {
+	case [][]float32:
+		for _, v := range vs {
+			put(int32(len(v)))
+			put(v)
+		}
+	case [][]int32:
+		for _, v := range vs {
+			put(int32(len(v)))
+			put(v)
+		}
+	case [][]uint8:
+		for _, v := range vs {
+			put(int32(len(v)))
+			put(v)
+		}
+	default:
+		t.Fatalf("Unsupported vector type %T", vectors)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// readAll reads every vector left in r
+func readAll(t *testing.T, r *Reader) [][]float32 {
+	t.Helper()
+
+	var vectors [][]float32
+	for {
+		v, err := r.Next()
+		if err == io.EOF {
+			return vectors
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		vectors = append(vectors, v)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		path   string
+		format Format
+	}{
+		{"sift_base.fvecs", Fvecs},
+		{"sift_groundtruth.ivecs", Ivecs},
+		{"bigann_base.bvecs", Bvecs},
+		{"glove.NPY", Npy},
+		{"embeddings.csv", CSV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			format, err := FormatOf(tt.path)
+			if err != nil || format != tt.format {
+				t.Errorf("Expected %v, got %v (%v)", tt.format, format, err)
+			}
+		})
+	}
+
+	if _, err := FormatOf("vectors.txt"); err == nil {
+		t.Errorf("Expected an error for an unknown extension")
+	}
+}
+
+func TestReadVecs(t *testing.T) {
+	dir := t.TempDir()
+
+	fvecs := filepath.Join(dir, "base.fvecs")
+	writeVecs(t, fvecs, [][]float32{{1, 2, 3}, {4, 5, 6}})
+	ivecs := filepath.Join(dir, "gt.ivecs")
+	writeVecs(t, ivecs, [][]int32{{7, 8, 9}, {-1, 0, 1}})
+	bvecs := filepath.Join(dir, "base.bvecs")
+	writeVecs(t, bvecs, [][]uint8{{0, 128, 255}, {1, 2, 3}})
+
+	tests := []struct {
+		path     string
+		expected [][]float32
+	}{
+		{fvecs, [][]float32{{1, 2, 3}, {4, 5, 6}}},
+		{ivecs, [][]float32{{7, 8, 9}, {-1, 0, 1}}},
+		{bvecs, [][]float32{{0, 128, 255}, {1, 2, 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			r, err := Open(tt.path)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer r.Close()
+
+			if r.Dim() != 3 || r.Len() != 2 {
+				t.Errorf("Expected 2 vectors of dimension 3, got %d of dimension %d", r.Len(), r.Dim())
+			}
+			if got := readAll(t, r); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReadVecsStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "base.fvecs")
+	writeVecs(t, path, [][]float32{{1, 2}, {3, 4}, {5, 6}})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(data), Fvecs)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	if r.Len() != -1 {
+		t.Errorf("Expected unknown length for a stream, got %d", r.Len())
+	}
+	if got := readAll(t, r); len(got) != 3 {
+		t.Errorf("Expected 3 vectors, got %d", len(got))
+	}
+}
+
+func TestReadVecsInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	// The file size is not a multiple of the first record size
+	mixed := filepath.Join(dir, "mixed.fvecs")
+	writeVecs(t, mixed, [][]float32{{1, 2}, {3, 4, 5}})
+	if _, err := Open(mixed); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Expected ErrInvalidFile for mixed dimensions, got %v", err)
+	}
+
+	// The sizes match, but the second record has another dimension
+	swapped := filepath.Join(dir, "swapped.fvecs")
+	writeVecs(t, swapped, [][]float32{{1, 2, 3}, {4, 5}, {6, 7, 8, 9}})
+	r, err := Open(swapped)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer r.Close()
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if _, err := r.Next(); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Expected ErrInvalidFile for a dimension change, got %v", err)
+	}
+
+	negative := filepath.Join(dir, "negative.fvecs")
+	if err := os.WriteFile(negative, []byte{0xff, 0xff, 0xff, 0xff}, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := Open(negative); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Expected ErrInvalidFile for a negative dimension, got %v", err)
+	}
+
+	// A truncated stream fails on the last vector
+	path := filepath.Join(dir, "base.fvecs")
+	writeVecs(t, path, [][]float32{{1, 2}, {3, 4}})
+	data, _ := os.ReadFile(path)
+	r, err = NewReader(bytes.NewReader(data[:len(data)-2]), Fvecs)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if _, err := r.Next(); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Expected ErrInvalidFile for a truncated vector, got %v", err)
+	}
+}
+
+func TestInsertFile(t *testing.T) {
+	dir := t.TempDir()
+	vectors := [][]float32{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {5, 5}}
+	path := filepath.Join(dir, "base.fvecs")
+	writeVecs(t, path, vectors)
+
+	h, err := hnsw.NewHNSW(hnsw.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	n, err := InsertFile(h, path, 3)
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 vectors inserted, got %d (%v)", n, err)
+	}
+	n, err = InsertFile(h, path, 0)
+	if err != nil || n != len(vectors) {
+		t.Fatalf("Expected %d vectors inserted, got %d (%v)", len(vectors), n, err)
+	}
+	if h.Len() != 3+len(vectors) {
+		t.Errorf("Expected %d nodes, got %d", 3+len(vectors), h.Len())
+	}
+	for i, node := range h.Nodes {
+		if node.ID != i {
+			t.Errorf("Expected node %d to have ID %d, got %d", i, i, node.ID)
+		}
+	}
+	if got := h.KNN_Search([]float32{5, 5}, 1, 10); got[0] != 3+4 {
+		t.Errorf("Expected nearest neighbor 7, got %v", got)
+	}
+
+	other := filepath.Join(dir, "other.fvecs")
+	writeVecs(t, other, [][]float32{{1, 2, 3}})
+	if _, err := InsertFile(h, other, 0); err == nil {
+		t.Errorf("Expected an error for a dimension mismatch")
+	}
+}
+
+func TestLoadGroundTruth(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gt.ivecs")
+	writeVecs(t, path, [][]int32{{3, 1, 2}, {0, 4, 5}, {9, 8, 7}})
+
+	lists, err := LoadGroundTruth(path, 2)
+	if err != nil {
+		t.Fatalf("LoadGroundTruth failed: %v", err)
+	}
+	if expected := [][]int{{3, 1, 2}, {0, 4, 5}}; !reflect.DeepEqual(lists, expected) {
+		t.Errorf("Expected %v, got %v", expected, lists)
+	}
+
+	queries := filepath.Join(dir, "query.fvecs")
+	writeVecs(t, queries, [][]float32{{1, 2, 3}})
+	if _, err := LoadGroundTruth(queries, 0); err == nil {
+		t.Errorf("Expected an error for a float ground truth file")
+	}
+	vectors, err := LoadVectors(queries, 0)
+	if err != nil || len(vectors) != 1 {
+		t.Errorf("Expected 1 query vector, got %d (%v)", len(vectors), err)
+	}
+}
+
+func TestReadEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.fvecs")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer r.Close()
+	if r.Len() != 0 || r.Dim() != 0 {
+		t.Errorf("Expected an empty reader, got %d vectors of dimension %d", r.Len(), r.Dim())
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// npyMagic starts every NumPy .npy file
+const npyMagic = "\x93NUMPY"
+
+// npyHeader is the part of a .npy header needed to read a matrix
+type npyHeader struct {
+	elem elemType
+	rows int
+	cols int
+}
+
+// readNpyHeader parses the header of a .npy file, which is a Python dict
+// literal such as {'descr': '<f4', 'fortran_order': False, 'shape': (10, 128), }
+func readNpyHeader(r *bufio.Reader) (npyHeader, error) {
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(r, prefix); err != nil || string(prefix[:6]) != npyMagic {
+		return npyHeader{}, fmt.Errorf("%w: not a .npy file", ErrInvalidFile)
+	}
+
+	var size int
+	switch prefix[6] {
+	case 1:
+		b := make([]byte, 2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return npyHeader{}, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		size = int(binary.LittleEndian.Uint16(b))
+	case 2, 3:
+		b := make([]byte, 4)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return npyHeader{}, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		size = int(binary.LittleEndian.Uint32(b))
+	default:
+		return npyHeader{}, fmt.Errorf("%w: unsupported .npy version %d", ErrInvalidFile, prefix[6])
+	}
+	if size > 1<<20 {
+		return npyHeader{}, fmt.Errorf("%w: .npy header too large", ErrInvalidFile)
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return npyHeader{}, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	dict := string(b)
+
+	var hd npyHeader
+	descr, ok := npyField(dict, "descr")
+	if !ok {
+		return npyHeader{}, fmt.Errorf("%w: .npy header without descr", ErrInvalidFile)
+	}
+	switch strings.Trim(descr, `'"`) {
+	case "<f4":
+		hd.elem = float32Elem
+	case "|u1", "<u1":
+		hd.elem = uint8Elem
+	case "<i4":
+		hd.elem = int32Elem
+	case "<i8":
+		hd.elem = int64Elem
+	default:
+		return npyHeader{}, fmt.Errorf("%w: unsupported .npy dtype %s", ErrInvalidFile, descr)
+	}
+
+	if order, _ := npyField(dict, "fortran_order"); order != "False" {
+		return npyHeader{}, fmt.Errorf("%w: only C-order .npy arrays are supported", ErrInvalidFile)
+	}
+
+	shape, ok := npyField(dict, "shape")
+	if !ok {
+		return npyHeader{}, fmt.Errorf("%w: .npy header without shape", ErrInvalidFile)
+	}
+	var dims []int
+	for _, s := range strings.Split(strings.Trim(shape, "()"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return npyHeader{}, fmt.Errorf("%w: invalid .npy shape %s", ErrInvalidFile, shape)
+		}
+		dims = append(dims, n)
+	}
+	if len(dims) != 2 || dims[1] == 0 && dims[0] > 0 {
+		return npyHeader{}, fmt.Errorf("%w: expected a two-dimensional .npy array, got shape %s", ErrInvalidFile, shape)
+	}
+	hd.rows, hd.cols = dims[0], dims[1]
+	if hd.cols > maxDim {
+		return npyHeader{}, fmt.Errorf("%w: invalid dimension %d", ErrInvalidFile, hd.cols)
+	}
+	if hd.cols > 0 && hd.rows > math.MaxInt/(hd.cols*hd.elem.size()) {
+		return npyHeader{}, fmt.Errorf("%w: .npy shape %s is too large", ErrInvalidFile, shape)
+	}
+	return hd, nil
+}
+
+// npyField returns the raw value of a key in a .npy header dict
+func npyField(dict, key string) (string, bool) {
+	i := strings.Index(dict, "'"+key+"'")
+	if i < 0 {
+		return "", false
+	}
+	rest := strings.TrimSpace(dict[i+len(key)+2:])
+	rest, ok := strings.CutPrefix(rest, ":")
+	if !ok {
+		return "", false
+	}
+	rest = strings.TrimSpace(rest)
+
+	// Tuples contain commas: the value ends at the closing parenthesis
+	end := strings.IndexAny(rest, ",}")
+	if strings.HasPrefix(rest, "(") {
+		end = strings.Index(rest, ")") + 1
+	}
+	if end <= 0 {
+		return "", false
+	}
+	return strings.TrimSpace(rest[:end]), true
+}
@@ -0,0 +1,153 @@
+package dataset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// npyBytes builds a .npy file with the given header dict and raw data, padded
+// like numpy does so that the data starts on a 64-byte boundary
+func npyBytes(t *testing.T, version byte, dict string, data any) []byte {
+	t.Helper()
+
+	prefix := 10
+	if version > 1 {
+		prefix = 12
+	}
+	header := dict + strings.Repeat(" ", 63-(prefix+len(dict))%64) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString(npyMagic)
+	buf.Write([]byte{version, 0})
+	if version > 1 {
+		binary.Write(&buf, binary.LittleEndian, uint32(len(header)))
+	} else {
+		binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	}
+	buf.WriteString(header)
+	if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
+		t.Fatalf("Failed to encode data: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadNpy(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  byte
+		dict     string
+		data     any
+		expected [][]float32
+	}{
+		{
+			"float32",
+			1,
+			"{'descr': '<f4', 'fortran_order': False, 'shape': (2, 3), }",
+			[]float32{1, 2, 3, 4, 5, 6},
+			[][]float32{{1, 2, 3}, {4, 5, 6}},
+		},
+		{
+			"uint8",
+			1,
+			"{'descr': '|u1', 'fortran_order': False, 'shape': (3, 2), }",
+			[]uint8{0, 1, 2, 3, 254, 255},
+			[][]float32{{0, 1}, {2, 3}, {254, 255}},
+		},
+		{
+			"version 2",
+			2,
+			"{'descr': '<f4', 'fortran_order': False, 'shape': (1, 2), }",
+			[]float32{0.5, -0.5},
+			[][]float32{{0.5, -0.5}},
+		},
+		{
+			"empty",
+			1,
+			"{'descr': '<f4', 'fortran_order': False, 'shape': (0, 128), }",
+			[]float32{},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "vectors.npy")
+			if err := os.WriteFile(path, npyBytes(t, tt.version, tt.dict, tt.data), 0o644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+
+			r, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer r.Close()
+
+			if r.Len() != len(tt.expected) {
+				t.Errorf("Expected %d vectors, got %d", len(tt.expected), r.Len())
+			}
+			if got := readAll(t, r); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReadNpyGroundTruth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gt.npy")
+	data := npyBytes(t, 1, "{'descr': '<i8', 'fortran_order': False, 'shape': (2, 2), }", []int64{4, 2, 0, 1})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	lists, err := LoadGroundTruth(path, 0)
+	if err != nil {
+		t.Fatalf("LoadGroundTruth failed: %v", err)
+	}
+	if expected := [][]int{{4, 2}, {0, 1}}; !reflect.DeepEqual(lists, expected) {
+		t.Errorf("Expected %v, got %v", expected, lists)
+	}
+}
+
+func TestReadNpyInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not npy", []byte("PK\x03\x04 this is a zip file")},
+		{"fortran order", npyBytes(t, 1, "{'descr': '<f4', 'fortran_order': True, 'shape': (2, 2), }", []float32{1, 2, 3, 4})},
+		{"float64", npyBytes(t, 1, "{'descr': '<f8', 'fortran_order': False, 'shape': (1, 2), }", []float64{1, 2})},
+		{"big endian", npyBytes(t, 1, "{'descr': '>f4', 'fortran_order': False, 'shape': (1, 2), }", []float32{1, 2})},
+		{"one dimension", npyBytes(t, 1, "{'descr': '<f4', 'fortran_order': False, 'shape': (4,), }", []float32{1, 2, 3, 4})},
+		{"three dimensions", npyBytes(t, 1, "{'descr': '<f4', 'fortran_order': False, 'shape': (1, 2, 2), }", []float32{1, 2, 3, 4})},
+		{"no shape", npyBytes(t, 1, "{'descr': '<f4', 'fortran_order': False, }", []float32{1, 2})},
+		{"huge dimension", npyBytes(t, 1, "{'descr': '<f4', 'fortran_order': False, 'shape': (1, 9223372036854775807), }", []float32{1, 2})},
+		{"overflowing shape", npyBytes(t, 1, "{'descr': '<f4', 'fortran_order': False, 'shape': (9223372036854775807, 2), }", []float32{1, 2})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReader(bytes.NewReader(tt.data), Npy); !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("Expected ErrInvalidFile, got %v", err)
+			}
+		})
+	}
+
+	// The header announces more vectors than the file contains
+	data := npyBytes(t, 1, "{'descr': '<f4', 'fortran_order': False, 'shape': (2, 2), }", []float32{1, 2})
+	r, err := NewReader(bytes.NewReader(data), Npy)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if _, err := r.Next(); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("Expected ErrInvalidFile for a truncated array, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Package eval measures the search quality and speed of an HNSW index.
+//
+// Run compares KNN_Search against exact neighbors, computed by a parallel
+// brute-force scan or loaded from a ground truth file, for a sweep of ef
+// values. For every ef it reports recall@K, latency percentiles and the
+// number of distance computations per query. Reports can be written as JSON
+// or CSV to be tracked between releases.
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// DefaultEfValues is the ef sweep used when Options.EfValues is empty.
+var DefaultEfValues = []int{10, 20, 40, 80, 160, 320}
+
+// Options configures an evaluation run.
+type Options struct {
+	// K is the number of neighbors requested from KNN_Search
+	K int
+
+	// EfValues are the ef values to evaluate, DefaultEfValues if empty
+	EfValues []int
+
+	// Truth holds the exact neighbors of every query, closest first, for
+	// example from dataset.LoadGroundTruth. If nil, they are computed with
+	// IndexGroundTruth.
+	Truth [][]int
+
+	// Workers is the number of goroutines of the brute-force scan,
+	// GOMAXPROCS if not positive
+	Workers int
+}
+
+// Result holds the measurements for a single ef value.
+type Result struct {
+	K       int     `json:"k"`
+	Ef      int     `json:"ef"`
+	Queries int     `json:"queries"`
+	Recall  float64 `json:"recall"`
+
+	// QPS is the number of queries per second on a single goroutine
+	QPS float64 `json:"qps"`
+
+	// Latencies are in microseconds
+	LatencyMean float64 `json:"latency_mean_us"`
+	LatencyP50  float64 `json:"latency_p50_us"`
+	LatencyP90  float64 `json:"latency_p90_us"`
+	LatencyP99  float64 `json:"latency_p99_us"`
+	LatencyMax  float64 `json:"latency_max_us"`
+
+	// DistanceComputations is the mean number of DistanceFunc calls per query
+	DistanceComputations float64 `json:"distance_computations"`
+}
+
+// Report is the outcome of an evaluation run.
+type Report struct {
+	Nodes   int       `json:"nodes"`
+	Dim     int       `json:"dim"`
+	Queries int       `json:"queries"`
+	K       int       `json:"k"`
+	Date    time.Time `json:"date"`
+	Results []Result  `json:"results"`
+}
+
+// Run evaluates KNN_Search on h with the given queries for every ef value.
+//
+// Queries run one at a time on the calling goroutine, so that latencies are
+// not skewed by contention. Distance computations are counted in a separate
+// pass with SearchExplain, so that counting does not add to the latencies.
+func Run(h *hnsw.HNSW, queries [][]float32, opts Options) (*Report, error) {
+	if opts.K <= 0 {
+		return nil, errors.New("K must be positive")
+	}
+	if len(queries) == 0 {
+		return nil, errors.New("no queries")
+	}
+	// An empty index has no dimension: the queries must still agree
+	dim := h.Dim()
+	if dim == 0 {
+		dim = len(queries[0])
+	}
+	for i, query := range queries {
+		if len(query) != dim {
+			return nil, fmt.Errorf("query %d has dimension %d, expected %d", i, len(query), dim)
+		}
+	}
+
+	efValues := opts.EfValues
+	if len(efValues) == 0 {
+		efValues = DefaultEfValues
+	}
+
+	truth := opts.Truth
+	if truth == nil {
+		truth = IndexGroundTruth(h, queries, opts.K, opts.Workers)
+	}
+	if len(truth) < len(queries) {
+		return nil, fmt.Errorf("ground truth has %d lists for %d queries", len(truth), len(queries))
+	}
+
+	report := &Report{
+		Nodes:   h.Len(),
+		Dim:     h.Dim(),
+		Queries: len(queries),
+		K:       opts.K,
+		Date:    time.Now().UTC(),
+	}
+
+	latencies := make([]time.Duration, len(queries))
+	for _, ef := range efValues {
+		var recall float64
+		var total time.Duration
+
+		for i, query := range queries {
+			start := time.Now()
+			results := h.KNN_Search(query, opts.K, ef)
+			latencies[i] = time.Since(start)
+
+			total += latencies[i]
+			recall += Recall(results, truth[i], opts.K)
+		}
+
+		distances := 0
+		for _, query := range queries {
+			distances += h.SearchExplain(query, opts.K, ef).Distances
+		}
+
+		slices.Sort(latencies)
+		n := float64(len(queries))
+		report.Results = append(report.Results, Result{
+			K:                    opts.K,
+			Ef:                   ef,
+			Queries:              len(queries),
+			Recall:               recall / n,
+			QPS:                  n / total.Seconds(),
+			LatencyMean:          microseconds(total) / n,
+			LatencyP50:           microseconds(percentile(latencies, 0.50)),
+			LatencyP90:           microseconds(percentile(latencies, 0.90)),
+			LatencyP99:           microseconds(percentile(latencies, 0.99)),
+			LatencyMax:           microseconds(latencies[len(latencies)-1]),
+			DistanceComputations: float64(distances) / n,
+		})
+	}
+	return report, nil
+}
+
+// percentile returns the p-th percentile of sorted latencies, using the
+// nearest-rank method
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+func microseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Microsecond)
+}
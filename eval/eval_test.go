@@ -0,0 +1,151 @@
+package eval
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	h := buildIndex(t, randomVectors(rng, 1000, 16))
+	queries := randomVectors(rng, 50, 16)
+
+	distanceFunc := h.DistanceFunc
+	report, err := Run(h, queries, Options{K: 10, EfValues: []int{10, 50, 200}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if report.Nodes != 1000 || report.Dim != 16 || report.Queries != 50 || report.K != 10 {
+		t.Errorf("Unexpected report header %+v", report)
+	}
+	if len(report.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(report.Results))
+	}
+
+	for i, res := range report.Results {
+		if res.Recall < 0 || res.Recall > 1 {
+			t.Errorf("ef=%d: recall %v out of range", res.Ef, res.Recall)
+		}
+		if res.DistanceComputations <= 0 {
+			t.Errorf("ef=%d: expected distance computations to be counted", res.Ef)
+		}
+		if !(res.LatencyP50 <= res.LatencyP90 && res.LatencyP90 <= res.LatencyP99 && res.LatencyP99 <= res.LatencyMax) {
+			t.Errorf("ef=%d: latency percentiles out of order: %+v", res.Ef, res)
+		}
+		if i > 0 {
+			prev := report.Results[i-1]
+			if res.Recall < prev.Recall {
+				t.Errorf("Recall dropped from %v at ef=%d to %v at ef=%d", prev.Recall, prev.Ef, res.Recall, res.Ef)
+			}
+			if res.DistanceComputations <= prev.DistanceComputations {
+				t.Errorf("Expected more distance computations at ef=%d than at ef=%d", res.Ef, prev.Ef)
+			}
+		}
+	}
+	if last := report.Results[2]; last.Recall < 0.9 {
+		t.Errorf("Expected recall ≥ 0.9 at ef=200, got %v", last.Recall)
+	}
+
+	// Run leaves the distance function of the index alone
+	if reflect.ValueOf(h.DistanceFunc).Pointer() != reflect.ValueOf(distanceFunc).Pointer() {
+		t.Errorf("DistanceFunc changed by Run")
+	}
+
+	// The counts match the calls KNN_Search makes to DistanceFunc
+	calls := 0
+	h.DistanceFunc = func(a, b []float32) float32 {
+		calls++
+		return distanceFunc(a, b)
+	}
+	for _, query := range queries {
+		h.KNN_Search(query, 10, 10)
+	}
+	h.DistanceFunc = distanceFunc
+	if got, want := report.Results[0].DistanceComputations, float64(calls)/50; got != want {
+		t.Errorf("Expected %v distance computations per query, got %v", want, got)
+	}
+}
+
+func TestRunWithTruth(t *testing.T) {
+	rng := rand.New(rand.NewPCG(7, 8))
+	vectors := randomVectors(rng, 200, 4)
+	h := buildIndex(t, vectors)
+	queries := randomVectors(rng, 5, 4)
+
+	// A ground truth made of nodes the search never returns gives zero recall
+	truth := make([][]int, len(queries))
+	for i := range truth {
+		truth[i] = []int{-1, -2, -3}
+	}
+	report, err := Run(h, queries, Options{K: 3, EfValues: []int{20}, Truth: truth})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Results[0].Recall != 0 {
+		t.Errorf("Expected recall 0 against the given truth, got %v", report.Results[0].Recall)
+	}
+
+	if _, err := Run(h, queries, Options{K: 3, Truth: truth[:2]}); err == nil {
+		t.Errorf("Expected an error for a short ground truth")
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	rng := rand.New(rand.NewPCG(9, 10))
+	h := buildIndex(t, randomVectors(rng, 10, 4))
+
+	tests := []struct {
+		name    string
+		queries [][]float32
+		opts    Options
+	}{
+		{"zero K", [][]float32{{1, 2, 3, 4}}, Options{K: 0}},
+		{"no queries", nil, Options{K: 1}},
+		{"dimension mismatch", [][]float32{{1, 2}}, Options{K: 1}},
+		{"ragged queries", [][]float32{{1, 2, 3, 4}, {1, 2}}, Options{K: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Run(h, tt.queries, tt.opts); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+
+	// Without a dimension, an empty index checks the queries against each other
+	empty := buildIndex(t, nil)
+	if _, err := Run(empty, [][]float32{{1, 2}, {1, 2, 3}}, Options{K: 1}); err == nil {
+		t.Errorf("Expected an error for ragged queries on an empty index")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for i := range latencies {
+		latencies[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0.50, 50 * time.Millisecond},
+		{0.90, 90 * time.Millisecond},
+		{0.99, 99 * time.Millisecond},
+		{1, 100 * time.Millisecond},
+		{0, time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(latencies, tt.p); got != tt.want {
+			t.Errorf("p%v: expected %v, got %v", tt.p*100, tt.want, got)
+		}
+	}
+
+	if got := percentile([]time.Duration{7}, 0.99); got != 7 {
+		t.Errorf("Expected the only value, got %v", got)
+	}
+}
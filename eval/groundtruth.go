@@ -0,0 +1,121 @@
+package eval
+
+import (
+	"cmp"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// BruteForce returns the exact K nearest neighbors of every query among
+// vectors, closest first, by scanning all of them. Neighbors are identified
+// by their position in vectors, and ties are broken by position. Queries are
+// split among workers goroutines, or GOMAXPROCS of them if workers is not
+// positive.
+func BruteForce(vectors, queries [][]float32, K int, distance func([]float32, []float32) float32, workers int) [][]int {
+	return scan(len(vectors), queries, K, workers, func(query []float32, i int) (float32, bool) {
+		return distance(query, vectors[i]), true
+	})
+}
+
+// IndexGroundTruth returns the exact K nearest neighbors of every query among
+// the live nodes of h, closest first, using h.DistanceFunc. Node IDs are
+// returned, so the result can be compared with KNN_Search directly.
+//
+// The index must not be modified while the scan runs.
+func IndexGroundTruth(h *hnsw.HNSW, queries [][]float32, K, workers int) [][]int {
+	nodes := h.Nodes
+	return scan(len(nodes), queries, K, workers, func(query []float32, i int) (float32, bool) {
+		if nodes[i].Deleted {
+			return 0, false
+		}
+		return h.DistanceFunc(query, nodes[i].Vector), true
+	})
+}
+
+// scan runs an exhaustive K nearest neighbor search over n items for every
+// query. distance returns false for items that must be skipped. Among items
+// at the same distance, the ones with the lowest index are kept.
+func scan(n int, queries [][]float32, K, workers int, distance func(query []float32, i int) (float32, bool)) [][]int {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	results := make([][]int, len(queries))
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(queries)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// The furthest of the K best items so far is on top
+			nearest := structs.NewMaxHeap()
+			for {
+				q := int(next.Add(1) - 1)
+				if q >= len(queries) {
+					return
+				}
+
+				nearest.Reset()
+				for i := 0; i < n; i++ {
+					dist, ok := distance(queries[q], i)
+					if !ok {
+						continue
+					}
+					if nearest.Len() < K {
+						nearest.Push(structs.NewNodeHeap(dist, i))
+					} else if dist < nearest.Peek().Dist {
+						nearest.Pop()
+						nearest.Push(structs.NewNodeHeap(dist, i))
+					}
+				}
+
+				// Ties are broken by ID, so the ground truth is reproducible
+				found := make([]*structs.NodeHeap, nearest.Len())
+				for i := range found {
+					found[i] = nearest.Pop()
+				}
+				slices.SortFunc(found, func(a, b *structs.NodeHeap) int {
+					if c := cmp.Compare(a.Dist, b.Dist); c != 0 {
+						return c
+					}
+					return cmp.Compare(a.Id, b.Id)
+				})
+
+				ids := make([]int, len(found))
+				for i, n := range found {
+					ids[i] = n.Id
+				}
+				results[q] = ids
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Recall returns the fraction of the first K exact neighbors in truth that
+// appear among the first K results.
+func Recall(results, truth []int, K int) float64 {
+	truth = truth[:min(K, len(truth))]
+	if len(truth) == 0 {
+		return 1
+	}
+
+	exact := make(map[int]struct{}, len(truth))
+	for _, id := range truth {
+		exact[id] = struct{}{}
+	}
+	found := 0
+	for _, id := range results[:min(K, len(results))] {
+		if _, ok := exact[id]; ok {
+			found++
+		}
+	}
+	return float64(found) / float64(len(truth))
+}
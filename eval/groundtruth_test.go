@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// randomVectors returns n random vectors of the given dimension
+func randomVectors(rng *rand.Rand, n, dim int) [][]float32 {
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = rng.Float32()
+		}
+	}
+	return vectors
+}
+
+// buildIndex inserts vectors into a new index, with IDs equal to their position
+func buildIndex(t testing.TB, vectors [][]float32) *hnsw.HNSW {
+	t.Helper()
+
+	h, err := hnsw.NewHNSW(hnsw.Config{
+		M:              8,
+		Mmax:           8,
+		Mmax0:          16,
+		EfConstruction: 64,
+		MaxLevel:       4,
+		DistanceFunc:   hnsw.EuclideanDistance,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	h.RandFunc = rand.New(rand.NewPCG(1, 2)).Float64
+	for i, v := range vectors {
+		h.Insert(v, i)
+	}
+	return h
+}
+
+func TestBruteForce(t *testing.T) {
+	vectors := [][]float32{{0, 0}, {1, 0}, {0, 2}, {3, 3}, {-1, 0}}
+	queries := [][]float32{{0, 0}, {3, 2}, {-5, 0}}
+
+	tests := []struct {
+		name    string
+		K       int
+		workers int
+		want    [][]int
+	}{
+		{"top 1", 1, 1, [][]int{{0}, {3}, {4}}},
+		{"top 3", 3, 2, [][]int{{0, 1, 4}, {3, 1, 2}, {4, 0, 2}}},
+		{"K larger than the set", 10, 0, [][]int{{0, 1, 4, 2, 3}, {3, 1, 2, 0, 4}, {4, 0, 2, 1, 3}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BruteForce(vectors, queries, tt.K, hnsw.EuclideanDistance, tt.workers)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIndexGroundTruth(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	vectors := randomVectors(rng, 500, 8)
+	queries := randomVectors(rng, 20, 8)
+	h := buildIndex(t, vectors)
+
+	expected := BruteForce(vectors, queries, 10, hnsw.EuclideanDistance, 4)
+	if got := IndexGroundTruth(h, queries, 10, 4); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Index ground truth differs from the brute-force scan of the vectors")
+	}
+
+	// Deleted nodes are never part of the ground truth
+	deleted := expected[0][0]
+	if err := h.Delete(deleted); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	for _, id := range IndexGroundTruth(h, queries[:1], 10, 1)[0] {
+		if id == deleted {
+			t.Errorf("Deleted node %d returned", deleted)
+		}
+	}
+}
+
+func TestRecall(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []int
+		truth   []int
+		K       int
+		want    float64
+	}{
+		{"exact", []int{1, 2, 3}, []int{1, 2, 3}, 3, 1},
+		{"order does not matter", []int{3, 1, 2}, []int{1, 2, 3}, 3, 1},
+		{"partial", []int{1, 5, 6, 2}, []int{1, 2, 3, 4}, 4, 0.5},
+		{"only the first K count", []int{9, 1}, []int{1, 2, 3}, 1, 0},
+		{"truth longer than K", []int{1, 2}, []int{1, 2, 3, 4, 5}, 2, 1},
+		{"short results", []int{1}, []int{1, 2}, 2, 0.5},
+		{"empty truth", nil, nil, 5, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Recall(tt.results, tt.truth, tt.K); got != tt.want {
+				t.Errorf("Expected recall %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
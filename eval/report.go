@@ -0,0 +1,55 @@
+package eval
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// csvHeader lists the CSV columns, in the order of Result's fields
+var csvHeader = []string{
+	"k", "ef", "queries", "recall", "qps",
+	"latency_mean_us", "latency_p50_us", "latency_p90_us", "latency_p99_us", "latency_max_us",
+	"distance_computations",
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes the results as CSV, one row per ef value, after a header row.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	for _, res := range r.Results {
+		row := []string{
+			strconv.Itoa(res.K),
+			strconv.Itoa(res.Ef),
+			strconv.Itoa(res.Queries),
+			format(res.Recall),
+			format(res.QPS),
+			format(res.LatencyMean),
+			format(res.LatencyP50),
+			format(res.LatencyP90),
+			format(res.LatencyP99),
+			format(res.LatencyMax),
+			format(res.DistanceComputations),
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,66 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testReport() *Report {
+	return &Report{
+		Nodes:   1000,
+		Dim:     16,
+		Queries: 50,
+		K:       10,
+		Date:    time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		Results: []Result{
+			{K: 10, Ef: 10, Queries: 50, Recall: 0.82, QPS: 20000, LatencyMean: 50, LatencyP50: 45, LatencyP90: 70, LatencyP99: 110, LatencyMax: 130, DistanceComputations: 310.5},
+			{K: 10, Ef: 40, Queries: 50, Recall: 0.97, QPS: 9000, LatencyMean: 111.1, LatencyP50: 100, LatencyP90: 150, LatencyP99: 200, LatencyMax: 250, DistanceComputations: 820},
+		},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	report := testReport()
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, report) {
+		t.Errorf("Expected %+v, got %+v", report, decoded)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"latency_p99_us": 110`)) {
+		t.Errorf("Expected snake case field names, got %s", buf.String())
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d rows", len(rows))
+	}
+	if !reflect.DeepEqual(rows[0], csvHeader) {
+		t.Errorf("Expected header %v, got %v", csvHeader, rows[0])
+	}
+	expected := []string{"10", "40", "50", "0.97", "9000", "111.1", "100", "150", "200", "250", "820"}
+	if !reflect.DeepEqual(rows[2], expected) {
+		t.Errorf("Expected row %v, got %v", expected, rows[2])
+	}
+}
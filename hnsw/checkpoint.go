@@ -0,0 +1,309 @@
+package hnsw
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/bits"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// Checkpoint layout (all integers little endian)
+//
+//	header   32 bytes: magic, version, dim, node count, entry point, records
+//	records  one per changed node, in ascending ID order:
+//	           ID, level word (as in the index format), vector,
+//	           then for each level 0..level: neighbor count and IDs
+//	trailer  CRC-32 of everything before it
+//
+// A checkpoint only holds the nodes whose vector, deletion mark or neighbor
+// lists changed since the previous Save or Checkpoint, so a chain made of a
+// full index followed by its checkpoints restores the latest state.
+const (
+	checkpointMagic      = "hnsw-ck\x00"
+	checkpointVersion    = 1
+	checkpointHeaderSize = 32
+)
+
+// checkpointRecord is a decoded node of a checkpoint
+type checkpointRecord struct {
+	id        int
+	level     int
+	deleted   bool
+	vector    []float32
+	neighbors [][]uint32
+}
+
+// Checkpoint writes an incremental checkpoint to w, containing only the nodes
+// changed since the last Save or Checkpoint, and starts a new epoch. It
+// returns the number of nodes written.
+//
+// Like Save, Checkpoint holds the read lock while writing, which is short as
+// long as few nodes changed.
+func (h *HNSW) Checkpoint(w io.Writer) (records int, err error) {
+	if span := h.startSpan(context.Background(), "hnsw.Checkpoint"); span != nil {
+		defer func() {
+			span.SetAttributes(Attribute{"nodes", records})
+			span.End(err)
+		}()
+	}
+
+	h.checkpointMutex.Lock()
+	defer h.checkpointMutex.Unlock()
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	records = 0
+	for _, word := range h.dirty {
+		records += bits.OnesCount64(word)
+	}
+
+	header := make([]byte, checkpointHeaderSize)
+	copy(header[0:8], checkpointMagic)
+	binary.LittleEndian.PutUint32(header[8:], checkpointVersion)
+	binary.LittleEndian.PutUint32(header[12:], uint32(h.storage.Vectors.Dim()))
+	binary.LittleEndian.PutUint64(header[16:], uint64(len(h.Nodes)))
+	entryPoint := noEntryPoint
+	if h.EntryPoint != nil {
+		entryPoint = uint32(h.EntryPoint.ID)
+	}
+	binary.LittleEndian.PutUint32(header[24:], entryPoint)
+	binary.LittleEndian.PutUint32(header[28:], uint32(records))
+
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(w)
+	enc := &encoder{w: io.MultiWriter(bw, crc)}
+	enc.write(header)
+
+	for word, set := range h.dirty {
+		for set != 0 {
+			id := word*64 + bits.TrailingZeros64(set)
+			set &= set - 1
+
+			node := h.Nodes[id]
+			level := uint32(node.Level)
+			if node.Deleted {
+				level |= deletedFlag
+			}
+			enc.uint32(uint32(id))
+			enc.uint32(level)
+			enc.float32s(node.Vector)
+			for _, neighbors := range node.Neighbors {
+				enc.uint32(uint32(len(neighbors)))
+				for _, n := range neighbors {
+					enc.uint32(n)
+				}
+			}
+		}
+	}
+
+	enc.w = bw
+	enc.uint32(crc.Sum32())
+	if enc.err != nil {
+		return 0, enc.err
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, err
+	}
+
+	clear(h.dirty)
+	return records, nil
+}
+
+// ApplyCheckpoint applies a checkpoint written by Checkpoint. It must be
+// applied to the state the checkpoint was taken from: the index loaded from
+// the preceding Save, with the preceding checkpoints applied in order.
+//
+// The checkpoint is fully read and verified before the index is modified, so
+// a damaged checkpoint leaves the index untouched. The applied nodes count as
+// changed for the next Checkpoint of this index.
+func (h *HNSW) ApplyCheckpoint(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(data) < checkpointHeaderSize+4 || string(data[0:8]) != checkpointMagic {
+		return fmt.Errorf("%w: not a checkpoint", ErrInvalidIndex)
+	}
+	body, trailer := data[:len(data)-4], data[len(data)-4:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(trailer) {
+		return fmt.Errorf("%w: checkpoint checksum mismatch", ErrInvalidIndex)
+	}
+	if v := binary.LittleEndian.Uint32(data[8:]); v != checkpointVersion {
+		return fmt.Errorf("%w: unsupported checkpoint version %d", ErrInvalidIndex, v)
+	}
+
+	dim := int(binary.LittleEndian.Uint32(data[12:]))
+	count64 := binary.LittleEndian.Uint64(data[16:])
+	entryPoint := binary.LittleEndian.Uint32(data[24:])
+	numRecords := int(binary.LittleEndian.Uint32(data[28:]))
+
+	// Every record starts with an 8-byte header, which bounds the number of
+	// records before they are allocated
+	if count64 > uint64(noEntryPoint) || uint64(numRecords) > count64 ||
+		numRecords*8 > len(body)-checkpointHeaderSize {
+		return fmt.Errorf("%w: invalid checkpoint header", ErrInvalidIndex)
+	}
+	count := int(count64)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if current := h.storage.Vectors.Dim(); current != 0 && current != dim {
+		return fmt.Errorf("%w: checkpoint dimension %d, index dimension %d", ErrInvalidIndex, dim, current)
+	}
+	if count < len(h.Nodes) {
+		return fmt.Errorf("%w: checkpoint has fewer nodes than the index", ErrInvalidIndex)
+	}
+	if (count == 0) != (entryPoint == noEntryPoint) || (count > 0 && int(entryPoint) >= count) {
+		return fmt.Errorf("%w: invalid entry point", ErrInvalidIndex)
+	}
+
+	records, err := h.decodeCheckpoint(bytes.NewReader(body[checkpointHeaderSize:]), numRecords, dim, count)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		var node *structs.Node
+		if rec.id < len(h.Nodes) {
+			node = h.Nodes[rec.id]
+			copy(node.Vector, rec.vector)
+		} else {
+			node = h.storage.NewNode(rec.id, rec.vector, rec.level)
+			h.Nodes = append(h.Nodes, node)
+		}
+
+		if node.Deleted != rec.deleted {
+			node.Deleted = rec.deleted
+			if rec.deleted {
+				h.deleted++
+			} else {
+				h.deleted--
+			}
+		}
+		for lc, neighbors := range rec.neighbors {
+			node.Neighbors[lc] = append(node.Neighbors[lc][:0], neighbors...)
+		}
+		h.markDirty(rec.id)
+	}
+
+	if count > 0 {
+		h.EntryPoint = h.Nodes[entryPoint]
+	}
+	return nil
+}
+
+// decodeCheckpoint reads and validates the records of a checkpoint
+func (h *HNSW) decodeCheckpoint(r io.Reader, numRecords, dim, count int) ([]checkpointRecord, error) {
+	dec := &decoder{r: r}
+	records := make([]checkpointRecord, 0, numRecords)
+	nextNew := len(h.Nodes)
+
+	for i := 0; i < numRecords; i++ {
+		header := dec.uint32s(2)
+		if dec.err != nil {
+			break
+		}
+
+		rec := checkpointRecord{
+			id:      int(header[0]),
+			level:   int(header[1] &^ deletedFlag),
+			deleted: header[1]&deletedFlag != 0,
+			vector:  dec.float32s(dim),
+		}
+		if rec.id >= count || rec.level > h.MaxLevel {
+			return nil, fmt.Errorf("%w: invalid checkpoint record for node %d", ErrInvalidIndex, rec.id)
+		}
+		// Levels never change, and new nodes must appear in order, without gaps
+		if rec.id < len(h.Nodes) {
+			if h.Nodes[rec.id].Level != rec.level {
+				return nil, fmt.Errorf("%w: node %d changed level", ErrInvalidIndex, rec.id)
+			}
+		} else {
+			if rec.id != nextNew {
+				return nil, fmt.Errorf("%w: missing node %d in checkpoint", ErrInvalidIndex, nextNew)
+			}
+			nextNew++
+		}
+
+		rec.neighbors = make([][]uint32, rec.level+1)
+		for lc := range rec.neighbors {
+			n := dec.uint32s(1)
+			if dec.err != nil {
+				break
+			}
+			maxConn := h.Mmax
+			if lc == 0 {
+				maxConn = h.Mmax0
+			}
+			if int(n[0]) > maxConn {
+				return nil, fmt.Errorf("%w: node %d has %d neighbors at level %d", ErrInvalidIndex, rec.id, n[0], lc)
+			}
+			rec.neighbors[lc] = dec.uint32s(int(n[0]))
+			for _, id := range rec.neighbors[lc] {
+				if int(id) >= count {
+					return nil, fmt.Errorf("%w: node %d links to unknown node %d", ErrInvalidIndex, rec.id, id)
+				}
+			}
+		}
+		records = append(records, rec)
+	}
+
+	if dec.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIndex, dec.err)
+	}
+	if nextNew != count {
+		return nil, fmt.Errorf("%w: missing node %d in checkpoint", ErrInvalidIndex, nextNew)
+	}
+	return records, nil
+}
+
+// Backup writes a consistent point-in-time copy of the index to the file at
+// path, replacing it atomically.
+//
+// The index is copied into memory under the read lock and written to disk
+// after releasing it. Holding the read lock during the slow disk write would
+// stall searches too: once an Insert is waiting for the lock, sync.RWMutex
+// makes every new reader wait behind it. The price is a temporary in-memory
+// copy of the serialized index.
+//
+// Unlike Save, Backup does not start a new checkpoint epoch.
+func (h *HNSW) Backup(path string) error {
+	var buf bytes.Buffer
+
+	h.rlock("backup")
+	buf.Grow(h.serializedSize())
+	err := h.save(&buf)
+	h.mutex.RUnlock()
+	if err != nil {
+		return h.observeError("backup", err)
+	}
+
+	return h.observeError("backup", writeFileAtomic(path, func(w io.Writer) error {
+		_, err := buf.WriteTo(w)
+		return err
+	}))
+}
+
+// serializedSize returns the size of the index in the serialized format.
+// The caller must hold the read lock.
+func (h *HNSW) serializedSize() int {
+	upperBlocks := 0
+	for _, node := range h.Nodes {
+		upperBlocks += node.Level
+	}
+	hd := indexHeader{
+		Dim:         uint32(h.storage.Vectors.Dim()),
+		Count:       uint64(len(h.Nodes)),
+		Mmax:        uint32(h.Mmax),
+		Mmax0:       uint32(h.Mmax0),
+		UpperBlocks: uint64(upperBlocks),
+	}
+	return int(hd.size())
+}
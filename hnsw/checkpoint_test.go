@@ -0,0 +1,308 @@
+package hnsw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math/rand/v2"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// assertSameIndex verifies that two indexes have the same nodes and entry point
+func assertSameIndex(t *testing.T, expected, got *HNSW) {
+	t.Helper()
+
+	if len(got.Nodes) != len(expected.Nodes) {
+		t.Fatalf("Expected %d nodes, got %d", len(expected.Nodes), len(got.Nodes))
+	}
+	if got.EntryPoint.ID != expected.EntryPoint.ID {
+		t.Errorf("Expected entry point %d, got %d", expected.EntryPoint.ID, got.EntryPoint.ID)
+	}
+	if got.Len() != expected.Len() {
+		t.Errorf("Expected %d live nodes, got %d", expected.Len(), got.Len())
+	}
+	for i, node := range expected.Nodes {
+		other := got.Nodes[i]
+		if other.Level != node.Level || other.Deleted != node.Deleted {
+			t.Errorf("Node %d: expected level %d deleted %v, got level %d deleted %v",
+				i, node.Level, node.Deleted, other.Level, other.Deleted)
+		}
+		if !reflect.DeepEqual(other.Vector, node.Vector) {
+			t.Errorf("Node %d: vector mismatch", i)
+		}
+		if !reflect.DeepEqual(other.Neighbors, node.Neighbors) {
+			t.Errorf("Node %d: neighbors mismatch, expected %v, got %v", i, node.Neighbors, other.Neighbors)
+		}
+	}
+}
+
+// TestCheckpointChain verifies that a full index followed by its checkpoints
+// restores the latest state of the index
+func TestCheckpointChain(t *testing.T) {
+	h := buildRandomIndex(t, 300, 8)
+
+	var base bytes.Buffer
+	if err := h.Save(&base); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	rng := rand.New(rand.NewPCG(3, 4))
+	randomVector := func() []float32 {
+		v := make([]float32, 8)
+		for i := range v {
+			v[i] = rng.Float32()
+		}
+		return v
+	}
+
+	var checkpoints []*bytes.Buffer
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 20; i++ {
+			h.Insert(randomVector(), len(h.Nodes))
+		}
+		if err := h.Delete(10 * (round + 1)); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if err := h.Update(5*round+1, randomVector()); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+
+		var buf bytes.Buffer
+		n, err := h.Checkpoint(&buf)
+		if err != nil {
+			t.Fatalf("Checkpoint failed: %v", err)
+		}
+		if n == 0 || n >= len(h.Nodes) {
+			t.Errorf("Round %d: expected a partial checkpoint, got %d of %d nodes", round, n, len(h.Nodes))
+		}
+		checkpoints = append(checkpoints, &buf)
+	}
+
+	restored, err := Load(&base, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for i, buf := range checkpoints {
+		if err := restored.ApplyCheckpoint(buf); err != nil {
+			t.Fatalf("ApplyCheckpoint %d failed: %v", i, err)
+		}
+	}
+	assertSameIndex(t, h, restored)
+
+	query := []float32{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+	if !reflect.DeepEqual(restored.KNN_Search(query, 10, 50), h.KNN_Search(query, 10, 50)) {
+		t.Errorf("Search results differ after applying checkpoints")
+	}
+}
+
+// TestCheckpointSize verifies that a checkpoint is much smaller than the full
+// index when few nodes changed
+func TestCheckpointSize(t *testing.T) {
+	h := buildRandomIndex(t, 2000, 16)
+
+	var full bytes.Buffer
+	if err := h.Save(&full); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if err := h.Delete(42); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	h.Insert(make([]float32, 16), len(h.Nodes))
+
+	var delta bytes.Buffer
+	if _, err := h.Checkpoint(&delta); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if delta.Len()*20 > full.Len() {
+		t.Errorf("Checkpoint too large: %d bytes, full index %d bytes", delta.Len(), full.Len())
+	}
+}
+
+// TestCheckpointEmpty verifies that a checkpoint taken without changes is empty
+// and still applies cleanly
+func TestCheckpointEmpty(t *testing.T) {
+	h := buildRandomIndex(t, 100, 4)
+
+	var first bytes.Buffer
+	if _, err := h.Checkpoint(&first); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+
+	var second bytes.Buffer
+	n, err := h.Checkpoint(&second)
+	if err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0 nodes in an unchanged checkpoint, got %d", n)
+	}
+
+	if err := h.ApplyCheckpoint(&second); err != nil {
+		t.Errorf("ApplyCheckpoint failed: %v", err)
+	}
+}
+
+// TestCheckpointFromEmpty verifies that a checkpoint of a new index applies
+// to an empty index
+func TestCheckpointFromEmpty(t *testing.T) {
+	h := buildRandomIndex(t, 150, 4)
+
+	var buf bytes.Buffer
+	n, err := h.Checkpoint(&buf)
+	if err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if n != len(h.Nodes) {
+		t.Errorf("Expected %d nodes in the first checkpoint, got %d", len(h.Nodes), n)
+	}
+
+	restored, err := NewHNSW(Config{
+		M:              8,
+		Mmax:           8,
+		Mmax0:          16,
+		EfConstruction: 64,
+		MaxLevel:       4,
+		DistanceFunc:   EuclideanDistance,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	if err := restored.ApplyCheckpoint(&buf); err != nil {
+		t.Fatalf("ApplyCheckpoint failed: %v", err)
+	}
+	assertSameIndex(t, h, restored)
+}
+
+// TestApplyCheckpointInvalid verifies that damaged or mismatched checkpoints
+// are rejected and leave the index untouched
+func TestApplyCheckpointInvalid(t *testing.T) {
+	h := buildRandomIndex(t, 200, 4)
+
+	var base bytes.Buffer
+	if err := h.Save(&base); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	h.Insert([]float32{1, 2, 3, 4}, len(h.Nodes))
+	var buf bytes.Buffer
+	if _, err := h.Checkpoint(&buf); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	valid := buf.Bytes()
+
+	corrupted := bytes.Clone(valid)
+	corrupted[len(corrupted)/2] ^= 0xff
+
+	// withCounts rewrites the node and record counts and recomputes the
+	// checksum, as a crafted checkpoint would
+	withCounts := func(count uint64, numRecords uint32) []byte {
+		b := bytes.Clone(valid)
+		binary.LittleEndian.PutUint64(b[16:], count)
+		binary.LittleEndian.PutUint32(b[28:], numRecords)
+		binary.LittleEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+		return b
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", append([]byte("not-a-ck"), valid[8:]...)},
+		{"truncated", valid[:len(valid)-10]},
+		{"corrupted", corrupted},
+		{"more records than nodes", withCounts(201, 1000)},
+		{"more records than data", withCounts(1<<31, 1<<31)},
+		{"huge count", withCounts(1<<40, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restored, err := Load(bytes.NewReader(base.Bytes()), EuclideanDistance)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			before := len(restored.Nodes)
+
+			err = restored.ApplyCheckpoint(bytes.NewReader(tt.data))
+			if !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex, got %v", err)
+			}
+			if len(restored.Nodes) != before {
+				t.Errorf("Index modified by a rejected checkpoint")
+			}
+		})
+	}
+
+	// A checkpoint of a different index does not apply
+	t.Run("wrong dimension", func(t *testing.T) {
+		other := buildRandomIndex(t, 10, 8)
+		if err := other.ApplyCheckpoint(bytes.NewReader(valid)); !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("Expected ErrInvalidIndex, got %v", err)
+		}
+	})
+}
+
+// TestBackup verifies that a backup is a loadable copy of the index and does
+// not affect the next checkpoint
+func TestBackup(t *testing.T) {
+	h := buildRandomIndex(t, 300, 8)
+	if err := h.Delete(7); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.hnsw")
+	if err := h.Backup(path); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	restored, err := LoadFile(path, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	assertSameIndex(t, h, restored)
+
+	var buf bytes.Buffer
+	n, err := h.Checkpoint(&buf)
+	if err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if n != len(h.Nodes) {
+		t.Errorf("Expected Backup to keep the dirty nodes, got %d of %d in the checkpoint", n, len(h.Nodes))
+	}
+}
+
+// TestBackupConcurrent runs backups while the index is searched and modified
+func TestBackupConcurrent(t *testing.T) {
+	h := buildRandomIndex(t, 500, 8)
+	dir := t.TempDir()
+
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 5; i++ {
+			if err := h.Backup(filepath.Join(dir, "backup.hnsw")); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for i := 0; i < 50; i++ {
+		h.Insert([]float32{0, 0, 0, 0, 0, 0, 0, float32(i)}, len(h.Nodes))
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	restored, err := LoadFile(filepath.Join(dir, "backup.hnsw"), EuclideanDistance)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if len(restored.Nodes) < 500 || len(restored.Nodes) > len(h.Nodes) {
+		t.Errorf("Unexpected backup size %d", len(restored.Nodes))
+	}
+}
@@ -0,0 +1,60 @@
+package hnsw
+
+import (
+	"errors"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// ErrNodeNotFound is returned when an operation refers to a node that is not
+// in the index or has been deleted.
+var ErrNodeNotFound = errors.New("node not found")
+
+// Delete marks the node with the given ID as deleted.
+//
+// Following the approach of hnswlib, the node is not unlinked from the graph:
+// it keeps routing searches through its neighborhood, which preserves the
+// connectivity of the graph, but it is never returned by KNN_Search.
+func (h *HNSW) Delete(id int) error {
+	h.lock("delete")
+	defer h.mutex.Unlock()
+
+	node, err := h.liveNode(id)
+	if err != nil {
+		return h.observeError("delete", err)
+	}
+
+	node.Deleted = true
+	h.deleted++
+	h.markDirty(id)
+	return nil
+}
+
+// IsDeleted reports whether the node with the given ID has been deleted.
+func (h *HNSW) IsDeleted(id int) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return id >= 0 && id < len(h.Nodes) && h.Nodes[id].Deleted
+}
+
+// Vector returns a copy of the vector of the node with the given ID, or
+// ErrNodeNotFound if the node does not exist or has been deleted.
+func (h *HNSW) Vector(id int) ([]float32, error) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	node, err := h.liveNode(id)
+	if err != nil {
+		return nil, err
+	}
+	return append([]float32(nil), node.Vector...), nil
+}
+
+// liveNode returns the node with the given ID if it exists and is not deleted
+func (h *HNSW) liveNode(id int) (*structs.Node, error) {
+	if id < 0 || id >= len(h.Nodes) || h.Nodes[id].Deleted {
+		return nil, ErrNodeNotFound
+	}
+	return h.Nodes[id], nil
+}
@@ -0,0 +1,155 @@
+package hnsw
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestDelete verifies that deleted nodes are never returned by searches
+func TestDelete(t *testing.T) {
+	h := buildRandomIndex(t, 200, 4)
+	query := []float32{0.5, 0.5, 0.5, 0.5}
+
+	before := h.KNN_Search(query, 5, 50)
+	for _, id := range before[:3] {
+		if err := h.Delete(id); err != nil {
+			t.Fatalf("Delete(%d) failed: %v", id, err)
+		}
+	}
+
+	after := h.KNN_Search(query, 5, 50)
+	if len(after) != 5 {
+		t.Fatalf("Expected 5 results, got %d", len(after))
+	}
+	for _, id := range before[:3] {
+		if slices.Contains(after, id) {
+			t.Errorf("Deleted node %d returned by search: %v", id, after)
+		}
+		if !h.IsDeleted(id) {
+			t.Errorf("IsDeleted(%d) = false after Delete", id)
+		}
+	}
+	// The remaining neighbors move up in the results
+	if after[0] != before[3] || after[1] != before[4] {
+		t.Errorf("Expected results to start with %v, got %v", before[3:], after)
+	}
+	if h.Len() != 197 {
+		t.Errorf("Expected 197 live nodes, got %d", h.Len())
+	}
+}
+
+// TestVector verifies that Vector returns a copy of live vectors only
+func TestVector(t *testing.T) {
+	h := buildRandomIndex(t, 10, 4)
+
+	v, err := h.Vector(3)
+	if err != nil {
+		t.Fatalf("Vector failed: %v", err)
+	}
+	if !slices.Equal(v, h.Nodes[3].Vector) {
+		t.Errorf("Expected %v, got %v", h.Nodes[3].Vector, v)
+	}
+	v[0] = -1
+	if h.Nodes[3].Vector[0] == -1 {
+		t.Errorf("Vector returned the stored slice instead of a copy")
+	}
+
+	if err := h.Delete(3); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	for _, id := range []int{-1, 3, 10} {
+		if _, err := h.Vector(id); !errors.Is(err, ErrNodeNotFound) {
+			t.Errorf("Vector(%d): expected ErrNodeNotFound, got %v", id, err)
+		}
+	}
+}
+
+// TestDeleteErrors verifies that unknown and already deleted nodes are rejected
+func TestDeleteErrors(t *testing.T) {
+	h := buildRandomIndex(t, 10, 2)
+
+	if err := h.Delete(3); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	for _, id := range []int{-1, 10, 3} {
+		if err := h.Delete(id); !errors.Is(err, ErrNodeNotFound) {
+			t.Errorf("Delete(%d): expected ErrNodeNotFound, got %v", id, err)
+		}
+	}
+}
+
+// TestDeleteAll verifies that searching an index with only deleted nodes returns nothing
+func TestDeleteAll(t *testing.T) {
+	h := buildRandomIndex(t, 20, 2)
+	for i := 0; i < 20; i++ {
+		if err := h.Delete(i); err != nil {
+			t.Fatalf("Delete(%d) failed: %v", i, err)
+		}
+	}
+
+	if results := h.KNN_Search([]float32{0.5, 0.5}, 3, 10); len(results) != 0 {
+		t.Errorf("Expected no results, got %v", results)
+	}
+}
+
+// TestDeleteSerialization verifies that deletions survive Save/Load
+// and are honored by the mapped and disk-resident indexes
+func TestDeleteSerialization(t *testing.T) {
+	h := buildRandomIndex(t, 200, 4)
+	query := []float32{0.2, 0.2, 0.2, 0.2}
+	deleted := h.KNN_Search(query, 2, 50)
+	for _, id := range deleted {
+		if err := h.Delete(id); err != nil {
+			t.Fatalf("Delete(%d) failed: %v", id, err)
+		}
+	}
+	want := h.KNN_Search(query, 5, 50)
+
+	var buf bytes.Buffer
+	if err := h.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(&buf, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !loaded.IsDeleted(deleted[0]) || loaded.Len() != 198 {
+		t.Errorf("Deletions were not restored")
+	}
+	if got := loaded.KNN_Search(query, 5, 50); !slices.Equal(got, want) {
+		t.Errorf("Loaded index: expected %v, got %v", want, got)
+	}
+
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := h.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	m, err := OpenMapped(path, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("OpenMapped failed: %v", err)
+	}
+	defer m.Close()
+	if got := m.KNN_Search(query, 5, 50); !slices.Equal(got, want) {
+		t.Errorf("Mapped index: expected %v, got %v", want, got)
+	}
+
+	d, err := OpenDisk(path, EuclideanDistance, 16)
+	if err != nil {
+		t.Fatalf("OpenDisk failed: %v", err)
+	}
+	defer d.Close()
+	got, err := d.KNN_Search(query, 5, 50)
+	if err != nil {
+		t.Fatalf("KNN_Search failed: %v", err)
+	}
+	for _, id := range deleted {
+		if slices.Contains(got, id) {
+			t.Errorf("Disk index returned deleted node %d", id)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package hnsw
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// DiskIndex serves an index whose full-precision vectors do not fit in memory,
+// in the style of DiskANN. The graph is kept in memory together with a
+// scalar-quantized copy of every vector (one byte per dimension), which is
+// used to guide the traversal. The full-precision vectors stay in the index
+// file and are read with pread only to re-rank the final candidates; a
+// bounded LRU cache keeps the hottest ones in memory.
+//
+// A DiskIndex is safe for concurrent use by multiple goroutines.
+type DiskIndex struct {
+	// DistanceFunc calculates the distance between two vectors
+	DistanceFunc func([]float32, []float32) float32
+
+	file          *os.File
+	vectorsOffset int64
+
+	graph     packedGraph
+	quantizer *structs.ScalarQuantizer
+	codes     []uint8
+	cache     *structs.VectorCache
+
+	// Reusable per-search state
+	visited sync.Pool
+	scratch sync.Pool
+}
+
+// OpenDisk opens an index file written by Save in disk-resident mode.
+// cacheSize is the maximum number of full-precision vectors cached in memory.
+func OpenDisk(path string, distanceFunc func([]float32, []float32) float32, cacheSize int) (*DiskIndex, error) {
+	if distanceFunc == nil {
+		return nil, fmt.Errorf("DistanceFunc must be provided")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DiskIndex{
+		DistanceFunc:  distanceFunc,
+		file:          f,
+		vectorsOffset: indexHeaderSize,
+		cache:         structs.NewVectorCache(cacheSize),
+	}
+	if err := d.init(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// init loads the graph sections into memory and quantizes the vectors,
+// streaming over the vectors section twice: once to train the quantizer and
+// once to encode.
+func (d *DiskIndex) init() error {
+	b := make([]byte, indexHeaderSize)
+	if _, err := d.file.ReadAt(b, 0); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIndex, err)
+	}
+	var hd indexHeader
+	if err := hd.decode(b); err != nil {
+		return err
+	}
+	info, err := d.file.Stat()
+	if err != nil {
+		return err
+	}
+	if uint64(info.Size()) < hd.size() {
+		return fmt.Errorf("%w: file is truncated", ErrInvalidIndex)
+	}
+
+	count, dim := int(hd.Count), int(hd.Dim)
+	vectorsSize := int64(4 * count * dim)
+
+	// The graph sections follow the vectors and are loaded into memory
+	body := make([]byte, int64(hd.size())-indexHeaderSize-vectorsSize)
+	if _, err := d.file.ReadAt(body, indexHeaderSize+vectorsSize); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIndex, err)
+	}
+	words := make([]uint32, len(body)/4)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(body[4*i:])
+	}
+	d.graph = splitGraph(hd, words)
+	if err := d.graph.validate(); err != nil {
+		return err
+	}
+
+	d.quantizer = structs.NewScalarQuantizer(dim)
+	observe, finish := d.quantizer.Trainer()
+	if err := d.scanVectors(count, dim, func(_ int, v []float32) { observe(v) }); err != nil {
+		return err
+	}
+	finish()
+
+	d.codes = make([]uint8, count*dim)
+	if err := d.scanVectors(count, dim, func(id int, v []float32) {
+		d.quantizer.Encode(d.codes[id*dim:(id+1)*dim], v)
+	}); err != nil {
+		return err
+	}
+
+	d.visited.New = func() any {
+		return &visitedList{marks: make([]uint32, count)}
+	}
+	d.scratch.New = func() any {
+		v := make([]float32, dim)
+		return &v
+	}
+	return nil
+}
+
+// scanVectors streams the vectors section sequentially, calling fn for each vector
+func (d *DiskIndex) scanVectors(count, dim int, fn func(id int, vector []float32)) error {
+	r := bufio.NewReaderSize(io.NewSectionReader(d.file, d.vectorsOffset, int64(4*count*dim)), 1<<20)
+	b := make([]byte, 4*dim)
+	vector := make([]float32, dim)
+	for id := 0; id < count; id++ {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidIndex, err)
+		}
+		decodeFloat32s(vector, b)
+		fn(id, vector)
+	}
+	return nil
+}
+
+// Close closes the index file. The index must not be used after Close.
+func (d *DiskIndex) Close() error {
+	return d.file.Close()
+}
+
+// Len returns the number of nodes in the index.
+func (d *DiskIndex) Len() int {
+	return int(d.graph.header.Count)
+}
+
+// Dim returns the dimension of the indexed vectors.
+func (d *DiskIndex) Dim() int {
+	return int(d.graph.header.Dim)
+}
+
+// Cache returns the cache of full-precision vectors.
+func (d *DiskIndex) Cache() *structs.VectorCache {
+	return d.cache
+}
+
+// Vector returns the full-precision vector of a node, from the cache when
+// possible and otherwise read from disk.
+func (d *DiskIndex) Vector(id int) ([]float32, error) {
+	if v, ok := d.cache.Get(id); ok {
+		return v, nil
+	}
+
+	dim := d.Dim()
+	b := make([]byte, 4*dim)
+	if _, err := d.file.ReadAt(b, d.vectorsOffset+int64(4*id*dim)); err != nil {
+		return nil, err
+	}
+	v := make([]float32, dim)
+	decodeFloat32s(v, b)
+	d.cache.Put(id, v)
+	return v, nil
+}
+
+// KNN_Search performs a K-nearest neighbor search. The graph is traversed
+// using distances to the quantized vectors; the ef best candidates are then
+// re-ranked with exact distances computed on the full-precision vectors.
+//
+// Unlike HNSW.KNN_Search, it can fail when reading vectors from disk.
+func (d *DiskIndex) KNN_Search(query []float32, K, ef int) ([]int, error) {
+	if K <= 0 {
+		return nil, nil
+	}
+	if ef < K {
+		ef = K
+	}
+
+	visited := d.visited.Get().(*visitedList)
+	defer d.visited.Put(visited)
+	scratch := d.scratch.Get().(*[]float32)
+	defer d.scratch.Put(scratch)
+
+	dim := d.Dim()
+	candidates := d.graph.search(func(id int) float32 {
+		d.quantizer.Decode(*scratch, d.codes[id*dim:(id+1)*dim])
+		return d.DistanceFunc(query, *scratch)
+	}, visited, ef)
+	if candidates == nil {
+		return nil, nil
+	}
+
+	// Re-rank the candidates with exact distances
+	exact := make([]structs.NodeHeap, len(candidates))
+	for i, id := range candidates {
+		v, err := d.Vector(id)
+		if err != nil {
+			return nil, err
+		}
+		exact[i] = structs.NodeHeap{Dist: d.DistanceFunc(query, v), Id: id}
+	}
+	sort.SliceStable(exact, func(i, j int) bool {
+		return exact[i].Dist < exact[j].Dist
+	})
+
+	results := make([]int, min(K, len(exact)))
+	for i := range results {
+		results[i] = exact[i].Id
+	}
+	return results, nil
+}
+
+// decodeFloat32s decodes little endian float32 values from b into dst
+func decodeFloat32s(dst []float32, b []byte) {
+	for i := range dst {
+		dst[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+}
@@ -0,0 +1,109 @@
+package hnsw
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestOpenDisk verifies that the disk-resident index reads the exact vectors
+// from the file and that re-ranked results match the in-memory index
+func TestOpenDisk(t *testing.T) {
+	h := buildRandomIndex(t, 500, 8)
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := h.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	d, err := OpenDisk(path, EuclideanDistance, 64)
+	if err != nil {
+		t.Fatalf("OpenDisk failed: %v", err)
+	}
+	defer d.Close()
+
+	if d.Len() != 500 || d.Dim() != 8 {
+		t.Fatalf("Expected 500 nodes of dimension 8, got %d of dimension %d", d.Len(), d.Dim())
+	}
+
+	for _, id := range []int{0, 17, 499} {
+		v, err := d.Vector(id)
+		if err != nil {
+			t.Fatalf("Vector(%d) failed: %v", id, err)
+		}
+		if !reflect.DeepEqual(v, h.Nodes[id].Vector) {
+			t.Errorf("Vector(%d) = %v, want %v", id, v, h.Nodes[id].Vector)
+		}
+	}
+
+	queries := [][]float32{
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5},
+		{1, 0, 1, 0, 1, 0, 1, 0},
+		{0.1, 0.9, 0.2, 0.8, 0.3, 0.7, 0.4, 0.6},
+	}
+
+	found, total := 0, 0
+	for _, query := range queries {
+		want := h.KNN_Search(query, 10, 64)
+		got, err := d.KNN_Search(query, 10, 64)
+		if err != nil {
+			t.Fatalf("KNN_Search failed: %v", err)
+		}
+		if len(got) != 10 {
+			t.Fatalf("Expected 10 results, got %d", len(got))
+		}
+
+		// Results must be sorted by exact distance
+		for i := 1; i < len(got); i++ {
+			if EuclideanDistance(query, h.Nodes[got[i-1]].Vector) > EuclideanDistance(query, h.Nodes[got[i]].Vector) {
+				t.Errorf("Results are not sorted by exact distance: %v", got)
+			}
+		}
+
+		inMemory := make(map[int]bool)
+		for _, id := range want {
+			inMemory[id] = true
+		}
+		for _, id := range got {
+			if inMemory[id] {
+				found++
+			}
+		}
+		total += len(want)
+	}
+
+	// Quantized traversal may take a slightly different path
+	if recall := float64(found) / float64(total); recall < 0.9 {
+		t.Errorf("Expected at least 90%% overlap with the in-memory index, got %.2f", recall)
+	}
+
+	if d.Cache().Len() > 64 {
+		t.Errorf("Cache exceeded its capacity: %d entries", d.Cache().Len())
+	}
+	if hits, _ := d.Cache().Stats(); hits == 0 {
+		t.Errorf("Expected repeated queries to hit the vector cache")
+	}
+}
+
+// TestOpenDiskEmpty verifies that an empty index can be opened and searched
+func TestOpenDiskEmpty(t *testing.T) {
+	h, err := NewHNSW(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "empty.hnsw")
+	if err := h.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	d, err := OpenDisk(path, EuclideanDistance, 8)
+	if err != nil {
+		t.Fatalf("OpenDisk failed: %v", err)
+	}
+	defer d.Close()
+
+	results, err := d.KNN_Search([]float32{1, 2}, 5, 10)
+	if err != nil || results != nil {
+		t.Errorf("Expected no results, got %v (%v)", results, err)
+	}
+}
@@ -0,0 +1,141 @@
+package hnsw
+
+import (
+	"fmt"
+	"time"
+)
+
+// StopReason tells why the search of a layer ended.
+type StopReason int
+
+const (
+	// StopLocalMinimum ends the greedy search of an upper layer: no
+	// neighbor of the current node is closer to the query
+	StopLocalMinimum StopReason = iota + 1
+	// StopBound ends the beam search of layer 0: the closest candidate left
+	// is farther than the furthest of the ef nearest elements found
+	StopBound
+	// StopExhausted ends the beam search of layer 0: every reachable node
+	// was evaluated, which means ef is large compared to the graph or the
+	// filter accepted few nodes
+	StopExhausted
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopLocalMinimum:
+		return "local minimum"
+	case StopBound:
+		return "bound reached"
+	case StopExhausted:
+		return "candidates exhausted"
+	}
+	return fmt.Sprintf("StopReason(%d)", int(r))
+}
+
+// MarshalText encodes the reason as its description, so that traces are
+// readable once logged as JSON.
+func (r StopReason) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// SearchTrace records how SearchExplain found its results.
+type SearchTrace struct {
+	K  int `json:"k"`
+	Ef int `json:"ef"`
+
+	// EntryPoint is the node the search started from, or -1 for an empty index
+	EntryPoint int `json:"entry_point"`
+
+	// Layers holds the search of every layer, from the top to layer 0
+	Layers []LayerTrace `json:"layers"`
+
+	// Results are the nearest nodes found, closest first
+	Results []TraceNode `json:"results"`
+
+	// Visited and Distances are the totals over all the layers
+	Visited   int `json:"visited"`
+	Distances int `json:"distances"`
+
+	Elapsed time.Duration `json:"elapsed"`
+
+	// summary skips the paths and candidates, to only count the work done
+	summary bool
+}
+
+// LayerTrace records the search of one layer.
+type LayerTrace struct {
+	Level int `json:"level"`
+	Entry int `json:"entry"`
+
+	// Path is the sequence of nodes the greedy search moved through on an
+	// upper layer, from the entry to the closest node found
+	Path []int `json:"path,omitempty"`
+
+	// Candidates are the nodes taken from the candidate queue and expanded
+	// by the beam search of layer 0, in order
+	Candidates []TraceNode `json:"candidates,omitempty"`
+
+	// Visited is the number of nodes reached, and Distances the number of
+	// distances computed. On upper layers a node can be evaluated from
+	// several nodes of the path, and counts every time.
+	Visited   int `json:"visited"`
+	Distances int `json:"distances"`
+
+	// Rejected is the number of nodes traversed but not returned because
+	// they are deleted
+	Rejected int `json:"rejected,omitempty"`
+
+	Stop StopReason `json:"stop"`
+
+	summary bool
+}
+
+// TraceNode is a node with its distance to the query.
+type TraceNode struct {
+	ID       int     `json:"id"`
+	Distance float32 `json:"distance"`
+}
+
+// SearchExplain is KNN_Search, recording the path of the search: the greedy
+// descent through the upper layers, the candidates expanded on layer 0, the
+// work done on every layer and the reason each one stopped. Tracing adds
+// allocations, so it is meant for debugging single queries rather than for
+// serving.
+func (h *HNSW) SearchExplain(query []float32, K, ef int) *SearchTrace {
+	start := time.Now()
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	trace := &SearchTrace{K: K, Ef: max(ef, K), EntryPoint: -1}
+	if h.EntryPoint != nil {
+		trace.EntryPoint = h.EntryPoint.ID
+	}
+
+	results := h.search(query, K, ef, h.EntryPoint, h.deleted > 0, nil, trace)
+	for _, id := range results {
+		trace.Results = append(trace.Results, TraceNode{ID: id, Distance: h.DistanceFunc(query, h.Nodes[id].Vector)})
+	}
+	trace.Visited, trace.Distances = trace.totals()
+	trace.Elapsed = time.Since(start)
+	return trace
+}
+
+// layer starts the trace of a layer and returns it, or nil when not tracing
+func (t *SearchTrace) layer(level, entry int) *LayerTrace {
+	if t == nil {
+		return nil
+	}
+	t.Layers = append(t.Layers, LayerTrace{Level: level, Entry: entry, summary: t.summary})
+	return &t.Layers[len(t.Layers)-1]
+}
+
+// totals returns the nodes visited and the distances computed on all layers
+func (t *SearchTrace) totals() (visited, distances int) {
+	for _, layer := range t.Layers {
+		visited += layer.Visited
+		distances += layer.Distances
+	}
+	return visited, distances
+}
@@ -0,0 +1,110 @@
+package hnsw
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSearchExplain(t *testing.T) {
+	h := buildRandomIndex(t, 1000, 8)
+	query := []float32{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+
+	trace := h.SearchExplain(query, 10, 50)
+	expected := h.KNN_Search(query, 10, 50)
+	if len(trace.Results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(trace.Results))
+	}
+	for i, r := range trace.Results {
+		if r.ID != expected[i] {
+			t.Errorf("Result %d: expected %d, got %d", i, expected[i], r.ID)
+		}
+		if i > 0 && r.Distance < trace.Results[i-1].Distance {
+			t.Errorf("Expected results sorted by distance, got %v", trace.Results)
+		}
+	}
+
+	if trace.K != 10 || trace.Ef != 50 || trace.EntryPoint != h.EntryPoint.ID {
+		t.Errorf("Expected K 10, ef 50 and entry point %d, got %d, %d and %d", h.EntryPoint.ID, trace.K, trace.Ef, trace.EntryPoint)
+	}
+	if len(trace.Layers) != h.EntryPoint.Level+1 {
+		t.Fatalf("Expected %d layers, got %d", h.EntryPoint.Level+1, len(trace.Layers))
+	}
+
+	// Each greedy descent starts where the previous one ended
+	entry, visited, distances := h.EntryPoint.ID, 0, 0
+	for i, layer := range trace.Layers {
+		if layer.Level != h.EntryPoint.Level-i {
+			t.Errorf("Layer %d: expected level %d, got %d", i, h.EntryPoint.Level-i, layer.Level)
+		}
+		if layer.Entry != entry {
+			t.Errorf("Level %d: expected entry %d, got %d", layer.Level, entry, layer.Entry)
+		}
+		visited += layer.Visited
+		distances += layer.Distances
+
+		if layer.Level > 0 {
+			if layer.Stop != StopLocalMinimum || len(layer.Path) == 0 || layer.Path[0] != entry {
+				t.Errorf("Level %d: expected a path from %d to a local minimum, got %v (%v)", layer.Level, entry, layer.Path, layer.Stop)
+			}
+			entry = layer.Path[len(layer.Path)-1]
+			continue
+		}
+
+		if layer.Stop != StopBound {
+			t.Errorf("Level 0: expected %v, got %v", StopBound, layer.Stop)
+		}
+		if len(layer.Candidates) == 0 || layer.Candidates[0].ID != entry {
+			t.Errorf("Level 0: expected candidates starting from %d, got %v", entry, layer.Candidates)
+		}
+		if layer.Visited < 50 || layer.Distances != layer.Visited {
+			t.Errorf("Level 0: expected at least 50 visited nodes with one distance each, got %d and %d", layer.Visited, layer.Distances)
+		}
+	}
+	if trace.Visited != visited || trace.Distances != distances {
+		t.Errorf("Expected totals %d and %d, got %d and %d", visited, distances, trace.Visited, trace.Distances)
+	}
+
+	data, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"stop":"bound reached"`) {
+		t.Errorf("Expected the stop reason as text, got %s", data)
+	}
+}
+
+func TestSearchExplainExhausted(t *testing.T) {
+	h := buildRandomIndex(t, 100, 4)
+	if err := h.Delete(5); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// With ef above the index size, every node is evaluated
+	trace := h.SearchExplain(h.Nodes[5].Vector, 5, 200)
+	layer := trace.Layers[len(trace.Layers)-1]
+	if layer.Stop != StopExhausted {
+		t.Errorf("Expected %v, got %v", StopExhausted, layer.Stop)
+	}
+	if layer.Visited != 100 {
+		t.Errorf("Expected 100 visited nodes, got %d", layer.Visited)
+	}
+	if layer.Rejected != 1 {
+		t.Errorf("Expected the deleted node to be rejected, got %d", layer.Rejected)
+	}
+	if slices.ContainsFunc(trace.Results, func(n TraceNode) bool { return n.ID == 5 }) {
+		t.Errorf("Expected the deleted node not to be returned, got %v", trace.Results)
+	}
+}
+
+func TestSearchExplainEmpty(t *testing.T) {
+	h, err := NewHNSW(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	trace := h.SearchExplain([]float32{1, 2}, 5, 10)
+	if trace.EntryPoint != -1 || len(trace.Layers) != 0 || len(trace.Results) != 0 {
+		t.Errorf("Expected an empty trace, got %+v", trace)
+	}
+}
@@ -0,0 +1,259 @@
+package hnsw
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// GraphFormat identifies a file format for Export.
+type GraphFormat int
+
+const (
+	// DOT is the Graphviz language, with one cluster per layer
+	DOT GraphFormat = iota + 1
+	// GraphML is the XML format read by Gephi, yEd and networkx
+	GraphML
+	// EdgeList is a CSV file with one edge per line
+	EdgeList
+)
+
+// String returns the usual file extension of the format, without the dot.
+func (f GraphFormat) String() string {
+	switch f {
+	case DOT:
+		return "dot"
+	case GraphML:
+		return "graphml"
+	case EdgeList:
+		return "csv"
+	}
+	return fmt.Sprintf("GraphFormat(%d)", int(f))
+}
+
+// GraphFormatOf returns the graph format of a file from its extension.
+func GraphFormatOf(path string) (GraphFormat, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dot", ".gv":
+		return DOT, nil
+	case ".graphml":
+		return GraphML, nil
+	case ".csv":
+		return EdgeList, nil
+	}
+	return 0, fmt.Errorf("unknown graph format for %s", path)
+}
+
+// ExportOptions selects the part of the graph written by Export.
+type ExportOptions struct {
+	// Levels are the layers to export; all of them when empty
+	Levels []int
+
+	// Sample is the maximum number of nodes picked at random on each layer,
+	// or 0 to export every node. The neighbors of the picked nodes are
+	// exported too, so that every edge has both ends.
+	Sample int
+
+	// Seed makes the sample reproducible
+	Seed uint64
+
+	// Distances adds the length of every edge
+	Distances bool
+}
+
+// exportLayer is the part of a layer written by Export
+type exportLayer struct {
+	level int
+	nodes []exportNode
+	edges []exportEdge
+}
+
+type exportNode struct {
+	id, level int
+	deleted   bool
+}
+
+type exportEdge struct {
+	from, to int
+	dist     float32
+}
+
+// Export writes the graph in the given format, layer by layer: the nodes of
+// a layer are the nodes whose Level is at least the layer, and the edges
+// are their neighbor lists on that layer. Edges are directed, since
+// neighbor lists are not symmetric after pruning. Nodes carry their ID,
+// their Level and whether they are deleted.
+//
+// A node appears once per exported layer, with a name made of the layer and
+// its ID, such as "L0_42".
+func (h *HNSW) Export(w io.Writer, format GraphFormat, opts ExportOptions) error {
+	var write func(*bufio.Writer, []exportLayer, bool)
+	switch format {
+	case DOT:
+		write = writeDOT
+	case GraphML:
+		write = writeGraphML
+	case EdgeList:
+		write = writeEdgeList
+	default:
+		return fmt.Errorf("unknown graph format %v", format)
+	}
+	if opts.Sample < 0 {
+		return fmt.Errorf("invalid sample size %d", opts.Sample)
+	}
+
+	layers, err := h.exportLayers(opts)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	write(bw, layers, opts.Distances)
+	return bw.Flush()
+}
+
+// exportLayers collects the nodes and edges to export while holding the
+// read lock, so that writing does not block the index
+func (h *HNSW) exportLayers(opts ExportOptions) ([]exportLayer, error) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	top := -1
+	for _, node := range h.Nodes {
+		top = max(top, node.Level)
+	}
+	levels := opts.Levels
+	if len(levels) == 0 {
+		for level := top; level >= 0; level-- {
+			levels = append(levels, level)
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, uint64(len(h.Nodes))))
+	layers := make([]exportLayer, 0, len(levels))
+	for _, level := range levels {
+		if level < 0 || level > top {
+			return nil, fmt.Errorf("level %d out of range [0, %d]", level, top)
+		}
+
+		var ids []int
+		for _, node := range h.Nodes {
+			if node.Level >= level {
+				ids = append(ids, node.ID)
+			}
+		}
+		if opts.Sample > 0 && len(ids) > opts.Sample {
+			rng.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+			ids = ids[:opts.Sample]
+			slices.Sort(ids)
+		}
+
+		layer := exportLayer{level: level}
+		included := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			included[id] = true
+		}
+		for _, id := range ids {
+			node := h.Nodes[id]
+			if level >= len(node.Neighbors) {
+				continue
+			}
+			for _, n := range node.Neighbors[level] {
+				edge := exportEdge{from: id, to: int(n)}
+				if opts.Distances {
+					edge.dist = h.DistanceFunc(node.Vector, h.Nodes[n].Vector)
+				}
+				layer.edges = append(layer.edges, edge)
+				if !included[int(n)] {
+					included[int(n)] = true
+					ids = append(ids, int(n))
+				}
+			}
+		}
+
+		slices.Sort(ids)
+		for _, id := range ids {
+			node := h.Nodes[id]
+			layer.nodes = append(layer.nodes, exportNode{id: id, level: node.Level, deleted: node.Deleted})
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// exportName is the name of a node on a layer
+func exportName(level, id int) string {
+	return fmt.Sprintf("L%d_%d", level, id)
+}
+
+func writeDOT(w *bufio.Writer, layers []exportLayer, distances bool) {
+	fmt.Fprintln(w, "digraph hnsw {")
+	fmt.Fprintln(w, "\tnode [shape=circle];")
+	for _, layer := range layers {
+		fmt.Fprintf(w, "\tsubgraph cluster_L%d {\n", layer.level)
+		fmt.Fprintf(w, "\t\tlabel=\"layer %d\";\n", layer.level)
+		for _, node := range layer.nodes {
+			style := ""
+			if node.deleted {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(w, "\t\t%s [label=\"%d\", level=%d%s];\n", exportName(layer.level, node.id), node.id, node.level, style)
+		}
+		for _, e := range layer.edges {
+			fmt.Fprintf(w, "\t\t%s -> %s", exportName(layer.level, e.from), exportName(layer.level, e.to))
+			if distances {
+				fmt.Fprintf(w, " [distance=%g]", e.dist)
+			}
+			fmt.Fprintln(w, ";")
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func writeGraphML(w *bufio.Writer, layers []exportLayer, distances bool) {
+	fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(w, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(w, `  <key id="id" for="node" attr.name="id" attr.type="int"/>`)
+	fmt.Fprintln(w, `  <key id="level" for="node" attr.name="level" attr.type="int"/>`)
+	fmt.Fprintln(w, `  <key id="deleted" for="node" attr.name="deleted" attr.type="boolean"/>`)
+	fmt.Fprintln(w, `  <key id="layer" for="all" attr.name="layer" attr.type="int"/>`)
+	fmt.Fprintln(w, `  <key id="distance" for="edge" attr.name="distance" attr.type="float"/>`)
+	fmt.Fprintln(w, `  <graph id="hnsw" edgedefault="directed">`)
+	for _, layer := range layers {
+		for _, node := range layer.nodes {
+			fmt.Fprintf(w, "    <node id=%q><data key=\"id\">%d</data><data key=\"level\">%d</data><data key=\"deleted\">%t</data><data key=\"layer\">%d</data></node>\n",
+				exportName(layer.level, node.id), node.id, node.level, node.deleted, layer.level)
+		}
+		for _, e := range layer.edges {
+			fmt.Fprintf(w, "    <edge source=%q target=%q><data key=\"layer\">%d</data>", exportName(layer.level, e.from), exportName(layer.level, e.to), layer.level)
+			if distances {
+				fmt.Fprintf(w, "<data key=\"distance\">%g</data>", e.dist)
+			}
+			fmt.Fprintln(w, "</edge>")
+		}
+	}
+	fmt.Fprintln(w, "  </graph>")
+	fmt.Fprintln(w, "</graphml>")
+}
+
+func writeEdgeList(w *bufio.Writer, layers []exportLayer, distances bool) {
+	if distances {
+		fmt.Fprintln(w, "layer,source,target,distance")
+	} else {
+		fmt.Fprintln(w, "layer,source,target")
+	}
+	for _, layer := range layers {
+		for _, e := range layer.edges {
+			if distances {
+				fmt.Fprintf(w, "%d,%d,%d,%g\n", layer.level, e.from, e.to, e.dist)
+			} else {
+				fmt.Fprintf(w, "%d,%d,%d\n", layer.level, e.from, e.to)
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package hnsw
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExportEdgeList(t *testing.T) {
+	h := buildRandomIndex(t, 300, 4)
+
+	var buf bytes.Buffer
+	if err := h.Export(&buf, EdgeList, ExportOptions{Distances: true}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read the edge list: %v", err)
+	}
+	if strings.Join(records[0], ",") != "layer,source,target,distance" {
+		t.Errorf("Unexpected header %v", records[0])
+	}
+
+	edges := 0
+	for _, node := range h.Nodes {
+		for _, neighbors := range node.Neighbors {
+			edges += len(neighbors)
+		}
+	}
+	if len(records)-1 != edges {
+		t.Errorf("Expected %d edges, got %d", edges, len(records)-1)
+	}
+
+	// Layers are written from the top
+	previous := h.EntryPoint.Level
+	for _, record := range records[1:] {
+		level, err := strconv.Atoi(record[0])
+		if err != nil || level > previous {
+			t.Fatalf("Expected layers in decreasing order, got %v after level %d", record, previous)
+		}
+		previous = level
+	}
+}
+
+func TestExportGraphML(t *testing.T) {
+	h := buildRandomIndex(t, 300, 4)
+	if err := h.Delete(1); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := h.Export(&buf, GraphML, ExportOptions{Levels: []int{0}, Sample: 20, Seed: 1}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var doc struct {
+		Graph struct {
+			Nodes []struct {
+				ID string `xml:"id,attr"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid GraphML: %v", err)
+	}
+
+	// The 20 sampled nodes, plus their neighbors
+	nodes := make(map[string]bool)
+	for _, n := range doc.Graph.Nodes {
+		if !strings.HasPrefix(n.ID, "L0_") {
+			t.Errorf("Expected only layer 0 nodes, got %s", n.ID)
+		}
+		nodes[n.ID] = true
+	}
+	if len(nodes) != len(doc.Graph.Nodes) || len(nodes) <= 20 || len(nodes) >= 300 {
+		t.Errorf("Expected 20 sampled nodes and their neighbors, got %d nodes", len(doc.Graph.Nodes))
+	}
+
+	sources := make(map[string]bool)
+	for _, e := range doc.Graph.Edges {
+		if !nodes[e.Source] || !nodes[e.Target] {
+			t.Errorf("Edge %s -> %s refers to a missing node", e.Source, e.Target)
+		}
+		sources[e.Source] = true
+	}
+	if len(sources) != 20 {
+		t.Errorf("Expected edges from the 20 sampled nodes, got %d sources", len(sources))
+	}
+
+	// The same seed picks the same sample
+	var again bytes.Buffer
+	if err := h.Export(&again, GraphML, ExportOptions{Levels: []int{0}, Sample: 20, Seed: 1}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Errorf("Expected the same export for the same seed")
+	}
+}
+
+func TestExportDOT(t *testing.T) {
+	h := buildRandomIndex(t, 100, 4)
+	if err := h.Delete(3); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := h.Export(&buf, DOT, ExportOptions{}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "digraph hnsw {") || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("Expected a digraph, got:\n%s", out)
+	}
+	if n := strings.Count(out, "subgraph cluster_L"); n != h.EntryPoint.Level+1 {
+		t.Errorf("Expected %d clusters, got %d", h.EntryPoint.Level+1, n)
+	}
+	if !strings.Contains(out, `L0_3 [label="3", level=`) || !strings.Contains(out, "style=dashed") {
+		t.Errorf("Expected node 3 to be drawn as deleted")
+	}
+	if n := strings.Count(out, " -> "); n == 0 {
+		t.Errorf("Expected edges")
+	}
+}
+
+func TestExportInvalid(t *testing.T) {
+	h := buildRandomIndex(t, 50, 4)
+
+	tests := []struct {
+		name   string
+		format GraphFormat
+		opts   ExportOptions
+	}{
+		{"unknown format", GraphFormat(0), ExportOptions{}},
+		{"level above the top", DOT, ExportOptions{Levels: []int{10}}},
+		{"negative level", DOT, ExportOptions{Levels: []int{-1}}},
+		{"negative sample", DOT, ExportOptions{Sample: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := h.Export(&bytes.Buffer{}, tt.format, tt.opts); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestGraphFormatOf(t *testing.T) {
+	tests := []struct {
+		path   string
+		format GraphFormat
+		ok     bool
+	}{
+		{"graph.dot", DOT, true},
+		{"graph.gv", DOT, true},
+		{"graph.GraphML", GraphML, true},
+		{"edges.csv", EdgeList, true},
+		{"graph.txt", 0, false},
+	}
+	for _, tt := range tests {
+		format, err := GraphFormatOf(tt.path)
+		if (err == nil) != tt.ok || format != tt.format {
+			t.Errorf("%s: expected %v (%v), got %v (%v)", tt.path, tt.format, tt.ok, format, err)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package hnsw
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// Serialized flat index layout (all integers little endian)
+//
+//	header   32 bytes: magic, version, dim, count and deleted count
+//	vectors  count*dim float32
+//	flags    count uint32, 1 marks deleted nodes
+const (
+	flatMagic      = "hnswflat"
+	flatVersion    = 1
+	flatHeaderSize = 32
+)
+
+// flatBlock is the number of vectors whose distances are computed before
+// they are merged into the results
+const flatBlock = 256
+
+// FlatIndex is an exact nearest-neighbor index: a search compares the query
+// with every vector. It is faster than HNSW for small collections, needs no
+// construction, and gives the ground truth HNSW results are measured against.
+//
+// The vectors are stored back to back in a single []float32, and a search
+// computes the distances of a whole block of vectors in a tight loop before
+// merging them into the results, which keeps the distance function on
+// sequential memory where the compiler and the CPU can stream it.
+//
+// Node IDs are assigned in insertion order as in HNSW, and deleted nodes are
+// never returned. A FlatIndex is safe for concurrent use by multiple
+// goroutines.
+type FlatIndex struct {
+	// DistanceFunc computes the distance between two vectors
+	DistanceFunc func(a, b []float32) float32
+
+	mutex   sync.RWMutex
+	dim     int
+	vectors []float32
+	deleted []bool
+
+	// removed is the number of deleted nodes
+	removed int
+}
+
+// NewFlatIndex creates an empty FlatIndex using the given distance function.
+func NewFlatIndex(distanceFunc func(a, b []float32) float32) *FlatIndex {
+	return &FlatIndex{DistanceFunc: distanceFunc}
+}
+
+// Insert adds a vector to the index. As with HNSW, id must be the number of
+// nodes inserted before it, deleted ones included.
+func (f *FlatIndex) Insert(vector []float32, id int) {
+	if len(vector) == 0 {
+		panic("vector cannot be empty")
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.dim == 0 {
+		f.dim = len(vector)
+	}
+	if len(vector) != f.dim {
+		panic("vector dimension mismatch")
+	}
+	if id != len(f.deleted) {
+		panic(fmt.Sprintf("flat index: id %d inserted as node %d", id, len(f.deleted)))
+	}
+	f.vectors = append(f.vectors, vector...)
+	f.deleted = append(f.deleted, false)
+}
+
+// Delete marks the node with the given ID as deleted. Its vector stays in
+// the index, which keeps the IDs of the following nodes stable.
+func (f *FlatIndex) Delete(id int) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if id < 0 || id >= len(f.deleted) || f.deleted[id] {
+		return ErrNodeNotFound
+	}
+	f.deleted[id] = true
+	f.removed++
+	return nil
+}
+
+// Vector returns a copy of the vector of the node with the given ID, or
+// ErrNodeNotFound if the node does not exist or has been deleted.
+func (f *FlatIndex) Vector(id int) ([]float32, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	if id < 0 || id >= len(f.deleted) || f.deleted[id] {
+		return nil, ErrNodeNotFound
+	}
+	return append([]float32(nil), f.vectors[id*f.dim:(id+1)*f.dim]...), nil
+}
+
+// KNN_Search returns the IDs of the K nodes closest to the query, sorted by
+// distance. The search is exact, so ef is ignored.
+func (f *FlatIndex) KNN_Search(query []float32, K, ef int) []int {
+	return f.KNN_SearchFilter(query, K, ef, nil)
+}
+
+// KNN_SearchFilter is KNN_Search restricted to the nodes for which filter
+// returns true. A nil filter accepts every node.
+//
+// filter is called with the read lock held and must not use the index.
+func (f *FlatIndex) KNN_SearchFilter(query []float32, K, ef int, filter func(id int) bool) []int {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	if K <= 0 || len(f.deleted) == 0 {
+		return nil
+	}
+	if len(query) != f.dim {
+		panic("vector dimension mismatch")
+	}
+
+	// nearest holds the K closest nodes found so far, the furthest on top
+	nearest := structs.NewMaxHeap()
+	defer nearest.Reset()
+	dists := make([]float32, flatBlock)
+
+	for start := 0; start < len(f.deleted); start += flatBlock {
+		n := min(flatBlock, len(f.deleted)-start)
+		block := f.vectors[start*f.dim : (start+n)*f.dim]
+		for i := range n {
+			dists[i] = f.DistanceFunc(query, block[i*f.dim:(i+1)*f.dim])
+		}
+
+		for i, dist := range dists[:n] {
+			id := start + i
+			if f.deleted[id] || (nearest.Len() == K && dist >= nearest.Peek().Dist) {
+				continue
+			}
+			if filter != nil && !filter(id) {
+				continue
+			}
+			nearest.Push(structs.NewNodeHeap(dist, id))
+			if nearest.Len() > K {
+				nearest.Pop()
+			}
+		}
+	}
+
+	results := make([]int, nearest.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = nearest.Pop().Id
+	}
+	return results
+}
+
+// Dim returns the dimension of the indexed vectors, or 0 if the index is empty.
+func (f *FlatIndex) Dim() int {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.dim
+}
+
+// Len returns the number of nodes in the index, excluding deleted ones.
+func (f *FlatIndex) Len() int {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return len(f.deleted) - f.removed
+}
+
+// Save writes the index to w in the binary format described above.
+func (f *FlatIndex) Save(w io.Writer) error {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	b := make([]byte, flatHeaderSize)
+	copy(b[0:8], flatMagic)
+	binary.LittleEndian.PutUint32(b[8:], flatVersion)
+	binary.LittleEndian.PutUint32(b[12:], uint32(f.dim))
+	binary.LittleEndian.PutUint64(b[16:], uint64(len(f.deleted)))
+	binary.LittleEndian.PutUint64(b[24:], uint64(f.removed))
+
+	bw := bufio.NewWriter(w)
+	enc := &encoder{w: bw}
+	enc.write(b)
+	enc.float32s(f.vectors)
+	for _, deleted := range f.deleted {
+		if deleted {
+			enc.uint32(1)
+		} else {
+			enc.uint32(0)
+		}
+	}
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return bw.Flush()
+}
+
+// LoadFlat reads an index written by FlatIndex.Save. Since functions cannot
+// be serialized, the distance function is given again.
+func LoadFlat(r io.Reader, distanceFunc func(a, b []float32) float32) (*FlatIndex, error) {
+	size, sized := remaining(r)
+	br := bufio.NewReader(r)
+	b := make([]byte, flatHeaderSize)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIndex, err)
+	}
+	if string(b[0:8]) != flatMagic {
+		return nil, ErrInvalidIndex
+	}
+	if v := binary.LittleEndian.Uint32(b[8:]); v != flatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidIndex, v)
+	}
+	dim := int(binary.LittleEndian.Uint32(b[12:]))
+	count := binary.LittleEndian.Uint64(b[16:])
+	removed := binary.LittleEndian.Uint64(b[24:])
+	if count > uint64(noEntryPoint) || removed > count || (count > 0) != (dim > 0) {
+		return nil, fmt.Errorf("%w: invalid header", ErrInvalidIndex)
+	}
+	words, ok := sectionWords([2]uint64{count, uint64(dim)}, [2]uint64{count, 1})
+	if !ok {
+		return nil, fmt.Errorf("%w: sections too large", ErrInvalidIndex)
+	}
+	if sized && uint64(size) < flatHeaderSize+4*words {
+		return nil, fmt.Errorf("%w: file is truncated", ErrInvalidIndex)
+	}
+
+	dec := &decoder{r: br}
+	vectors := dec.float32s(int(count) * dim)
+	flags := dec.uint32s(int(count))
+	if dec.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIndex, dec.err)
+	}
+
+	f := NewFlatIndex(distanceFunc)
+	f.dim = dim
+	f.vectors = vectors
+	f.deleted = make([]bool, count)
+	for i, flag := range flags {
+		f.deleted[i] = flag != 0
+		if f.deleted[i] {
+			f.removed++
+		}
+	}
+	if uint64(f.removed) != removed {
+		return nil, fmt.Errorf("%w: %d deleted nodes, header says %d", ErrInvalidIndex, f.removed, removed)
+	}
+	return f, nil
+}
+
+// SaveFile writes the index to the file at path, replacing it atomically.
+func (f *FlatIndex) SaveFile(path string) error {
+	return writeFileAtomic(path, f.Save)
+}
+
+// LoadFlatFile reads a flat index from the file at path.
+func LoadFlatFile(path string, distanceFunc func(a, b []float32) float32) (*FlatIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadFlat(file, distanceFunc)
+}
@@ -0,0 +1,184 @@
+package hnsw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// buildFlatIndex inserts n random vectors of the given dimension into a new
+// flat index, and returns them too
+func buildFlatIndex(t testing.TB, n, dim int) (*FlatIndex, [][]float32) {
+	t.Helper()
+	f := NewFlatIndex(EuclideanDistance)
+	rng := rand.New(rand.NewPCG(1, 2))
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = rng.Float32()
+		}
+		f.Insert(vectors[i], i)
+	}
+	return f, vectors
+}
+
+// exactNeighbors returns the IDs of the K vectors closest to the query
+func exactNeighbors(vectors [][]float32, query []float32, K int, skip func(id int) bool) []int {
+	ids := make([]int, 0, len(vectors))
+	for i := range vectors {
+		if skip == nil || !skip(i) {
+			ids = append(ids, i)
+		}
+	}
+	sort.SliceStable(ids, func(a, b int) bool {
+		return EuclideanDistance(query, vectors[ids[a]]) < EuclideanDistance(query, vectors[ids[b]])
+	})
+	return ids[:min(K, len(ids))]
+}
+
+func TestFlatSearch(t *testing.T) {
+	// More vectors than a block, and not a multiple of it
+	f, vectors := buildFlatIndex(t, 700, 8)
+	if f.Len() != 700 || f.Dim() != 8 {
+		t.Fatalf("Expected 700 vectors of dimension 8, got %d of dimension %d", f.Len(), f.Dim())
+	}
+
+	rng := rand.New(rand.NewPCG(3, 4))
+	for q := 0; q < 20; q++ {
+		query := make([]float32, 8)
+		for j := range query {
+			query[j] = rng.Float32()
+		}
+		want := exactNeighbors(vectors, query, 10, nil)
+		if got := f.KNN_Search(query, 10, 0); !slices.Equal(got, want) {
+			t.Fatalf("Query %d: expected %v, got %v", q, want, got)
+		}
+	}
+
+	if got := f.KNN_Search(vectors[42], 1, 0); len(got) != 1 || got[0] != 42 {
+		t.Errorf("Expected a vector to be its own nearest neighbor, got %v", got)
+	}
+	if got := f.KNN_Search(vectors[0], 1000, 0); len(got) != 700 {
+		t.Errorf("Expected every vector for K above the size, got %d", len(got))
+	}
+	if got := NewFlatIndex(EuclideanDistance).KNN_Search([]float32{1}, 5, 0); got != nil {
+		t.Errorf("Expected no results from an empty index, got %v", got)
+	}
+}
+
+func TestFlatDeleteFilter(t *testing.T) {
+	f, vectors := buildFlatIndex(t, 100, 4)
+
+	if err := f.Delete(42); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	for _, id := range []int{42, -1, 100} {
+		if err := f.Delete(id); !errors.Is(err, ErrNodeNotFound) {
+			t.Errorf("Delete(%d): expected ErrNodeNotFound, got %v", id, err)
+		}
+	}
+	if _, err := f.Vector(42); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound for a deleted vector, got %v", err)
+	}
+	if f.Len() != 99 {
+		t.Errorf("Expected 99 vectors, got %d", f.Len())
+	}
+	if got := f.KNN_Search(vectors[42], 1, 0); got[0] == 42 {
+		t.Errorf("Expected the deleted vector not to be returned")
+	}
+
+	even := func(id int) bool { return id%2 == 0 }
+	want := exactNeighbors(vectors, vectors[7], 5, func(id int) bool { return id == 42 || !even(id) })
+	if got := f.KNN_SearchFilter(vectors[7], 5, 0, even); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// IDs stay stable after a delete
+	f.Insert([]float32{0.5, 0.5, 0.5, 0.5}, 100)
+	if v, err := f.Vector(100); err != nil || v[0] != 0.5 {
+		t.Errorf("Expected the vector inserted after a delete, got %v, %v", v, err)
+	}
+}
+
+func TestFlatSaveLoad(t *testing.T) {
+	f, vectors := buildFlatIndex(t, 300, 8)
+	f.Delete(3)
+
+	path := filepath.Join(t.TempDir(), "index.flat")
+	if err := f.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+	loaded, err := LoadFlatFile(path, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("LoadFlatFile failed: %v", err)
+	}
+	if loaded.Len() != 299 || loaded.Dim() != 8 {
+		t.Errorf("Expected 299 vectors of dimension 8, got %d of dimension %d", loaded.Len(), loaded.Dim())
+	}
+	if got, want := loaded.KNN_Search(vectors[10], 10, 0), f.KNN_Search(vectors[10], 10, 0); !slices.Equal(got, want) {
+		t.Errorf("Expected %v after load, got %v", want, got)
+	}
+
+	var buf bytes.Buffer
+	if err := NewFlatIndex(EuclideanDistance).Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	empty, err := LoadFlat(&buf, EuclideanDistance)
+	if err != nil || empty.Len() != 0 {
+		t.Errorf("Expected an empty index, got %v", err)
+	}
+}
+
+func TestLoadFlatInvalid(t *testing.T) {
+	f, _ := buildFlatIndex(t, 10, 4)
+	var buf bytes.Buffer
+	if err := f.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	corrupt := func(edit func(b []byte) []byte) []byte {
+		return edit(bytes.Clone(data))
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", corrupt(func(b []byte) []byte { b[0] = 'x'; return b })},
+		{"bad version", corrupt(func(b []byte) []byte { b[8] = 9; return b })},
+		{"truncated", data[:len(data)-4]},
+		{"deleted count", corrupt(func(b []byte) []byte { b[24] = 1; return b })},
+		{"overflowing sections", corrupt(func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[12:], math.MaxUint32)
+			binary.LittleEndian.PutUint64(b[16:], math.MaxUint32)
+			return b
+		})},
+		{"oversized sections", corrupt(func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[12:], math.MaxUint32)
+			binary.LittleEndian.PutUint64(b[16:], 1<<24)
+			return b
+		})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadFlat(bytes.NewReader(tt.data), EuclideanDistance); !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex, got %v", err)
+			}
+
+			// Without a known size, the data runs out before the sections
+			if _, err := LoadFlat(io.MultiReader(bytes.NewReader(tt.data)), EuclideanDistance); !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex from a stream, got %v", err)
+			}
+		})
+	}
+}
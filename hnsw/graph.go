@@ -0,0 +1,210 @@
+package hnsw
+
+import (
+	"fmt"
+	"math"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// packedGraph is the graph of a serialized index kept in its on-disk layout:
+// levels, fixed-stride layer 0 blocks and upper-layer blocks. The sections
+// may be views into a memory-mapped file or plain slices read into memory.
+type packedGraph struct {
+	header indexHeader
+
+	levels       []uint32
+	level0       []uint32
+	upperOffsets []uint32
+	upper        []uint32
+}
+
+// visitedList tracks the nodes seen by a single search using a version
+// stamp. Every index keeps a pool of them, one per concurrent search.
+type visitedList struct {
+	stamp uint32
+	marks []uint32
+}
+
+// next starts a new search, invalidating all the previous marks
+func (v *visitedList) next() {
+	v.stamp++
+	if v.stamp == 0 {
+		// The stamp wrapped around: old marks could collide with new ones
+		clear(v.marks)
+		v.stamp = 1
+	}
+}
+
+// visit marks id as visited and reports whether it had already been visited
+func (v *visitedList) visit(id int) bool {
+	if v.marks[id] == v.stamp {
+		return true
+	}
+	v.marks[id] = v.stamp
+	return false
+}
+
+// splitGraph slices the graph sections of a serialized index out of words,
+// which must start right after the vectors section. The returned graph
+// aliases words.
+func splitGraph(hd indexHeader, words []uint32) packedGraph {
+	offset := 0
+	section := func(n int) []uint32 {
+		s := words[offset : offset+n : offset+n]
+		offset += n
+		return s
+	}
+
+	count := int(hd.Count)
+	return packedGraph{
+		header:       hd,
+		levels:       section(count),
+		level0:       section(count * (1 + int(hd.Mmax0))),
+		upperOffsets: section(count),
+		upper:        section(int(hd.UpperBlocks) * (1 + int(hd.Mmax))),
+	}
+}
+
+// validate checks the levels, upper-layer offsets and neighbor IDs of every
+// node, which the search methods use without bounds checks. It reads each
+// neighbor list once.
+func (g *packedGraph) validate() error {
+	count := int(g.header.Count)
+	for id := 0; id < count; id++ {
+		level := g.level(id)
+		if level > int(g.header.MaxLevel) {
+			return fmt.Errorf("%w: node %d level %d exceeds MaxLevel", ErrInvalidIndex, id, level)
+		}
+		if level > 0 && uint64(g.upperOffsets[id])+uint64(level) > g.header.UpperBlocks {
+			return fmt.Errorf("%w: upper links of node %d out of range", ErrInvalidIndex, id)
+		}
+		for lc := 0; lc <= level; lc++ {
+			for _, neighborID := range g.neighbors(id, lc) {
+				if int(neighborID) >= count {
+					return fmt.Errorf("%w: node %d links to unknown node %d", ErrInvalidIndex, id, neighborID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// level returns the highest level of a node
+func (g *packedGraph) level(id int) int {
+	return int(g.levels[id] &^ deletedFlag)
+}
+
+// deleted reports whether a node is marked as deleted
+func (g *packedGraph) deleted(id int) bool {
+	return g.levels[id]&deletedFlag != 0
+}
+
+// neighbors returns the neighbor IDs of a node at the given level
+func (g *packedGraph) neighbors(id, level int) []uint32 {
+	if level > g.level(id) {
+		return nil
+	}
+
+	var block []uint32
+	if level == 0 {
+		stride := 1 + int(g.header.Mmax0)
+		block = g.level0[id*stride : (id+1)*stride]
+	} else {
+		stride := 1 + int(g.header.Mmax)
+		start := (int(g.upperOffsets[id]) + level - 1) * stride
+		block = g.upper[start : start+stride]
+	}
+
+	n := min(int(block[0]), len(block)-1)
+	return block[1 : 1+n]
+}
+
+// search runs the two-phase search of KNN_Search: a greedy descent through
+// the upper layers followed by a beam search of width ef at layer 0.
+// distance returns the distance between the query and a node.
+func (g *packedGraph) search(distance func(id int) float32, visited *visitedList, ef int) []int {
+	if g.header.Count == 0 {
+		return nil
+	}
+
+	entry := int(g.header.EntryPoint)
+	for lc := g.level(entry); lc > 0; lc-- {
+		entry = g.greedySearchLayer(distance, entry, lc)
+	}
+	return g.searchLayer(distance, visited, entry, ef, 0)
+}
+
+// greedySearchLayer moves to the first closer neighbor until no neighbor improves
+func (g *packedGraph) greedySearchLayer(distance func(id int) float32, entry, level int) int {
+	current := entry
+	bestDist := distance(current)
+
+	for improved := true; improved; {
+		improved = false
+		for _, neighborID := range g.neighbors(current, level) {
+			dist := distance(int(neighborID))
+			if dist < bestDist {
+				bestDist = dist
+				current = int(neighborID)
+				improved = true
+				break
+			}
+		}
+	}
+
+	return current
+}
+
+// searchLayer is the beam search of Algorithm 2 over the packed graph.
+// Results are sorted in ascending order of distance. As in HNSW.searchLayer,
+// deleted nodes are traversed but never returned.
+func (g *packedGraph) searchLayer(distance func(id int) float32, visited *visitedList, entry, ef, level int) []int {
+	visited.next()
+
+	candidates := structs.NewMinHeap()
+	nearest := structs.NewMaxHeap()
+
+	initialDist := distance(entry)
+	candidates.Push(structs.NewNodeHeap(initialDist, entry))
+	if !g.deleted(entry) {
+		nearest.Push(structs.NewNodeHeap(initialDist, entry))
+	}
+	visited.visit(entry)
+
+	hasDeletions := g.header.Deleted > 0
+	for candidates.Len() > 0 {
+		current := candidates.Pop()
+		furthestDist := float32(math.MaxFloat32)
+		if nearest.Len() > 0 {
+			furthestDist = nearest.Peek().Dist
+		}
+		if current.Dist > furthestDist && (!hasDeletions || nearest.Len() >= ef) {
+			break
+		}
+
+		for _, neighborID := range g.neighbors(current.Id, level) {
+			if visited.visit(int(neighborID)) {
+				continue
+			}
+
+			dist := distance(int(neighborID))
+			if dist < furthestDist || nearest.Len() < ef {
+				candidates.Push(structs.NewNodeHeap(dist, int(neighborID)))
+				if g.deleted(int(neighborID)) {
+					continue
+				}
+				nearest.Push(structs.NewNodeHeap(dist, int(neighborID)))
+				if nearest.Len() > ef {
+					nearest.Pop()
+				}
+			}
+		}
+	}
+
+	results := make([]int, nearest.Len())
+	for i := len(results) - 1; i >= 0; i-- {
+		results[i] = nearest.Pop().Id
+	}
+	return results
+}
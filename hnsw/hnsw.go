@@ -19,6 +19,10 @@ type HNSW struct {
 	// RandFunc provides random values for level generation
 	RandFunc func() float64
 
+	// LevelGenerator chooses the level of new nodes; Geometric is used
+	// when it is nil
+	LevelGenerator LevelGenerator
+
 	// M is the number of established connections on index construction
 	M int
 
@@ -31,6 +35,9 @@ type HNSW struct {
 	// mL is the normalization factor for level generation (1/ln(M))
 	mL float64
 
+	// seed is the Seed of the configuration, kept for ExtractTenant
+	seed uint64
+
 	// EfConstruction controls the quality of index construction
 	// Higher values provide better quality at the cost of longer construction time
 	EfConstruction int
@@ -47,11 +54,31 @@ type HNSW struct {
 	// mutex is used to synchronize access and write to the HNSW index
 	mutex sync.RWMutex
 
-	// Versioning counter for faster visited node check
-	visitStamp int
+	// visited holds reusable visited lists, one per concurrent search, so
+	// that searches holding only the read lock do not share state
+	visited sync.Pool
+
+	// storage allocates node vectors and neighbor lists from shared arenas
+	storage *structs.Storage
+
+	// deleted is the number of nodes marked as deleted
+	deleted int
+
+	// dirty is a bitset of the nodes changed since the last Save or Checkpoint
+	dirty []uint64
+
+	// checkpointMutex serializes Save and Checkpoint, which reset dirty
+	// while holding only the read lock
+	checkpointMutex sync.Mutex
+
+	// tuning maps a recall target to the ef selected by AutoTune for each K
+	tuning map[float64]map[int]int
+
+	// Metrics, if not nil, receives measurements of the index operations
+	Metrics Metrics
 
-	// Pre-allocated array for tracking visited nodes
-	visitedIDs []int
+	// Tracer, if not nil, creates spans around the index operations
+	Tracer Tracer
 }
 
 // Config holds the configuration parameters for HNSW construction
@@ -73,6 +100,23 @@ type Config struct {
 
 	// DistanceFunc is the distance function to use
 	DistanceFunc func([]float32, []float32) float32
+
+	// Seed, if not zero, seeds the generator of the node levels. Inserting
+	// the same vectors in the same order from a single goroutine then
+	// builds the same graph, which Save writes byte for byte identically,
+	// as long as the architecture and the Go version are the same: the
+	// compiler may fuse multiply-adds in the distance functions, as it
+	// does on arm64, which rounds distances differently and can change
+	// the graph. Zero uses the global random source.
+	//
+	// The seed is not serialized: an index read by Load or LoadFile draws
+	// the levels of later insertions from the global random source, unless
+	// RandFunc is set again.
+	Seed uint64
+
+	// LevelGenerator chooses the level of new nodes. Nil uses Geometric,
+	// the distribution of the paper. It is not serialized.
+	LevelGenerator LevelGenerator
 }
 
 // DefaultConfig returns a Config with recommended default values
@@ -103,8 +147,13 @@ func NewHNSW(cfg Config) (*HNSW, error) {
 		MaxLevel:       cfg.MaxLevel,
 		DistanceFunc:   cfg.DistanceFunc,
 		RandFunc:       rand.Float64,
-		visitStamp:     0,
-		visitedIDs:     make([]int, cfg.EfConstruction),
+		LevelGenerator: cfg.LevelGenerator,
+		seed:           cfg.Seed,
+		storage:        structs.NewStorage(cfg.Mmax, cfg.Mmax0),
+	}
+	if cfg.Seed != 0 {
+		// Insertions hold the write lock, so the generator is never shared
+		h.RandFunc = rand.New(rand.NewPCG(cfg.Seed, cfg.Seed)).Float64
 	}
 
 	return h, nil
@@ -154,20 +203,36 @@ func (h *HNSW) RandomLevel() int {
 	return level
 }
 
-// markVisited marks a node as visited during the search process
-func (h *HNSW) markVisited(id int) bool {
-	// Exppand the visitedIDs slice if necessary
-	if id >= len(h.visitedIDs) {
-		newSize := id * 2
-		newVisited := make([]int, newSize)
-		copy(newVisited, h.visitedIDs)
-		h.visitedIDs = newVisited
-	}
+// Dim returns the dimension of the indexed vectors, or 0 if the index is empty.
+func (h *HNSW) Dim() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.storage.Vectors.Dim()
+}
 
-	if h.visitedIDs[id] == h.visitStamp {
-		return true
+// Len returns the number of nodes in the index, excluding deleted ones.
+func (h *HNSW) Len() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.Nodes) - h.deleted
+}
+
+// markDirty records that the vector or the neighbor lists of a node changed
+func (h *HNSW) markDirty(id int) {
+	word := id / 64
+	if word >= len(h.dirty) {
+		h.dirty = append(h.dirty, make([]uint64, word-len(h.dirty)+1)...)
 	}
+	h.dirty[word] |= 1 << (id % 64)
+}
 
-	h.visitedIDs[id] = h.visitStamp
-	return false
+// getVisited returns a visited list large enough for every node in the
+// index. It must be returned with h.visited.Put when the search is done.
+func (h *HNSW) getVisited() *visitedList {
+	visited, _ := h.visited.Get().(*visitedList)
+	if visited == nil || len(visited.marks) < len(h.Nodes) {
+		// Leave room for growth, so the list is not reallocated on every insertion
+		visited = &visitedList{marks: make([]uint32, 2*len(h.Nodes))}
+	}
+	return visited
 }
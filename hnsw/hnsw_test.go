@@ -1,12 +1,21 @@
 package hnsw
 
 import (
+	"bytes"
 	"errors"
+	"flag"
 	"fmt"
 	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
 	"testing"
 )
 
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 
@@ -137,3 +146,117 @@ func TestRandomLevel(t *testing.T) {
 		}
 	})
 }
+
+// buildSeeded inserts n vectors, generated from a fixed seed, into an index
+// built with the given level seed, and returns the serialized index
+func buildSeeded(t *testing.T, seed uint64, n, dim int) []byte {
+	t.Helper()
+	h, err := NewHNSW(Config{
+		M:              8,
+		Mmax:           8,
+		Mmax0:          16,
+		EfConstruction: 32,
+		MaxLevel:       4,
+		DistanceFunc:   EuclideanDistance,
+		Seed:           seed,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	rng := rand.New(rand.NewPCG(3, 4))
+	for i := 0; i < n; i++ {
+		vector := make([]float32, dim)
+		for j := range vector {
+			vector[j] = rng.Float32()
+		}
+		h.Insert(vector, i)
+	}
+
+	var buf bytes.Buffer
+	if err := h.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSeededBuild(t *testing.T) {
+	a := buildSeeded(t, 42, 500, 8)
+	b := buildSeeded(t, 42, 500, 8)
+	if !bytes.Equal(a, b) {
+		t.Errorf("Expected identical indexes for the same seed")
+	}
+	if c := buildSeeded(t, 43, 500, 8); bytes.Equal(a, c) {
+		t.Errorf("Expected different indexes for different seeds")
+	}
+}
+
+func TestSeededBuildGolden(t *testing.T) {
+	// Architectures such as arm64 fuse multiply-adds, which changes the
+	// rounding of the distances and therefore the graph
+	if runtime.GOARCH != "amd64" {
+		t.Skipf("golden file generated on amd64, running on %s", runtime.GOARCH)
+	}
+
+	got := buildSeeded(t, 42, 100, 4)
+	golden := filepath.Join("testdata", "seeded.hnsw")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatalf("Failed to create testdata: %v", err)
+		}
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatalf("Failed to update the golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read the golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Index differs from %s; run go test -run TestSeededBuildGolden -update if the format changed on purpose", golden)
+	}
+}
+
+// TestVisitedListsConcurrent verifies that searches holding only the read
+// lock each get their own visited list: concurrent searches must return
+// the same results as sequential ones. Run with -race to also check that
+// no state is shared.
+func TestVisitedListsConcurrent(t *testing.T) {
+	h := buildRandomIndex(t, 1000, 8)
+	rng := rand.New(rand.NewPCG(7, 8))
+	queries := make([][]float32, 32)
+	want := make([][]int, len(queries))
+	for i := range queries {
+		queries[i] = make([]float32, 8)
+		for j := range queries[i] {
+			queries[i][j] = rng.Float32()
+		}
+		want[i] = h.KNN_Search(queries[i], 10, 64)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := 0; round < 10; round++ {
+				for i, query := range queries {
+					if got := h.KNN_Search(query, 10, 64); !slices.Equal(got, want[i]) {
+						t.Errorf("Query %d: expected %v, got %v", i, want[i], got)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// A list taken from the pool covers every node, even after growth
+	h.Insert(make([]float32, 8), len(h.Nodes))
+	visited := h.getVisited()
+	defer h.visited.Put(visited)
+	if len(visited.marks) < len(h.Nodes) {
+		t.Errorf("Expected a visited list for %d nodes, got %d", len(h.Nodes), len(visited.marks))
+	}
+}
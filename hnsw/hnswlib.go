@@ -0,0 +1,343 @@
+package hnsw
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// hnswlib index layout, as written by HierarchicalNSW::saveIndex on a little
+// endian host (size_t is 8 bytes, tableint and linklistsizeint are 4 bytes)
+//
+//	header      96 bytes, see hnswlibHeader
+//	level0      count elements of size_data_per_element bytes:
+//	              link count (low 16 bits, bit 16 marks deleted elements),
+//	              maxM0 neighbor IDs, the vector, the 8-byte label
+//	link lists  for each element: the byte size of its upper link lists,
+//	            then one block of (1+maxM) uint32 per level ≥ 1
+//
+// hnswlib internal IDs are positions in the level0 section, exactly like node
+// IDs in HNSW, while the user-facing IDs are stored separately as labels.
+const (
+	hnswlibHeaderSize = 96
+
+	// hnswlibDeleteMark is hnswlib's DELETE_MARK, stored in the third byte
+	// of the level 0 link count
+	hnswlibDeleteMark = uint32(1) << 16
+
+	// hnswlibMaxLinks bounds maxM and maxM0, whose counts are stored on 16 bits
+	hnswlibMaxLinks = 1<<16 - 1
+
+	// hnswlibMaxLevel bounds the levels read from a file. hnswlib draws
+	// levels from 53-bit random numbers, which cannot go this high.
+	hnswlibMaxLevel = 64
+
+	// hnswlibMaxDim bounds the dimension of the vectors read from a file, as
+	// the vector file readers of the dataset package do
+	hnswlibMaxDim = 1 << 24
+)
+
+// hnswlibHeader mirrors the header fields written by hnswlib
+type hnswlibHeader struct {
+	OffsetLevel0       uint64
+	MaxElements        uint64
+	CurElementCount    uint64
+	SizeDataPerElement uint64
+	LabelOffset        uint64
+	OffsetData         uint64
+	MaxLevel           int32
+	EnterpointNode     uint32
+	MaxM               uint64
+	MaxM0              uint64
+	M                  uint64
+	Mult               float64
+	EfConstruction     uint64
+}
+
+func (hd *hnswlibHeader) encode() []byte {
+	b := make([]byte, hnswlibHeaderSize)
+	binary.LittleEndian.PutUint64(b[0:], hd.OffsetLevel0)
+	binary.LittleEndian.PutUint64(b[8:], hd.MaxElements)
+	binary.LittleEndian.PutUint64(b[16:], hd.CurElementCount)
+	binary.LittleEndian.PutUint64(b[24:], hd.SizeDataPerElement)
+	binary.LittleEndian.PutUint64(b[32:], hd.LabelOffset)
+	binary.LittleEndian.PutUint64(b[40:], hd.OffsetData)
+	binary.LittleEndian.PutUint32(b[48:], uint32(hd.MaxLevel))
+	binary.LittleEndian.PutUint32(b[52:], hd.EnterpointNode)
+	binary.LittleEndian.PutUint64(b[56:], hd.MaxM)
+	binary.LittleEndian.PutUint64(b[64:], hd.MaxM0)
+	binary.LittleEndian.PutUint64(b[72:], hd.M)
+	binary.LittleEndian.PutUint64(b[80:], math.Float64bits(hd.Mult))
+	binary.LittleEndian.PutUint64(b[88:], hd.EfConstruction)
+	return b
+}
+
+func (hd *hnswlibHeader) decode(b []byte) error {
+	hd.OffsetLevel0 = binary.LittleEndian.Uint64(b[0:])
+	hd.MaxElements = binary.LittleEndian.Uint64(b[8:])
+	hd.CurElementCount = binary.LittleEndian.Uint64(b[16:])
+	hd.SizeDataPerElement = binary.LittleEndian.Uint64(b[24:])
+	hd.LabelOffset = binary.LittleEndian.Uint64(b[32:])
+	hd.OffsetData = binary.LittleEndian.Uint64(b[40:])
+	hd.MaxLevel = int32(binary.LittleEndian.Uint32(b[48:]))
+	hd.EnterpointNode = binary.LittleEndian.Uint32(b[52:])
+	hd.MaxM = binary.LittleEndian.Uint64(b[56:])
+	hd.MaxM0 = binary.LittleEndian.Uint64(b[64:])
+	hd.M = binary.LittleEndian.Uint64(b[72:])
+	hd.Mult = math.Float64frombits(binary.LittleEndian.Uint64(b[80:]))
+	hd.EfConstruction = binary.LittleEndian.Uint64(b[88:])
+
+	if hd.M == 0 || hd.MaxM == 0 || hd.MaxM0 == 0 || hd.EfConstruction == 0 ||
+		hd.MaxM > hnswlibMaxLinks || hd.MaxM0 > hnswlibMaxLinks || hd.M > hnswlibMaxLinks {
+		return fmt.Errorf("%w: invalid hnswlib configuration", ErrInvalidIndex)
+	}
+	if hd.OffsetLevel0 != 0 || hd.OffsetData != hd.sizeLinksLevel0() ||
+		hd.LabelOffset < hd.OffsetData || (hd.LabelOffset-hd.OffsetData)%4 != 0 ||
+		(hd.LabelOffset-hd.OffsetData)/4 > hnswlibMaxDim || hd.SizeDataPerElement != hd.LabelOffset+8 {
+		return fmt.Errorf("%w: unsupported hnswlib element layout", ErrInvalidIndex)
+	}
+	if hd.CurElementCount >= uint64(noEntryPoint) {
+		return fmt.Errorf("%w: too many nodes", ErrInvalidIndex)
+	}
+	if hd.CurElementCount == 0 {
+		return nil
+	}
+	if hd.LabelOffset == hd.OffsetData {
+		return fmt.Errorf("%w: hnswlib vectors are empty", ErrInvalidIndex)
+	}
+	if hd.MaxLevel < 0 || hd.MaxLevel > hnswlibMaxLevel || uint64(hd.EnterpointNode) >= hd.CurElementCount {
+		return fmt.Errorf("%w: invalid entry point", ErrInvalidIndex)
+	}
+	return nil
+}
+
+// sizeLinksLevel0 is hnswlib's size_links_level0_
+func (hd *hnswlibHeader) sizeLinksLevel0() uint64 {
+	return 4 + 4*hd.MaxM0
+}
+
+// sizeLinksPerElement is hnswlib's size_links_per_element_
+func (hd *hnswlibHeader) sizeLinksPerElement() uint64 {
+	return 4 + 4*hd.MaxM
+}
+
+// dim returns the number of float32 components per vector
+func (hd *hnswlibHeader) dim() int {
+	return int(hd.LabelOffset-hd.OffsetData) / 4
+}
+
+// LoadHnswlib reads an index saved by hnswlib (saveIndex in C++, save_index in
+// Python) with float32 vectors, such as the "l2", "ip" and "cosine" spaces.
+// It returns the index together with the hnswlib label of every node: node i
+// of the index corresponds to labels[i].
+//
+// distanceFunc must match the space the index was built with: hnswlib's "l2"
+// space computes the squared Euclidean distance, like EuclideanDistance.
+// Elements marked as deleted in hnswlib are loaded as deleted nodes.
+func LoadHnswlib(r io.Reader, distanceFunc func([]float32, []float32) float32) (*HNSW, []uint64, error) {
+	size, sized := remaining(r)
+	br := bufio.NewReader(r)
+	b := make([]byte, hnswlibHeaderSize)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidIndex, err)
+	}
+
+	var hd hnswlibHeader
+	if err := hd.decode(b); err != nil {
+		return nil, nil, err
+	}
+
+	// Every element takes its level 0 data and the size of its link lists
+	words, ok := sectionWords([2]uint64{hd.CurElementCount, hd.SizeDataPerElement/4 + 1})
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: sections too large", ErrInvalidIndex)
+	}
+	if sized && uint64(size) < hnswlibHeaderSize+4*words {
+		return nil, nil, fmt.Errorf("%w: file is truncated", ErrInvalidIndex)
+	}
+
+	// hnswlib has no level cap: keep the usual one unless the graph is taller
+	cfg := Config{
+		M:              int(hd.M),
+		Mmax:           int(hd.MaxM),
+		Mmax0:          int(hd.MaxM0),
+		EfConstruction: int(hd.EfConstruction),
+		MaxLevel:       max(int(hd.MaxLevel), DefaultConfig().MaxLevel),
+		DistanceFunc:   distanceFunc,
+	}
+	h, err := NewHNSW(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hd.Mult > 0 {
+		h.mL = hd.Mult
+	}
+
+	count, dim := int(hd.CurElementCount), hd.dim()
+	mMax0 := int(hd.MaxM0)
+
+	// The level 0 section is read element by element, so that a corrupted
+	// count fails on a short read rather than on a huge allocation
+	vectors := make([]float32, 0, min(count, 1<<16)*dim)
+	level0 := make([]uint32, 0, min(count, 1<<16)*(1+mMax0))
+	labels := make([]uint64, 0, min(count, 1<<16))
+	element := make([]byte, hd.SizeDataPerElement)
+	for i := 0; i < count; i++ {
+		if _, err := io.ReadFull(br, element); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidIndex, err)
+		}
+		for j := 0; j <= mMax0; j++ {
+			level0 = append(level0, binary.LittleEndian.Uint32(element[4*j:]))
+		}
+		data := element[hd.OffsetData:hd.LabelOffset]
+		for j := 0; j < dim; j++ {
+			vectors = append(vectors, math.Float32frombits(binary.LittleEndian.Uint32(data[4*j:])))
+		}
+		labels = append(labels, binary.LittleEndian.Uint64(element[hd.LabelOffset:]))
+	}
+
+	h.Nodes = make([]*structs.Node, 0, count)
+	linksPerElement := hd.sizeLinksPerElement()
+	sizeBuf := make([]byte, 4)
+	for i := 0; i < count; i++ {
+		if _, err := io.ReadFull(br, sizeBuf); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidIndex, err)
+		}
+		linkListSize := uint64(binary.LittleEndian.Uint32(sizeBuf))
+		if linkListSize%linksPerElement != 0 || linkListSize/linksPerElement > uint64(hd.MaxLevel) {
+			return nil, nil, fmt.Errorf("%w: invalid link list size for node %d", ErrInvalidIndex, i)
+		}
+		level := int(linkListSize / linksPerElement)
+
+		node := h.storage.NewNode(i, vectors[i*dim:(i+1)*dim], level)
+		header := level0[i*(1+mMax0)]
+		block := level0[i*(1+mMax0) : (i+1)*(1+mMax0)]
+		block[0] = header & 0xffff
+		if err := readLinks(node, 0, block, h.Mmax0, count); err != nil {
+			return nil, nil, err
+		}
+		if header&hnswlibDeleteMark != 0 {
+			node.Deleted = true
+			h.deleted++
+		}
+
+		dec := &decoder{r: br}
+		for lc := 1; lc <= level; lc++ {
+			block := dec.uint32s(1 + h.Mmax)
+			if dec.err != nil {
+				return nil, nil, fmt.Errorf("%w: %v", ErrInvalidIndex, dec.err)
+			}
+			block[0] &= 0xffff
+			if err := readLinks(node, lc, block, h.Mmax, count); err != nil {
+				return nil, nil, err
+			}
+		}
+		h.Nodes = append(h.Nodes, node)
+	}
+
+	if count > 0 {
+		h.EntryPoint = h.Nodes[hd.EnterpointNode]
+		if h.EntryPoint.Level != int(hd.MaxLevel) {
+			return nil, nil, fmt.Errorf("%w: entry point is not on the top level", ErrInvalidIndex)
+		}
+	}
+	return h, labels, nil
+}
+
+// LoadHnswlibFile reads an hnswlib index from the file at path.
+func LoadHnswlibFile(path string, distanceFunc func([]float32, []float32) float32) (*HNSW, []uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return LoadHnswlib(f, distanceFunc)
+}
+
+// SaveHnswlib writes the index to w in the hnswlib format, so that it can be
+// loaded with hnswlib's loadIndex (load_index in Python). labels gives the
+// hnswlib label of every node; if nil, node IDs are used as labels.
+//
+// hnswlib looks up elements by label, so labels should be unique. Deleted
+// nodes are written with hnswlib's delete mark.
+func (h *HNSW) SaveHnswlib(w io.Writer, labels []uint64) error {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	count := len(h.Nodes)
+	if labels != nil && len(labels) != count {
+		return fmt.Errorf("expected %d labels, got %d", count, len(labels))
+	}
+
+	dim := uint64(h.storage.Vectors.Dim())
+	hd := hnswlibHeader{
+		MaxElements:     uint64(max(count, 1)),
+		CurElementCount: uint64(count),
+		MaxLevel:        -1,
+		EnterpointNode:  noEntryPoint,
+		MaxM:            uint64(h.Mmax),
+		MaxM0:           uint64(h.Mmax0),
+		M:               uint64(h.M),
+		Mult:            h.mL,
+		EfConstruction:  uint64(h.EfConstruction),
+	}
+	hd.OffsetData = hd.sizeLinksLevel0()
+	hd.LabelOffset = hd.OffsetData + 4*dim
+	hd.SizeDataPerElement = hd.LabelOffset + 8
+	if h.EntryPoint != nil {
+		hd.MaxLevel = int32(h.EntryPoint.Level)
+		hd.EnterpointNode = uint32(h.EntryPoint.ID)
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := &encoder{w: bw}
+	enc.write(hd.encode())
+
+	label := make([]byte, 8)
+	for i, node := range h.Nodes {
+		header := uint32(len(node.Neighbors[0]))
+		if node.Deleted {
+			header |= hnswlibDeleteMark
+		}
+		enc.uint32(header)
+		for _, id := range node.Neighbors[0] {
+			enc.uint32(id)
+		}
+		for j := len(node.Neighbors[0]); j < h.Mmax0; j++ {
+			enc.uint32(0)
+		}
+		enc.float32s(node.Vector)
+
+		if labels != nil {
+			binary.LittleEndian.PutUint64(label, labels[i])
+		} else {
+			binary.LittleEndian.PutUint64(label, uint64(i))
+		}
+		enc.write(label)
+	}
+
+	for _, node := range h.Nodes {
+		enc.uint32(uint32(uint64(node.Level) * hd.sizeLinksPerElement()))
+		for lc := 1; lc <= node.Level; lc++ {
+			enc.links(node.Neighbors[lc], h.Mmax)
+		}
+	}
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return bw.Flush()
+}
+
+// SaveHnswlibFile writes the index to the file at path in the hnswlib format,
+// replacing it atomically.
+func (h *HNSW) SaveHnswlibFile(path string, labels []uint64) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return h.SaveHnswlib(w, labels)
+	})
+}
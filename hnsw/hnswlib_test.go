@@ -0,0 +1,259 @@
+package hnsw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// hnswlibFixture is a small index written field by field in the layout of
+// hnswlib's saveIndex: 3 elements of dimension 2 with maxM 2 and maxM0 4.
+// Element 0 is on level 1 and is the entry point, element 2 is deleted.
+func hnswlibFixture(t *testing.T) []byte {
+	t.Helper()
+
+	const dim, maxM, maxM0 = 2, 2, 4
+	sizeLinks0 := uint64(4 + 4*maxM0)
+	var buf bytes.Buffer
+	write := func(v any) {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			t.Fatalf("Failed to write fixture: %v", err)
+		}
+	}
+
+	write(struct {
+		OffsetLevel0, MaxElements, CurElementCount, SizeDataPerElement, LabelOffset, OffsetData uint64
+		MaxLevel                                                                                int32
+		EnterpointNode                                                                          uint32
+		MaxM, MaxM0, M                                                                          uint64
+		Mult                                                                                    float64
+		EfConstruction                                                                          uint64
+	}{0, 10, 3, sizeLinks0 + 4*dim + 8, sizeLinks0 + 4*dim, sizeLinks0, 1, 0, maxM, maxM0, 2, 1.4426950408889634, 100})
+
+	// Level 0: link count, maxM0 neighbor slots, vector, label
+	write([]uint32{2, 1, 2, 0, 0})
+	write([]float32{0, 0})
+	write(uint64(100))
+	write([]uint32{1, 0, 0, 0, 0})
+	write([]float32{1, 0})
+	write(uint64(200))
+	write([]uint32{1 | 1<<16, 0, 0, 0, 0})
+	write([]float32{0, 1})
+	write(uint64(300))
+
+	// Link lists: only element 0 has an upper level, with no neighbors
+	write([]uint32{4 + 4*maxM, 0, 0, 0})
+	write(uint32(0))
+	write(uint32(0))
+	return buf.Bytes()
+}
+
+// TestLoadHnswlibFixture verifies that a file in the hnswlib layout is loaded
+// with the expected vectors, neighbors, labels and deletion marks
+func TestLoadHnswlibFixture(t *testing.T) {
+	h, labels, err := LoadHnswlib(bytes.NewReader(hnswlibFixture(t)), EuclideanDistance)
+	if err != nil {
+		t.Fatalf("LoadHnswlib failed: %v", err)
+	}
+
+	if h.M != 2 || h.Mmax != 2 || h.Mmax0 != 4 || h.EfConstruction != 100 {
+		t.Errorf("Unexpected configuration M=%d Mmax=%d Mmax0=%d EfConstruction=%d", h.M, h.Mmax, h.Mmax0, h.EfConstruction)
+	}
+	if !reflect.DeepEqual(labels, []uint64{100, 200, 300}) {
+		t.Errorf("Expected labels [100 200 300], got %v", labels)
+	}
+	if h.EntryPoint == nil || h.EntryPoint.ID != 0 {
+		t.Fatalf("Expected entry point 0")
+	}
+
+	tests := []struct {
+		id        int
+		level     int
+		deleted   bool
+		vector    []float32
+		neighbors [][]uint32
+	}{
+		{0, 1, false, []float32{0, 0}, [][]uint32{{1, 2}, {}}},
+		{1, 0, false, []float32{1, 0}, [][]uint32{{0}}},
+		{2, 0, true, []float32{0, 1}, [][]uint32{{0}}},
+	}
+	for _, tt := range tests {
+		node := h.Nodes[tt.id]
+		if node.Level != tt.level || node.Deleted != tt.deleted {
+			t.Errorf("Node %d: expected level %d deleted %v, got level %d deleted %v", tt.id, tt.level, tt.deleted, node.Level, node.Deleted)
+		}
+		if !reflect.DeepEqual(node.Vector, tt.vector) {
+			t.Errorf("Node %d: expected vector %v, got %v", tt.id, tt.vector, node.Vector)
+		}
+		if !reflect.DeepEqual(node.Neighbors, tt.neighbors) {
+			t.Errorf("Node %d: expected neighbors %v, got %v", tt.id, tt.neighbors, node.Neighbors)
+		}
+	}
+
+	if h.Len() != 2 {
+		t.Errorf("Expected 2 live nodes, got %d", h.Len())
+	}
+	if results := h.KNN_Search([]float32{0, 0.9}, 1, 10); !reflect.DeepEqual(results, []int{0}) {
+		t.Errorf("Expected deleted node to be skipped, got %v", results)
+	}
+}
+
+// TestSaveHnswlibFixture verifies that exporting the fixture reproduces it
+// byte for byte
+func TestSaveHnswlibFixture(t *testing.T) {
+	fixture := hnswlibFixture(t)
+	h, labels, err := LoadHnswlib(bytes.NewReader(fixture), EuclideanDistance)
+	if err != nil {
+		t.Fatalf("LoadHnswlib failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := h.SaveHnswlib(&buf, labels); err != nil {
+		t.Fatalf("SaveHnswlib failed: %v", err)
+	}
+
+	// max_elements_ is the only field that is not preserved
+	got, want := buf.Bytes(), bytes.Clone(fixture)
+	binary.LittleEndian.PutUint64(want[8:], 3)
+	if !bytes.Equal(got, want) {
+		t.Errorf("Exported index differs from the hnswlib fixture:\n got %x\nwant %x", got, want)
+	}
+}
+
+// TestHnswlibRoundTrip verifies that an index survives an export and import
+func TestHnswlibRoundTrip(t *testing.T) {
+	h := buildRandomIndex(t, 300, 8)
+	if err := h.Delete(3); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "index.bin")
+	if err := h.SaveHnswlibFile(path, nil); err != nil {
+		t.Fatalf("SaveHnswlibFile failed: %v", err)
+	}
+	loaded, labels, err := LoadHnswlibFile(path, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("LoadHnswlibFile failed: %v", err)
+	}
+
+	assertSameIndex(t, h, loaded)
+	for i, label := range labels {
+		if label != uint64(i) {
+			t.Fatalf("Expected label %d, got %d", i, label)
+		}
+	}
+
+	query := []float32{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+	if !reflect.DeepEqual(loaded.KNN_Search(query, 10, 50), h.KNN_Search(query, 10, 50)) {
+		t.Errorf("Search results differ after round trip")
+	}
+}
+
+// TestHnswlibEmpty verifies the export and import of an empty index
+func TestHnswlibEmpty(t *testing.T) {
+	h, err := NewHNSW(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := h.SaveHnswlib(&buf, nil); err != nil {
+		t.Fatalf("SaveHnswlib failed: %v", err)
+	}
+	loaded, labels, err := LoadHnswlib(&buf, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("LoadHnswlib failed: %v", err)
+	}
+	if len(loaded.Nodes) != 0 || len(labels) != 0 || loaded.EntryPoint != nil {
+		t.Errorf("Expected an empty index")
+	}
+}
+
+// TestLoadHnswlibInvalid verifies that malformed files are rejected
+func TestLoadHnswlibInvalid(t *testing.T) {
+	fixture := hnswlibFixture(t)
+	withUint64 := func(offset int, v uint64) []byte {
+		b := bytes.Clone(fixture)
+		binary.LittleEndian.PutUint64(b[offset:], v)
+		return b
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated header", fixture[:50]},
+		{"truncated level 0", fixture[:hnswlibHeaderSize+40]},
+		{"truncated link lists", fixture[:len(fixture)-4]},
+		{"zero M", withUint64(72, 0)},
+		{"bad data offset", withUint64(40, 12)},
+		{"huge count", withUint64(16, 1<<40)},
+		{"huge count in range", withUint64(16, 1<<30)},
+		{"wrapping label offset", func() []byte {
+			b := withUint64(32, math.MaxUint64-7)
+			binary.LittleEndian.PutUint64(b[24:], 0)
+			return b
+		}()},
+		{"huge dimension", func() []byte {
+			b := withUint64(32, 20+4*(1<<24+1))
+			binary.LittleEndian.PutUint64(b[24:], 20+4*(1<<24+1)+8)
+			return b
+		}()},
+		{"entry point out of range", func() []byte {
+			b := bytes.Clone(fixture)
+			binary.LittleEndian.PutUint32(b[52:], 7)
+			return b
+		}()},
+		{"too many neighbors", func() []byte {
+			b := bytes.Clone(fixture)
+			binary.LittleEndian.PutUint32(b[hnswlibHeaderSize:], 5)
+			return b
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := LoadHnswlib(bytes.NewReader(tt.data), EuclideanDistance)
+			if !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex, got %v", err)
+			}
+
+			// Without a known size, the data runs out before the sections
+			_, _, err = LoadHnswlib(io.MultiReader(bytes.NewReader(tt.data)), EuclideanDistance)
+			if !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex from a stream, got %v", err)
+			}
+		})
+	}
+}
+
+// FuzzLoadHnswlib verifies that LoadHnswlib rejects corrupt input with an
+// error rather than a panic
+func FuzzLoadHnswlib(f *testing.F) {
+	var buf bytes.Buffer
+	if err := buildRandomIndex(f, 20, 4).SaveHnswlib(&buf, nil); err != nil {
+		f.Fatalf("SaveHnswlib failed: %v", err)
+	}
+	f.Add(buf.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		loaded, _, err := LoadHnswlib(bytes.NewReader(data), EuclideanDistance)
+		if err == nil && len(loaded.Nodes) > 0 {
+			loaded.KNN_Search(loaded.Nodes[0].Vector, 3, 10)
+		}
+	})
+}
+
+// TestSaveHnswlibLabels verifies that the number of labels is checked
+func TestSaveHnswlibLabels(t *testing.T) {
+	h := buildRandomIndex(t, 10, 4)
+	if err := h.SaveHnswlib(&bytes.Buffer{}, []uint64{1, 2, 3}); err == nil {
+		t.Errorf("Expected an error for a wrong number of labels")
+	}
+}
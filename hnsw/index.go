@@ -0,0 +1,77 @@
+package hnsw
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// Index is a nearest-neighbor index over vectors identified by their
+// insertion order. HNSW answers searches approximately, in logarithmic
+// time, and FlatIndex exactly, in linear time, so callers can switch from
+// one to the other as a collection grows.
+//
+// The search method keeps the name of HNSW.KNN_Search, since HNSW.Search
+// already selects the ef tuned by AutoTune.
+type Index interface {
+	// Insert adds a vector; id must be the number of nodes inserted before
+	Insert(vector []float32, id int)
+
+	// Delete removes a node from the results, or returns ErrNodeNotFound
+	Delete(id int) error
+
+	// KNN_Search returns the IDs of the K nodes closest to the query,
+	// sorted by distance. ef trades accuracy for speed in approximate indexes.
+	KNN_Search(query []float32, K, ef int) []int
+
+	// Len returns the number of nodes, excluding deleted ones
+	Len() int
+
+	// Save writes the index, to be read back by LoadIndex
+	Save(w io.Writer) error
+}
+
+var (
+	_ Index = (*HNSW)(nil)
+	_ Index = (*FlatIndex)(nil)
+)
+
+// LoadIndex reads an index written by HNSW.Save or FlatIndex.Save, telling
+// them apart by the magic number at the start of the data.
+func LoadIndex(r io.Reader, distanceFunc func(a, b []float32) float32) (Index, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(flatMagic))
+	return loadIndex(br, string(magic) == flatMagic, distanceFunc)
+}
+
+// LoadIndexFile reads an HNSW or flat index from the file at path.
+func LoadIndexFile(path string, distanceFunc func(a, b []float32) float32) (Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// The file is passed on unwrapped, so that its size is checked against
+	// the header
+	magic := make([]byte, len(flatMagic))
+	f.ReadAt(magic, 0)
+	return loadIndex(f, string(magic) == flatMagic, distanceFunc)
+}
+
+// loadIndex reads a flat or an HNSW index from r
+func loadIndex(r io.Reader, flat bool, distanceFunc func(a, b []float32) float32) (Index, error) {
+	// A nil pointer in an Index would not compare equal to nil
+	if flat {
+		f, err := LoadFlat(r, distanceFunc)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+	h, err := Load(r, distanceFunc)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
@@ -0,0 +1,114 @@
+package hnsw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestIndexRecall compares HNSW with the exact results of FlatIndex through
+// the Index interface
+func TestIndexRecall(t *testing.T) {
+	var exact, approx Index = NewFlatIndex(EuclideanDistance), buildRandomIndex(t, 0, 8)
+
+	rng := rand.New(rand.NewPCG(5, 6))
+	random := func() []float32 {
+		v := make([]float32, 8)
+		for j := range v {
+			v[j] = rng.Float32()
+		}
+		return v
+	}
+	for _, index := range []Index{exact, approx} {
+		rng = rand.New(rand.NewPCG(5, 6))
+		for i := 0; i < 1000; i++ {
+			index.Insert(random(), i)
+		}
+		for i := 0; i < 1000; i += 10 {
+			if err := index.Delete(i); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+		}
+	}
+	if exact.Len() != 900 || approx.Len() != 900 {
+		t.Fatalf("Expected 900 vectors in both indexes, got %d and %d", exact.Len(), approx.Len())
+	}
+
+	found, total := 0, 0
+	for q := 0; q < 50; q++ {
+		query := random()
+		truth := make(map[int]bool)
+		for _, id := range exact.KNN_Search(query, 10, 0) {
+			truth[id] = true
+		}
+		for _, id := range approx.KNN_Search(query, 10, 64) {
+			if id%10 == 0 {
+				t.Fatalf("Expected deleted node %d not to be returned", id)
+			}
+			if truth[id] {
+				found++
+			}
+		}
+		total += len(truth)
+	}
+	if recall := float64(found) / float64(total); recall < 0.95 {
+		t.Errorf("Expected recall ≥ 0.95 against the flat index, got %.3f", recall)
+	}
+}
+
+func TestLoadIndex(t *testing.T) {
+	flat, _ := buildFlatIndex(t, 50, 4)
+	tests := []struct {
+		name  string
+		index Index
+	}{
+		{"hnsw", buildRandomIndex(t, 50, 4)},
+		{"flat", flat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.index.Save(&buf); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			loaded, err := LoadIndex(&buf, EuclideanDistance)
+			if err != nil {
+				t.Fatalf("LoadIndex failed: %v", err)
+			}
+			if got, want := loaded, tt.index; got.Len() != want.Len() {
+				t.Errorf("Expected %d vectors, got %d", want.Len(), got.Len())
+			}
+			if _, ok := loaded.(*FlatIndex); ok != (tt.name == "flat") {
+				t.Errorf("Expected a %s index, got %T", tt.name, loaded)
+			}
+		})
+	}
+
+	if index, err := LoadIndex(bytes.NewReader([]byte("garbage")), EuclideanDistance); err == nil || index != nil {
+		t.Errorf("Expected a nil index and an error, got %v, %v", index, err)
+	}
+	// A corrupt flat index must not come back as a nil *FlatIndex
+	var buf bytes.Buffer
+	if err := flat.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data := buf.Bytes()
+	binary.LittleEndian.PutUint32(data[12:], math.MaxUint32)
+	binary.LittleEndian.PutUint64(data[16:], math.MaxUint32)
+	if index, err := LoadIndex(bytes.NewReader(data), EuclideanDistance); err == nil || index != nil {
+		t.Errorf("Expected a nil index and an error, got %v, %v", index, err)
+	}
+	path := filepath.Join(t.TempDir(), "corrupt.flat")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if index, err := LoadIndexFile(path, EuclideanDistance); !errors.Is(err, ErrInvalidIndex) || index != nil {
+		t.Errorf("Expected a nil index and ErrInvalidIndex, got %v, %v", index, err)
+	}
+}
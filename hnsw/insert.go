@@ -2,7 +2,10 @@
 package hnsw
 
 import (
+	"context"
 	"math"
+	"slices"
+	"time"
 
 	"dmarro89.github.com/hnsw-go/structs"
 )
@@ -22,27 +25,59 @@ import (
 // Time Complexity: O(log N) average case
 // Space Complexity: O(M * log N) where M is the max connections per layer
 func (h *HNSW) Insert(vector []float32, id int) {
+	h.InsertContext(context.Background(), vector, id)
+}
+
+// InsertContext is Insert, traced as a child of the span in ctx.
+func (h *HNSW) InsertContext(ctx context.Context, vector []float32, id int) {
 	if len(vector) == 0 {
 		panic("vector cannot be empty")
 	}
 
-	h.mutex.Lock()
+	span := h.startSpan(ctx, "hnsw.Insert")
+	if span != nil {
+		defer span.End(nil)
+	}
+
+	h.lock("insert")
 	defer h.mutex.Unlock()
 
+	if h.Metrics == nil && span == nil {
+		h.insert(vector, id, &h.EntryPoint)
+		return
+	}
+	start := time.Now()
+	node := h.insert(vector, id, &h.EntryPoint)
+	if h.Metrics != nil {
+		h.Metrics.ObserveInsert(time.Since(start), node.Level)
+	}
+	if span != nil {
+		span.SetAttributes(Attribute{"id", id}, Attribute{"level", node.Level})
+	}
+}
+
+// insert adds a node to the graph whose entry point is *entryPoint, and
+// updates *entryPoint when the new node becomes the top one. The new node is
+// only linked to nodes reachable from the entry point, which lets several
+// graphs share the nodes and the storage of an index. The caller must hold
+// the write lock.
+func (h *HNSW) insert(vector []float32, id int, entryPoint **structs.Node) *structs.Node {
 	// l ← ⌊-ln(unif(0..1))∙mL⌋ // new element’s level
 	// Generate the level for the new node based on a random distribution.
-	level := h.RandomLevel()
+	level := h.newLevel(vector, *entryPoint)
 
-	newNode := structs.NewNode(id, vector, level, h.MaxLevel, h.Mmax, h.Mmax0)
+	// The vector is copied into the shared arena, so the caller may reuse it
+	newNode := h.storage.NewNode(id, vector, level)
+	h.markDirty(newNode.ID)
 	// Generate the level for the new node based on a random distribution.
-	if h.EntryPoint == nil {
-		h.EntryPoint = newNode
+	if *entryPoint == nil {
+		*entryPoint = newNode
 		h.Nodes = append(h.Nodes, newNode)
-		return
+		return newNode
 	}
 
 	// ep ← get entry point for hnsw
-	ep := h.EntryPoint
+	ep := *entryPoint
 	// L ← level of ep - top layer for hnsw
 	L := ep.Level
 
@@ -54,7 +89,7 @@ func (h *HNSW) Insert(vector []float32, id int) {
 	// for lc ← L … l+1
 	for lc := L; lc > level; lc-- {
 		// W ← SEARCH-LAYER(q, ep, ef=1, lc)
-		newEp := h.greedySearchLayer(vector, ep, lc)
+		newEp := h.greedySearchLayer(vector, ep, lc, nil)
 		if newEp == nil {
 			break
 		}
@@ -69,7 +104,7 @@ func (h *HNSW) Insert(vector []float32, id int) {
 	for lc := maxLayer; lc >= 0; lc-- {
 		// W ← list for the currently found nearest elements
 		// W ← SEARCH-LAYER(q, ep, efConstruction, lc)
-		nearestNeighbors := h.searchLayer(vector, ep, h.EfConstruction, lc)
+		nearestNeighbors := h.searchLayer(vector, ep, h.EfConstruction, lc, nil, nil)
 
 		// Ensure that the number of connections does not exceed the allowed limit.
 		maxConn := h.Mmax
@@ -90,8 +125,9 @@ func (h *HNSW) Insert(vector []float32, id int) {
 	// If the new node's level is higher than the current top level, update the entry point.
 	// if l > L
 	if level > L {
-		h.EntryPoint = newNode
+		*entryPoint = newNode
 	}
+	return newNode
 }
 
 // updateBidirectionalConnections establishes and maintains bidirectional connections
@@ -104,8 +140,11 @@ func (h *HNSW) Insert(vector []float32, id int) {
 // 4. Connections are optimized to maintain the best possible neighbors
 func (h *HNSW) updateBidirectionalConnections(q *structs.Node, neighbors []int, level int, maxConn int) {
 	// add bidirectional connections from neighbors to q at layer lc
-	q.Neighbors[level] = q.Neighbors[level][:0]                   // Reset and reuse the slice
-	q.Neighbors[level] = append(q.Neighbors[level], neighbors...) // Append neighbors
+	h.markDirty(q.ID)
+	q.Neighbors[level] = q.Neighbors[level][:0] // Reset and reuse the slice
+	for _, neighborID := range neighbors {
+		q.Neighbors[level] = append(q.Neighbors[level], uint32(neighborID)) // Append neighbors
+	}
 
 	// Getting the candidates nodes for the neighbors from the pool
 	// and the temporary heap for the optimization process
@@ -120,17 +159,26 @@ func (h *HNSW) updateBidirectionalConnections(q *structs.Node, neighbors []int,
 			continue
 		}
 
+		// Skip neighbors already connected to q, which happens when an
+		// existing node is re-linked by Update
+		if slices.Contains(neighbor.Neighbors[level], uint32(q.ID)) {
+			continue
+		}
+
+		// The neighbor list changes either way
+		h.markDirty(neighborID)
+
 		// Check if we need to optimize connections
 		if len(neighbor.Neighbors[level])+1 <= maxConn {
 			currentLen := len(neighbor.Neighbors[level])
 			if currentLen < cap(neighbor.Neighbors[level]) {
 				// There is enough capacity, so we can reuse the slice
-				neighbor.Neighbors[level] = append(neighbor.Neighbors[level], q.ID)
+				neighbor.Neighbors[level] = append(neighbor.Neighbors[level], uint32(q.ID))
 			} else {
 				// We need to allocate a new slice with incremented capacity
-				newNeighbors := make([]int, currentLen+1, currentLen+2)
+				newNeighbors := make([]uint32, currentLen+1, currentLen+2)
 				copy(newNeighbors, neighbor.Neighbors[level])
-				newNeighbors[currentLen] = q.ID
+				newNeighbors[currentLen] = uint32(q.ID)
 				neighbor.Neighbors[level] = newNeighbors
 			}
 			continue
@@ -149,7 +197,7 @@ func (h *HNSW) updateBidirectionalConnections(q *structs.Node, neighbors []int,
 
 		for _, n := range eConn {
 			dist := h.DistanceFunc(neighbor.Vector, h.Nodes[n].Vector)
-			tmpHeap.Push(structs.NewNodeHeap(dist, n))
+			tmpHeap.Push(structs.NewNodeHeap(dist, int(n)))
 		}
 
 		// Get the top maxConn neighbors
@@ -166,6 +214,8 @@ func (h *HNSW) updateBidirectionalConnections(q *structs.Node, neighbors []int,
 
 		// eNewConn ← SELECT-NEIGHBORS(e, eConn, Mmax, lc)
 		neighbor.Neighbors[level] = neighbor.Neighbors[level][:len(candidates)]
-		copy(neighbor.Neighbors[level], candidates)
+		for i, c := range candidates {
+			neighbor.Neighbors[level][i] = uint32(c)
+		}
 	}
 }
@@ -125,7 +125,7 @@ func TestBidirectionalConnections(t *testing.T) {
 	// Helper function to check if node 'from' is connected to node 'to'
 	hasConnection := func(from, to int) bool {
 		for _, neighborID := range h.Nodes[from].Neighbors[0] {
-			if neighborID == to {
+			if int(neighborID) == to {
 				return true
 			}
 		}
@@ -313,8 +313,8 @@ func TestUpdateBidirectionalConnections(t *testing.T) {
 
 	h.Nodes = append(h.Nodes, q, n1, n2)
 	// Initialize neighbors of n1 and n2
-	n1.Neighbors[level] = []int{}
-	n2.Neighbors[level] = []int{}
+	n1.Neighbors[level] = []uint32{}
+	n2.Neighbors[level] = []uint32{}
 
 	// Update bidirectional connections
 	h.updateBidirectionalConnections(q, []int{n1.ID, n2.ID}, level, maxConn)
@@ -327,7 +327,7 @@ func TestUpdateBidirectionalConnections(t *testing.T) {
 	// Verify that n1 and n2 are connected to q
 	foundInN1 := false
 	for _, nodeID := range n1.Neighbors[level] {
-		if nodeID == q.ID {
+		if int(nodeID) == q.ID {
 			foundInN1 = true
 			break
 		}
@@ -335,7 +335,7 @@ func TestUpdateBidirectionalConnections(t *testing.T) {
 
 	foundInN2 := false
 	for _, nodeID := range n2.Neighbors[level] {
-		if nodeID == q.ID {
+		if int(nodeID) == q.ID {
 			foundInN2 = true
 			break
 		}
@@ -449,7 +449,7 @@ func TestHNSWInsertionAlgorithm(t *testing.T) {
 		}
 
 		for _, neighborID := range fromNode.Neighbors[level] {
-			if neighborID == toID {
+			if int(neighborID) == toID {
 				return true
 			}
 		}
@@ -544,7 +544,7 @@ func TestHNSWInsertionAlgorithm(t *testing.T) {
 
 			// Check all actual connections
 			for _, neighborID := range node.Neighbors[level] {
-				if !expectedNeighbors[neighborID] {
+				if !expectedNeighbors[int(neighborID)] {
 					t.Errorf("Node %d at level %d has unexpected connection to node %d",
 						nodeID, level, neighborID)
 				}
@@ -687,9 +687,9 @@ func TestNeighborSelectionQuality(t *testing.T) {
 	}
 
 	// Helper function to check if a node is in a slice of nodes
-	contains := func(nodes []int, id int) bool {
+	contains := func(nodes []uint32, id int) bool {
 		for _, n := range nodes {
-			if n == id {
+			if int(n) == id {
 				return true
 			}
 		}
@@ -726,7 +726,7 @@ func TestNeighborSelectionQuality(t *testing.T) {
 				for _, neighborID := range node.Neighbors[level] {
 					found := false
 					for _, expectedID := range expectedNeighborIDs {
-						if neighborID == expectedID {
+						if int(neighborID) == expectedID {
 							found = true
 							break
 						}
@@ -800,7 +800,7 @@ func TestNeighborSelectionQuality(t *testing.T) {
 			for _, neighborID := range node.Neighbors[level] {
 				isNearest := false
 				for _, nearestID := range nearestIDs {
-					if neighborID == nearestID {
+					if int(neighborID) == nearestID {
 						isNearest = true
 						break
 					}
@@ -0,0 +1,58 @@
+package hnsw
+
+import "time"
+
+// Metrics receives measurements of the operations of an index, for export
+// to a monitoring system. Set it in the Metrics field of HNSW before the
+// index is used; a nil Metrics disables the measurements and their cost.
+// Implementations must be safe for concurrent use, and should be quick:
+// they are called with the index lock held.
+//
+// The op arguments name the operation: "insert", "search", "delete",
+// "update", "save" or "backup".
+type Metrics interface {
+	// ObserveInsert is called after every insertion with its duration,
+	// lock wait excluded, and the level given to the new node
+	ObserveInsert(elapsed time.Duration, level int)
+
+	// ObserveSearch is called after every KNN_Search with its duration,
+	// lock wait excluded, the number of nodes visited and the number of
+	// distances computed, over all the layers
+	ObserveSearch(elapsed time.Duration, visited, distances int)
+
+	// ObserveLockWait is called with the time op waited for the index lock
+	ObserveLockWait(op string, wait time.Duration)
+
+	// ObserveError is called when op returns an error
+	ObserveError(op string)
+}
+
+// lock acquires the write lock for op, measuring the wait
+func (h *HNSW) lock(op string) {
+	if h.Metrics == nil {
+		h.mutex.Lock()
+		return
+	}
+	start := time.Now()
+	h.mutex.Lock()
+	h.Metrics.ObserveLockWait(op, time.Since(start))
+}
+
+// rlock acquires the read lock for op, measuring the wait
+func (h *HNSW) rlock(op string) {
+	if h.Metrics == nil {
+		h.mutex.RLock()
+		return
+	}
+	start := time.Now()
+	h.mutex.RLock()
+	h.Metrics.ObserveLockWait(op, time.Since(start))
+}
+
+// observeError reports err, if not nil, as an error of op, and returns it
+func (h *HNSW) observeError(op string, err error) error {
+	if err != nil && h.Metrics != nil {
+		h.Metrics.ObserveError(op)
+	}
+	return err
+}
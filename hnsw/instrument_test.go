@@ -0,0 +1,86 @@
+package hnsw
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics keeps every measurement in memory
+type recordingMetrics struct {
+	mutex    sync.Mutex
+	inserts  []int
+	searches [][2]int
+	waits    map[string]int
+	errors   map[string]int
+}
+
+func (m *recordingMetrics) ObserveInsert(elapsed time.Duration, level int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.inserts = append(m.inserts, level)
+}
+
+func (m *recordingMetrics) ObserveSearch(elapsed time.Duration, visited, distances int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.searches = append(m.searches, [2]int{visited, distances})
+}
+
+func (m *recordingMetrics) ObserveLockWait(op string, wait time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.waits[op]++
+}
+
+func (m *recordingMetrics) ObserveError(op string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.errors[op]++
+}
+
+func TestMetrics(t *testing.T) {
+	h := buildRandomIndex(t, 200, 4)
+	m := &recordingMetrics{waits: make(map[string]int), errors: make(map[string]int)}
+	h.Metrics = m
+
+	h.Insert([]float32{0.5, 0.5, 0.5, 0.5}, len(h.Nodes))
+	if len(m.inserts) != 1 || m.inserts[0] != h.Nodes[200].Level {
+		t.Errorf("Expected one insertion on level %d, got %v", h.Nodes[200].Level, m.inserts)
+	}
+
+	query := []float32{0.2, 0.4, 0.6, 0.8}
+	h.KNN_Search(query, 10, 50)
+	if len(m.searches) != 1 {
+		t.Fatalf("Expected one search, got %d", len(m.searches))
+	}
+	trace := h.SearchExplain(query, 10, 50)
+	if m.searches[0] != [2]int{trace.Visited, trace.Distances} {
+		t.Errorf("Expected %d visited and %d distances, got %v", trace.Visited, trace.Distances, m.searches[0])
+	}
+
+	if err := h.Delete(1000); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("Expected ErrNodeNotFound, got %v", err)
+	}
+	if err := h.Update(3, []float32{1}); err == nil {
+		t.Fatalf("Expected a dimension error")
+	}
+	if err := h.Save(&bytes.Buffer{}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := h.Backup(filepath.Join(t.TempDir(), "backup.hnsw")); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if m.errors["delete"] != 1 || m.errors["update"] != 1 || m.errors["save"] != 0 {
+		t.Errorf("Expected one delete and one update error, got %v", m.errors)
+	}
+
+	for _, op := range []string{"insert", "search", "delete", "update", "save", "backup"} {
+		if m.waits[op] != 1 {
+			t.Errorf("Expected one lock wait for %s, got %d", op, m.waits[op])
+		}
+	}
+}
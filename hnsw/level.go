@@ -0,0 +1,183 @@
+package hnsw
+
+import (
+	"math"
+	"math/bits"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// LevelGenerator chooses the level of every new node. The level is capped
+// at the MaxLevel of the index.
+//
+// Generators are called with the write lock held, one insertion at a time,
+// so they may keep state without further synchronization, but must not use
+// the methods of the index.
+type LevelGenerator interface {
+	Level(in LevelInput) int
+}
+
+// LevelInput describes the node whose level is being chosen.
+type LevelInput struct {
+	Vector []float32
+
+	// Index is the position of the node in insertion order, which is also
+	// its ID
+	Index int
+
+	// MaxLevel and ML are the level cap and the normalization factor
+	// 1/ln(M) of the index
+	MaxLevel int
+	ML       float64
+
+	h *HNSW
+
+	// entry is the entry point of the graph the node is inserted into
+	entry *structs.Node
+}
+
+// Rand returns a random value in [0, 1) from the RandFunc of the index, so
+// that seeded indexes stay reproducible.
+func (in LevelInput) Rand() float64 {
+	return in.h.RandFunc()
+}
+
+// NearestDistance returns the distance from the vector to the closest live
+// node of the graph it is inserted into, found by a search with the given
+// ef, or +Inf for an empty graph.
+func (in LevelInput) NearestDistance(ef int) float32 {
+	h := in.h
+	nearest := h.search(in.Vector, 1, ef, in.entry, h.deleted > 0, nil, nil)
+	if len(nearest) == 0 {
+		return float32(math.Inf(1))
+	}
+	return h.DistanceFunc(in.Vector, h.Nodes[nearest[0]].Vector)
+}
+
+// newLevel returns the level of a new node inserted into the graph whose
+// entry point is entry, from the LevelGenerator of the index or from
+// Geometric if it is nil. The caller must hold the write lock.
+func (h *HNSW) newLevel(vector []float32, entry *structs.Node) int {
+	generator := h.LevelGenerator
+	if generator == nil {
+		generator = Geometric{}
+	}
+	level := generator.Level(LevelInput{Vector: vector, Index: len(h.Nodes), MaxLevel: h.MaxLevel, ML: h.mL, h: h, entry: entry})
+	return min(max(level, 0), h.MaxLevel)
+}
+
+// Geometric is the level distribution of the HNSW paper, the default one:
+// l = ⌊-ln(unif(0..1))∙mL⌋, so that each layer holds about a 1/M fraction
+// of the layer below.
+type Geometric struct {
+	// ML is the normalization factor; zero uses the one of the index
+	ML float64
+}
+
+func (g Geometric) Level(in LevelInput) int {
+	mL := g.ML
+	if mL == 0 {
+		mL = in.ML
+	}
+	return int(-math.Log(in.Rand()) * mL)
+}
+
+// FixedLayers spreads the levels geometrically over exactly Layers layers
+// for an index of about Size nodes: the normalization factor is chosen so
+// that the top layer holds about one node, whatever M is.
+type FixedLayers struct {
+	Layers int
+	Size   int
+}
+
+func (f FixedLayers) Level(in LevelInput) int {
+	if f.Layers <= 1 || f.Size <= 1 {
+		return 0
+	}
+	// A node reaches level l with probability exp(-l/mL) = Size^(-l/(Layers-1))
+	mL := float64(f.Layers-1) / math.Log(float64(f.Size))
+	return min(int(-math.Log(in.Rand())*mL), f.Layers-1)
+}
+
+// InsertionOrder assigns the levels deterministically from the insertion
+// order, for bulk loads of shuffled data: node i reaches level l when Base^l
+// divides i, so every layer holds exactly a 1/Base fraction of the layer
+// below. The first node gets the top level and stays the entry point, so
+// MaxLevel should be about log_Base of the final size.
+type InsertionOrder struct {
+	// Base is the ratio between the sizes of consecutive layers, usually M
+	Base int
+}
+
+func (o InsertionOrder) Level(in LevelInput) int {
+	if in.Index == 0 {
+		return in.MaxLevel
+	}
+	if o.Base < 2 {
+		return 0
+	}
+	level := 0
+	if o.Base&(o.Base-1) == 0 {
+		// Powers of two only need the trailing zeros
+		level = bits.TrailingZeros(uint(in.Index)) / bits.TrailingZeros(uint(o.Base))
+	} else {
+		for i := in.Index; i%o.Base == 0; i /= o.Base {
+			level++
+		}
+	}
+	return level
+}
+
+// DensityAware raises the level of outliers: nodes much farther from their
+// nearest neighbor than the nodes inserted before them. On skewed data such
+// nodes tend to end up with no incoming edge on layer 0, since they are
+// farther than all the existing neighbors of the nodes they link to; on
+// upper layers they are linked from the sparser nodes above.
+//
+// The mean is kept per generator, not per graph: in a PartitionedIndex the
+// insertions of every tenant feed the same mean, so a large tenant sets the
+// scale the nodes of the others are compared with. Tenants whose data is at
+// different scales are better served by a stateless generator.
+type DensityAware struct {
+	// Base chooses the level before the adjustment
+	Base LevelGenerator
+
+	// Threshold is the ratio to the mean nearest-neighbor distance above
+	// which a node is an outlier, and Boost the number of levels it is raised
+	Threshold float64
+	Boost     int
+
+	// Ef is the ef of the search for the nearest neighbor
+	Ef int
+
+	mean  float64
+	count int
+}
+
+// NewDensityAware returns a DensityAware generator raising by boost levels
+// the nodes whose nearest neighbor is more than threshold times farther
+// than the mean.
+func NewDensityAware(base LevelGenerator, threshold float64, boost int) *DensityAware {
+	return &DensityAware{Base: base, Threshold: threshold, Boost: boost, Ef: 10}
+}
+
+// minDensitySamples is the number of nodes DensityAware sees before it
+// starts raising outliers, so that the mean is meaningful
+const minDensitySamples = 32
+
+func (d *DensityAware) Level(in LevelInput) int {
+	level := d.Base.Level(in)
+
+	dist := float64(in.NearestDistance(max(d.Ef, 1)))
+	if math.IsInf(dist, 1) {
+		return level
+	}
+	if d.count >= minDensitySamples && dist > d.Threshold*d.mean {
+		level += d.Boost
+	}
+
+	// Running mean of the nearest-neighbor distances
+	d.count++
+	d.mean += (dist - d.mean) / float64(d.count)
+	return level
+}
@@ -0,0 +1,197 @@
+package hnsw
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// constantLevel gives every node the same level
+type constantLevel int
+
+func (c constantLevel) Level(LevelInput) int {
+	return int(c)
+}
+
+func newLevelIndex(t *testing.T, gen LevelGenerator) *HNSW {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.MaxLevel = 4
+	cfg.Seed = 1
+	cfg.LevelGenerator = gen
+	h, err := NewHNSW(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	return h
+}
+
+func TestGeometric(t *testing.T) {
+	h := newLevelIndex(t, nil)
+	in := LevelInput{ML: h.mL, MaxLevel: h.MaxLevel, h: h}
+
+	// The default generator draws the same levels as RandomLevel
+	for _, u := range []float64{1, 0.5, 0.1, 0.01, 0.001} {
+		h.RandFunc = func() float64 { return u }
+		if got, want := (Geometric{}).Level(in), h.RandomLevel(); got != want {
+			t.Errorf("u=%v: expected level %d, got %d", u, want, got)
+		}
+	}
+
+	h.RandFunc = func() float64 { return 0.01 }
+	if got := (Geometric{ML: 1}).Level(in); got != 4 {
+		t.Errorf("Expected level 4 with mL 1, got %d", got)
+	}
+}
+
+func TestFixedLayers(t *testing.T) {
+	h := newLevelIndex(t, nil)
+	in := LevelInput{h: h}
+	gen := FixedLayers{Layers: 4, Size: 10000}
+
+	counts := make([]int, 4)
+	for i := 0; i < 10000; i++ {
+		level := gen.Level(in)
+		if level < 0 || level > 3 {
+			t.Fatalf("Expected levels in [0, 3], got %d", level)
+		}
+		counts[level]++
+	}
+
+	// A node reaches level 1 with probability 10000^(-1/3) ≈ 0.046
+	above := 10000 - counts[0]
+	if want := 10000 * math.Pow(10000, -1.0/3); math.Abs(float64(above)-want) > 0.2*want {
+		t.Errorf("Expected about %.0f nodes above level 0, got %d", want, above)
+	}
+
+	if got := (FixedLayers{Layers: 1, Size: 100}).Level(in); got != 0 {
+		t.Errorf("Expected level 0 with a single layer, got %d", got)
+	}
+}
+
+func TestInsertionOrder(t *testing.T) {
+	tests := []struct {
+		base, index, level int
+	}{
+		{4, 0, 4},
+		{4, 1, 0},
+		{4, 4, 1},
+		{4, 8, 1},
+		{4, 16, 2},
+		{4, 64, 3},
+		{10, 0, 4},
+		{10, 5, 0},
+		{10, 30, 1},
+		{10, 300, 2},
+		{10, 1000, 3},
+		{1, 8, 0},
+	}
+	for _, tt := range tests {
+		in := LevelInput{Index: tt.index, MaxLevel: 4}
+		if got := (InsertionOrder{Base: tt.base}).Level(in); got != tt.level {
+			t.Errorf("Base %d, index %d: expected level %d, got %d", tt.base, tt.index, tt.level, got)
+		}
+	}
+
+	// Levels above MaxLevel are capped on insertion
+	h := newLevelIndex(t, InsertionOrder{Base: 2})
+	for i := 0; i < 64; i++ {
+		h.Insert([]float32{float32(i), 0}, i)
+	}
+	if h.Nodes[0].Level != 4 || h.Nodes[32].Level != 4 || h.Nodes[2].Level != 1 || h.Nodes[3].Level != 0 {
+		t.Errorf("Expected levels 4, 4, 1 and 0, got %d, %d, %d and %d",
+			h.Nodes[0].Level, h.Nodes[32].Level, h.Nodes[2].Level, h.Nodes[3].Level)
+	}
+}
+
+func TestDensityAware(t *testing.T) {
+	gen := NewDensityAware(constantLevel(0), 5, 2)
+	h := newLevelIndex(t, gen)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 200; i++ {
+		h.Insert([]float32{rng.Float32(), rng.Float32(), rng.Float32(), rng.Float32()}, i)
+	}
+	for _, node := range h.Nodes {
+		if node.Level != 0 {
+			t.Fatalf("Expected clustered node %d on level 0, got %d", node.ID, node.Level)
+		}
+	}
+
+	// An outlier is raised, and remains reachable
+	h.Insert([]float32{10, 10, 10, 10}, 200)
+	if h.Nodes[200].Level != 2 {
+		t.Errorf("Expected the outlier on level 2, got %d", h.Nodes[200].Level)
+	}
+	if results := h.KNN_Search([]float32{10, 10, 10, 10}, 1, 10); len(results) != 1 || results[0] != 200 {
+		t.Errorf("Expected the outlier to be found, got %v", results)
+	}
+
+	// A node close to the others is not
+	h.Insert([]float32{0.5, 0.5, 0.5, 0.5}, 201)
+	if h.Nodes[201].Level != 0 {
+		t.Errorf("Expected a clustered node on level 0, got %d", h.Nodes[201].Level)
+	}
+	// Stats cannot predict the level counts of a custom generator
+	if expected := h.Stats().Levels[0].Expected; expected != 0 {
+		t.Errorf("Expected no level prediction with a LevelGenerator, got %v", expected)
+	}
+}
+
+// nearestRecorder records the nearest distance seen by every insertion
+type nearestRecorder struct {
+	distances []float32
+}
+
+func (r *nearestRecorder) Level(in LevelInput) int {
+	r.distances = append(r.distances, in.NearestDistance(10))
+	return 0
+}
+
+func TestNearestDistance(t *testing.T) {
+	rec := &nearestRecorder{}
+	h := newLevelIndex(t, rec)
+	h.Insert([]float32{0, 0}, 0)
+	h.Insert([]float32{3, 4}, 1)
+	if err := h.Delete(0); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// The deleted node at the same position is not the nearest one
+	h.Insert([]float32{0, 0}, 2)
+	want := []float32{float32(math.Inf(1)), 25, 25}
+	if !slices.Equal(rec.distances, want) {
+		t.Errorf("Expected distances %v, got %v", want, rec.distances)
+	}
+}
+
+func TestDensityAwarePartitioned(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxLevel = 4
+	cfg.Seed = 1
+	cfg.LevelGenerator = NewDensityAware(constantLevel(0), 5, 2)
+	p, err := NewPartitioned(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create the partitioned index: %v", err)
+	}
+	if err := p.CreateTenant("a"); err != nil {
+		t.Fatalf("CreateTenant failed: %v", err)
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 200; i++ {
+		if _, err := p.Insert("a", []float32{rng.Float32(), rng.Float32(), rng.Float32(), rng.Float32()}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	// The outlier is measured against the tenant's graph, and raised
+	id, err := p.Insert("a", []float32{10, 10, 10, 10})
+	if err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if level := p.index.Nodes[id].Level; level != 2 {
+		t.Errorf("Expected the outlier on level 2, got %d", level)
+	}
+}
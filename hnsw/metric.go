@@ -0,0 +1,60 @@
+package hnsw
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Names of the built-in metrics
+const (
+	MetricL2           = "l2"
+	MetricCosine       = "cosine"
+	MetricInnerProduct = "ip"
+)
+
+// metrics maps metric names to distance functions. Distance functions
+// cannot be serialized, so indexes whose configuration is stored, such as
+// named collections, refer to their metric by name.
+var (
+	metricsMutex sync.RWMutex
+	metrics      = map[string]func([]float32, []float32) float32{
+		MetricL2:           EuclideanDistance,
+		MetricCosine:       CosineDistance,
+		MetricInnerProduct: InnerProductDistance,
+	}
+)
+
+// RegisterMetric makes a distance function available under name. It
+// replaces any function registered with the same name.
+func RegisterMetric(name string, distanceFunc func([]float32, []float32) float32) {
+	if name == "" || distanceFunc == nil {
+		panic("hnsw: RegisterMetric requires a name and a distance function")
+	}
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+	metrics[name] = distanceFunc
+}
+
+// Metric returns the distance function registered under name.
+func Metric(name string) (func([]float32, []float32) float32, error) {
+	metricsMutex.RLock()
+	defer metricsMutex.RUnlock()
+	fn, ok := metrics[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", name)
+	}
+	return fn, nil
+}
+
+// MetricNames returns the names of the registered metrics, sorted.
+func MetricNames() []string {
+	metricsMutex.RLock()
+	defer metricsMutex.RUnlock()
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,43 @@
+package hnsw
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestMetric(t *testing.T) {
+	for _, name := range []string{MetricL2, MetricCosine, MetricInnerProduct} {
+		if _, err := Metric(name); err != nil {
+			t.Errorf("Metric(%q) failed: %v", name, err)
+		}
+	}
+	if _, err := Metric("hamming"); err == nil {
+		t.Errorf("Expected an error for an unknown metric")
+	}
+
+	manhattan := func(a, b []float32) float32 {
+		var sum float32
+		for i := range a {
+			sum += max(a[i]-b[i], b[i]-a[i])
+		}
+		return sum
+	}
+	RegisterMetric("manhattan", manhattan)
+	defer func() {
+		metricsMutex.Lock()
+		delete(metrics, "manhattan")
+		metricsMutex.Unlock()
+	}()
+
+	fn, err := Metric("manhattan")
+	if err != nil {
+		t.Fatalf("Metric failed after RegisterMetric: %v", err)
+	}
+	if reflect.ValueOf(fn).Pointer() != reflect.ValueOf(manhattan).Pointer() {
+		t.Errorf("Metric returned a different function")
+	}
+	if names := MetricNames(); !slices.IsSorted(names) || !slices.Contains(names, "manhattan") {
+		t.Errorf("Unexpected metric names %v", names)
+	}
+}
@@ -0,0 +1,149 @@
+package hnsw
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"unsafe"
+)
+
+// MappedIndex is a read-only index backed by a memory-mapped file written by
+// Save. Vectors and neighbor lists are read directly from the mapped pages,
+// so opening an index costs the same regardless of its size and the OS page
+// cache is shared between every process that maps the same file.
+//
+// Opening validates the header, the file size and the links of every node,
+// which reads the graph sections once but not the vectors.
+//
+// A MappedIndex is safe for concurrent use by multiple goroutines.
+type MappedIndex struct {
+	// DistanceFunc calculates the distance between two vectors
+	DistanceFunc func([]float32, []float32) float32
+
+	data  []byte
+	unmap func([]byte) error
+
+	// Sections of the file, viewed in place
+	vectors []float32
+	graph   packedGraph
+
+	// visited holds reusable visited lists, one per concurrent search
+	visited sync.Pool
+}
+
+// OpenMapped maps the index file at path into memory. The distance function
+// must be the same one the index was built with.
+func OpenMapped(path string, distanceFunc func([]float32, []float32) float32) (*MappedIndex, error) {
+	if distanceFunc == nil {
+		return nil, fmt.Errorf("DistanceFunc must be provided")
+	}
+	if !littleEndian() {
+		return nil, fmt.Errorf("memory-mapped indexes require a little endian host")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, unmap, err := mapFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MappedIndex{DistanceFunc: distanceFunc, data: data, unmap: unmap}
+	if err := m.init(); err != nil {
+		unmap(data)
+		return nil, err
+	}
+	return m, nil
+}
+
+// init validates the header and slices the mapped file into its sections
+func (m *MappedIndex) init() error {
+	var hd indexHeader
+	if err := hd.decode(m.data); err != nil {
+		return err
+	}
+	if uint64(len(m.data)) < hd.size() {
+		return fmt.Errorf("%w: file is truncated", ErrInvalidIndex)
+	}
+
+	// Every section is made of 4-byte words, viewed in place
+	body := m.data[indexHeaderSize:hd.size()]
+	words := unsafe.Slice((*uint32)(unsafe.Pointer(unsafe.SliceData(body))), len(body)/4)
+	vectorWords := int(hd.Count) * int(hd.Dim)
+	m.vectors = unsafe.Slice((*float32)(unsafe.Pointer(unsafe.SliceData(words))), vectorWords)
+	m.graph = splitGraph(hd, words[vectorWords:])
+	if err := m.graph.validate(); err != nil {
+		return err
+	}
+	m.visited.New = func() any {
+		return &visitedList{marks: make([]uint32, hd.Count)}
+	}
+	return nil
+}
+
+// Close unmaps the file. The index must not be used after Close.
+func (m *MappedIndex) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	err := m.unmap(m.data)
+	m.data = nil
+	return err
+}
+
+// Len returns the number of nodes in the index.
+func (m *MappedIndex) Len() int {
+	return int(m.graph.header.Count)
+}
+
+// Dim returns the dimension of the indexed vectors.
+func (m *MappedIndex) Dim() int {
+	return int(m.graph.header.Dim)
+}
+
+// Vector returns the vector of the node with the given ID. The returned slice
+// points into the mapped file and must not be modified.
+func (m *MappedIndex) Vector(id int) []float32 {
+	dim := m.Dim()
+	return m.vectors[id*dim : (id+1)*dim : (id+1)*dim]
+}
+
+// Level returns the highest level of the node with the given ID.
+func (m *MappedIndex) Level(id int) int {
+	return m.graph.level(id)
+}
+
+// Neighbors returns the neighbor IDs of a node at the given level. The
+// returned slice points into the mapped file and must not be modified.
+func (m *MappedIndex) Neighbors(id, level int) []uint32 {
+	return m.graph.neighbors(id, level)
+}
+
+// KNN_Search performs a K-nearest neighbor search over the mapped graph,
+// following the same two-phase strategy as HNSW.KNN_Search.
+func (m *MappedIndex) KNN_Search(query []float32, K, ef int) []int {
+	if K <= 0 {
+		return nil
+	}
+	if ef < K {
+		ef = K
+	}
+
+	visited := m.visited.Get().(*visitedList)
+	defer m.visited.Put(visited)
+
+	candidates := m.graph.search(func(id int) float32 {
+		return m.DistanceFunc(query, m.Vector(id))
+	}, visited, ef)
+	return candidates[:min(K, len(candidates))]
+}
+
+// littleEndian reports whether the host stores integers in little endian order
+func littleEndian() bool {
+	probe := uint16(1)
+	return *(*byte)(unsafe.Pointer(&probe)) == 1
+}
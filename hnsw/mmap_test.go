@@ -0,0 +1,161 @@
+package hnsw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestOpenMapped verifies that a mapped index exposes the same graph
+// and returns the same results as the in-memory index it was saved from
+func TestOpenMapped(t *testing.T) {
+	h := buildRandomIndex(t, 500, 8)
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := h.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	m, err := OpenMapped(path, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("OpenMapped failed: %v", err)
+	}
+	defer m.Close()
+
+	if m.Len() != len(h.Nodes) || m.Dim() != 8 {
+		t.Fatalf("Expected %d nodes of dimension 8, got %d of dimension %d", len(h.Nodes), m.Len(), m.Dim())
+	}
+
+	for i, node := range h.Nodes {
+		if m.Level(i) != node.Level {
+			t.Errorf("Node %d: expected level %d, got %d", i, node.Level, m.Level(i))
+		}
+		if !reflect.DeepEqual(m.Vector(i), node.Vector) {
+			t.Errorf("Node %d: vector mismatch", i)
+		}
+		for lc := 0; lc <= node.Level; lc++ {
+			if !reflect.DeepEqual(m.Neighbors(i, lc), node.Neighbors[lc]) {
+				t.Errorf("Node %d level %d: expected neighbors %v, got %v", i, lc, node.Neighbors[lc], m.Neighbors(i, lc))
+			}
+		}
+		if m.Neighbors(i, node.Level+1) != nil {
+			t.Errorf("Node %d: expected no neighbors above its level", i)
+		}
+	}
+
+	for _, query := range [][]float32{
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5},
+		{1, 0, 1, 0, 1, 0, 1, 0},
+	} {
+		want := h.KNN_Search(query, 10, 40)
+		got := m.KNN_Search(query, 10, 40)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Query %v: expected %v, got %v", query, want, got)
+		}
+	}
+}
+
+// TestOpenMappedConcurrent verifies that concurrent searches do not interfere
+func TestOpenMappedConcurrent(t *testing.T) {
+	h := buildRandomIndex(t, 300, 4)
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := h.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	m, err := OpenMapped(path, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("OpenMapped failed: %v", err)
+	}
+	defer m.Close()
+
+	query := []float32{0.2, 0.4, 0.6, 0.8}
+	want := m.KNN_Search(query, 5, 20)
+
+	done := make(chan []int)
+	for i := 0; i < 8; i++ {
+		go func() {
+			var got []int
+			for j := 0; j < 50; j++ {
+				got = m.KNN_Search(query, 5, 20)
+			}
+			done <- got
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		if got := <-done; !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+}
+
+// TestOpenMappedInvalid verifies that malformed files are rejected
+func TestOpenMappedInvalid(t *testing.T) {
+	h := buildRandomIndex(t, 50, 4)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.hnsw")
+	if err := h.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	truncated := filepath.Join(dir, "truncated.hnsw")
+	if err := os.WriteFile(truncated, data[:len(data)-4], 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := OpenMapped(truncated, EuclideanDistance); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex for truncated file, got %v", err)
+	}
+
+	empty := filepath.Join(dir, "empty.hnsw")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if _, err := OpenMapped(empty, EuclideanDistance); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("Expected ErrInvalidIndex for empty file, got %v", err)
+	}
+
+	// Corrupted links keep the file size intact and must be found by
+	// scanning the graph sections
+	var hd indexHeader
+	if err := hd.decode(data); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	count := int(hd.Count)
+	levels := indexHeaderSize + 4*count*int(hd.Dim)
+	level0 := levels + 4*count
+	upperOffsets := level0 + 4*count*(1+int(hd.Mmax0))
+	top := int(hd.EntryPoint)
+	tests := []struct {
+		name   string
+		offset int
+		value  uint32
+	}{
+		{"dangling link", level0 + 4, uint32(count)},
+		{"level above MaxLevel", levels + 4*top, hd.MaxLevel + 1},
+		{"upper offset out of range", upperOffsets + 4*top, uint32(hd.UpperBlocks)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt := bytes.Clone(data)
+			binary.LittleEndian.PutUint32(corrupt[tt.offset:], tt.value)
+			path := filepath.Join(t.TempDir(), "corrupt.hnsw")
+			if err := os.WriteFile(path, corrupt, 0o644); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			if _, err := OpenMapped(path, EuclideanDistance); !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex from OpenMapped, got %v", err)
+			}
+			if _, err := OpenDisk(path, EuclideanDistance, 16); !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex from OpenDisk, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package hnsw
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the whole file read-only into memory
+func mapFile(f *os.File) ([]byte, func([]byte) error, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return nil, nil, ErrInvalidIndex
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, syscall.Munmap, nil
+}
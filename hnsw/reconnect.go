@@ -0,0 +1,114 @@
+package hnsw
+
+import (
+	"slices"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// maxReconnectRounds bounds the passes of Reconnect. Forcing an edge into a
+// full neighbor list evicts another edge, which may have been the only one
+// to some node; that node is rescued by the next pass.
+const maxReconnectRounds = 4
+
+// Reconnect finds the live nodes that cannot be reached from the entry point
+// on layer 0, and links them back into the graph. It returns the number of
+// nodes rescued.
+//
+// Pruning in updateBidirectionalConnections keeps the closest neighbors of
+// every node, so a node that is farther than all the existing neighbors of
+// its own neighbors can end up with no incoming edge at all. Searches never
+// return such a node. Reconnect re-links each one by running SEARCH-LAYER
+// from the entry point and adding the reverse edges; when pruning drops all
+// of them again, the node replaces the furthest neighbor of its closest
+// neighbor.
+//
+// Reconnect holds the write lock for the whole pass. It is worth running
+// after heavy deletions or updates, or when Verify reports unreachable nodes.
+func (h *HNSW) Reconnect() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.reconnect())
+}
+
+// reconnect re-links the unreachable live nodes and returns the IDs of the
+// ones rescued. The caller must hold the write lock.
+func (h *HNSW) reconnect() []int {
+	if h.EntryPoint == nil {
+		return nil
+	}
+
+	reached := h.reachable()
+	var lost []int
+	for id, node := range h.Nodes {
+		if !reached[id] && !node.Deleted {
+			lost = append(lost, id)
+		}
+	}
+	if len(lost) == 0 {
+		return nil
+	}
+
+	pending := lost
+	for round := 0; round < maxReconnectRounds && len(pending) > 0; round++ {
+		for _, id := range pending {
+			h.relink(h.Nodes[id], reached)
+		}
+
+		reached = h.reachable()
+		pending = pending[:0:0]
+		for id, node := range h.Nodes {
+			if !reached[id] && !node.Deleted {
+				pending = append(pending, id)
+			}
+		}
+	}
+
+	return slices.DeleteFunc(lost, func(id int) bool { return !reached[id] })
+}
+
+// relink connects node on layer 0 to its nearest reachable neighbors, and
+// makes sure that at least one of them links back to it
+func (h *HNSW) relink(node *structs.Node, reached []bool) {
+	id := node.ID
+	// Only live nodes are selected, and never the node itself
+	usable := func(n int) bool { return n != id && !h.Nodes[n].Deleted }
+
+	// Descend through the upper layers, which may still reach the node. The
+	// descent is only a better starting point if it ends on a node reachable
+	// on layer 0, otherwise the search could not find reachable neighbors
+	ep := h.EntryPoint
+	for lc := ep.Level; lc > 0; lc-- {
+		ep = h.greedySearchLayer(node.Vector, ep, lc, nil)
+	}
+	if !reached[ep.ID] {
+		ep = h.EntryPoint
+	}
+
+	// W ← SEARCH-LAYER(q, ep, efConstruction, 0)
+	nearest := h.searchLayer(node.Vector, ep, h.EfConstruction, 0, usable, nil)
+	if len(nearest) == 0 {
+		return
+	}
+	neighbors := nearest[:min(len(nearest), h.Mmax0)]
+	h.updateBidirectionalConnections(node, neighbors, 0, h.Mmax0)
+
+	for _, n := range neighbors {
+		if slices.Contains(h.Nodes[n].Neighbors[0], uint32(id)) {
+			return
+		}
+	}
+
+	// Pruning dropped every reverse edge, so the lists of the neighbors are
+	// full: replace the furthest neighbor of the closest one
+	closest := h.Nodes[neighbors[0]]
+	list := closest.Neighbors[0]
+	furthest, furthestDist := 0, float32(-1)
+	for i, n := range list {
+		if dist := h.DistanceFunc(closest.Vector, h.Nodes[n].Vector); dist > furthestDist {
+			furthest, furthestDist = i, dist
+		}
+	}
+	list[furthest] = uint32(id)
+	h.markDirty(closest.ID)
+}
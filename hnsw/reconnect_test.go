@@ -0,0 +1,84 @@
+package hnsw
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReconnect(t *testing.T) {
+	h := buildRandomIndex(t, 500, 8)
+
+	// Cut every edge to a few nodes on layer 0
+	cut := []int{10, 20, 30, 40}
+	for _, node := range h.Nodes {
+		node.Neighbors[0] = slices.DeleteFunc(node.Neighbors[0], func(id uint32) bool {
+			return slices.Contains(cut, int(id)) && h.Nodes[id] != h.EntryPoint
+		})
+	}
+	unreachable := h.Verify().Count(Unreachable)
+	if unreachable == 0 {
+		t.Fatalf("Expected unreachable nodes")
+	}
+
+	if rescued := h.Reconnect(); rescued != unreachable {
+		t.Fatalf("Expected %d nodes rescued, got %d", unreachable, rescued)
+	}
+	if report := h.Verify(); !report.OK() {
+		t.Fatalf("Expected a clean report, got %v", report.Issues)
+	}
+	for _, id := range cut {
+		if results := h.KNN_Search(h.Nodes[id].Vector, 1, 64); len(results) != 1 || results[0] != id {
+			t.Errorf("Expected node %d to be found, got %v", id, results)
+		}
+	}
+
+	// Nothing left to rescue
+	if rescued := h.Reconnect(); rescued != 0 {
+		t.Errorf("Expected no node rescued, got %d", rescued)
+	}
+}
+
+func TestReconnectOutliers(t *testing.T) {
+	h := buildRandomIndex(t, 1000, 4)
+
+	// Outliers are farther than every existing neighbor of the nodes they
+	// link to, so pruning drops all their reverse edges
+	for i := range 5 {
+		h.Insert([]float32{5, 5, 5, float32(5 + i)}, len(h.Nodes))
+	}
+	unreachable := h.Verify().Count(Unreachable)
+	if unreachable == 0 {
+		t.Fatalf("Expected unreachable outliers")
+	}
+
+	if rescued := h.Reconnect(); rescued != unreachable {
+		t.Fatalf("Expected %d nodes rescued, got %d", unreachable, rescued)
+	}
+	if report := h.Verify(); !report.OK() {
+		t.Fatalf("Expected a clean report, got %v", report.Issues)
+	}
+	results := h.KNN_Search([]float32{5, 5, 5, 5}, 5, 64)
+	for id := 1000; id < 1005; id++ {
+		if !slices.Contains(results, id) {
+			t.Errorf("Expected outlier %d in %v", id, results)
+		}
+	}
+}
+
+func TestReconnectDeleted(t *testing.T) {
+	h := buildRandomIndex(t, 200, 4)
+	for _, node := range h.Nodes {
+		node.Neighbors[0] = slices.DeleteFunc(node.Neighbors[0], func(id uint32) bool { return id == 7 })
+	}
+	if err := h.Delete(7); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Deleted nodes need not be reachable
+	if n := h.Verify().Count(Unreachable); n != 0 {
+		t.Errorf("Expected no unreachable node, got %d", n)
+	}
+	if rescued := h.Reconnect(); rescued != 0 {
+		t.Errorf("Expected no node rescued, got %d", rescued)
+	}
+}
@@ -1,6 +1,10 @@
 package hnsw
 
 import (
+	"context"
+	"math"
+	"time"
+
 	"dmarro89.github.com/hnsw-go/structs"
 )
 
@@ -23,6 +27,9 @@ Parameters:
   - entry: the entry point node at the current layer
   - ef: size of the dynamic candidate list (controls accuracy vs speed trade-off)
   - level: the current layer in the graph
+  - filter: if not nil, only nodes for which it returns true are added to the
+    results. Rejected nodes are still traversed, so the graph stays connected.
+  - trace: if not nil, records the candidates expanded and the work done
 
 Returns:
   - The ef closest nodes to the query vector, sorted in ascending order of distance.
@@ -32,12 +39,13 @@ Space Complexity: O(ef + N) where N is the number of visited nodes
 
 Note: For ef=1, it automatically switches to a more efficient greedy search strategy.
 */
-func (h *HNSW) searchLayer(query []float32, entry *structs.Node, ef, level int) []int {
+func (h *HNSW) searchLayer(query []float32, entry *structs.Node, ef, level int, filter func(id int) bool, trace *LayerTrace) []int {
 	//v ← ep  set of visited elements
-	// Increment the visit stamp for this search
-	// This is used to mark nodes as visited and avoid revisiting them
-	// in the same search iteration.
-	h.visitStamp++
+	// The visited list is versioned: starting a new search invalidates the
+	// marks of the previous one without clearing them.
+	visited := h.getVisited()
+	defer h.visited.Put(visited)
+	visited.next()
 
 	//C ← ep set of candidates
 	candidates := structs.NewMinHeap()
@@ -50,10 +58,20 @@ func (h *HNSW) searchLayer(query []float32, entry *structs.Node, ef, level int)
 	initialDist := h.DistanceFunc(query, entry.Vector)
 
 	candidates.Push(structs.NewNodeHeap(initialDist, entry.ID))
-	nearest.Push(structs.NewNodeHeap(initialDist, entry.ID))
+	if filter == nil || filter(entry.ID) {
+		nearest.Push(structs.NewNodeHeap(initialDist, entry.ID))
+	}
 
 	// Mark the entry point as visited
-	h.markVisited(entry.ID)
+	visited.visit(entry.ID)
+	if trace != nil {
+		trace.Visited++
+		trace.Distances++
+		trace.Stop = StopExhausted
+		if filter != nil && !filter(entry.ID) {
+			trace.Rejected++
+		}
+	}
 
 	var (
 		currentDist  float32
@@ -68,6 +86,8 @@ func (h *HNSW) searchLayer(query []float32, entry *structs.Node, ef, level int)
 		currentNode := h.Nodes[current.Id]
 
 		// f ← get furthest element from W to q
+		// While W is empty every element is a candidate
+		furthestDist = math.MaxFloat32
 		if nearest.Len() > 0 {
 			furthest := nearest.Peek()
 			furthestDist = furthest.Dist
@@ -75,9 +95,16 @@ func (h *HNSW) searchLayer(query []float32, entry *structs.Node, ef, level int)
 
 		// if distance(c, q) > distance(f, q)
 		// break  -> all elements in W are evaluated
-		if currentDist > furthestDist {
+		// With a filter, keep going until W holds ef accepted elements
+		if currentDist > furthestDist && (filter == nil || nearest.Len() >= ef) {
+			if trace != nil {
+				trace.Stop = StopBound
+			}
 			break
 		}
+		if trace != nil && !trace.summary {
+			trace.Candidates = append(trace.Candidates, TraceNode{ID: current.Id, Distance: currentDist})
+		}
 
 		if currentNode == nil || level >= len(currentNode.Neighbors) || len(currentNode.Neighbors[level]) == 0 {
 			continue
@@ -87,19 +114,30 @@ func (h *HNSW) searchLayer(query []float32, entry *structs.Node, ef, level int)
 		for _, neighborID := range currentNode.Neighbors[level] {
 			// if e ∉ v
 			// v ← v ⋃ e
-			if h.markVisited(neighborID) {
+			if visited.visit(int(neighborID)) {
 				continue
 			}
 
 			// f ← get furthest element from W to q
 			// if distance(e, q) < distance(f, q) or │W│ < ef
 			dist := h.DistanceFunc(query, h.Nodes[neighborID].Vector)
+			if trace != nil {
+				trace.Visited++
+				trace.Distances++
+			}
 			if dist < furthestDist || nearest.Len() < ef {
 
 				// C ← C ⋃ e
-				candidates.Push(structs.NewNodeHeap(dist, neighborID))
+				candidates.Push(structs.NewNodeHeap(dist, int(neighborID)))
+				if filter != nil && !filter(int(neighborID)) {
+					if trace != nil {
+						trace.Rejected++
+					}
+					continue
+				}
+
 				// W ← W ⋃ e
-				nearest.Push(structs.NewNodeHeap(dist, neighborID))
+				nearest.Push(structs.NewNodeHeap(dist, int(neighborID)))
 
 				// if │W│ > ef
 				// remove furthest element from W to q
@@ -124,9 +162,18 @@ func (h *HNSW) searchLayer(query []float32, entry *structs.Node, ef, level int)
 // greedySearchLayer performs a simple greedy search at a specific layer.
 // This is an optimization for ef=1 cases, following a simple hill-climbing approach.
 // It's used primarily during the upper layer searches in the HNSW algorithm.
-func (h *HNSW) greedySearchLayer(query []float32, entry *structs.Node, level int) *structs.Node {
+// If trace is not nil, the path followed and the work done are recorded.
+func (h *HNSW) greedySearchLayer(query []float32, entry *structs.Node, level int, trace *LayerTrace) *structs.Node {
 	currentNode := entry
 	bestDist := h.DistanceFunc(query, currentNode.Vector)
+	if trace != nil {
+		if !trace.summary {
+			trace.Path = append(trace.Path, entry.ID)
+		}
+		trace.Visited++
+		trace.Distances++
+		trace.Stop = StopLocalMinimum
+	}
 
 	for {
 		improved := false
@@ -136,10 +183,17 @@ func (h *HNSW) greedySearchLayer(query []float32, entry *structs.Node, level int
 			for _, neighborID := range currentNode.Neighbors[level] {
 				neighbor := h.Nodes[neighborID]
 				dist := h.DistanceFunc(query, neighbor.Vector)
+				if trace != nil {
+					trace.Visited++
+					trace.Distances++
+				}
 				if dist < bestDist {
 					bestDist = dist
 					currentNode = neighbor
 					improved = true
+					if trace != nil && !trace.summary {
+						trace.Path = append(trace.Path, neighbor.ID)
+					}
 					break // Take first improvement
 				}
 			}
@@ -169,20 +223,64 @@ func (h *HNSW) greedySearchLayer(query []float32, entry *structs.Node, level int
 // Note: ef should be >= K for meaningful results. Larger ef values give better
 // accuracy at the cost of slower search times.
 func (h *HNSW) KNN_Search(query []float32, K, ef int) []int {
-	if ef < K {
-		ef = K
+	return h.KNN_SearchFilter(query, K, ef, nil)
+}
+
+// KNN_SearchFilter is KNN_Search restricted to the nodes for which filter
+// returns true. Rejected nodes are still traversed, so the graph stays
+// connected, and the search continues until ef accepted nodes are found or
+// the graph is exhausted: a filter accepting few nodes makes the search
+// slower, not less accurate. A nil filter accepts every node.
+//
+// filter is called with the read lock held and must not use the index.
+func (h *HNSW) KNN_SearchFilter(query []float32, K, ef int, filter func(id int) bool) []int {
+	return h.KNN_SearchContext(context.Background(), query, K, ef, filter)
+}
+
+// KNN_SearchContext is KNN_SearchFilter, traced as a child of the span in
+// ctx. A nil filter accepts every node.
+func (h *HNSW) KNN_SearchContext(ctx context.Context, query []float32, K, ef int, filter func(id int) bool) []int {
+	span := h.startSpan(ctx, "hnsw.KNN_Search")
+	if span != nil {
+		defer span.End(nil)
 	}
 
-	h.mutex.RLock()
+	h.rlock("search")
 	defer h.mutex.RUnlock()
 
-	if h.EntryPoint == nil {
-		return nil
+	if h.Metrics == nil && span == nil {
+		return h.search(query, K, ef, h.EntryPoint, h.deleted > 0, filter, nil)
+	}
+
+	// A summary trace only counts the work done
+	start := time.Now()
+	trace := &SearchTrace{summary: true}
+	results := h.search(query, K, ef, h.EntryPoint, h.deleted > 0, filter, trace)
+	visited, distances := trace.totals()
+	if h.Metrics != nil {
+		h.Metrics.ObserveSearch(time.Since(start), visited, distances)
+	}
+	if span != nil {
+		span.SetAttributes(Attribute{"k", K}, Attribute{"ef", max(ef, K)}, Attribute{"visited", visited},
+			Attribute{"distances", distances}, Attribute{"results", len(results)})
+	}
+	return results
+}
+
+// search runs the two phases of KNN_Search from entry, which may be nil for
+// an empty graph. Deleted nodes are filtered out when hasDeleted is set, and
+// the search of every layer is recorded when trace is not nil. A K that is
+// not positive returns no results.
+// The caller must hold the read lock.
+func (h *HNSW) search(query []float32, K, ef int, entry *structs.Node, hasDeleted bool, filter func(id int) bool, trace *SearchTrace) []int {
+	if ef < K {
+		ef = K
 	}
 
-	// Set the entry point for the search.
 	// ep ← get entry point for hnsw
-	entry := h.EntryPoint
+	if entry == nil || K <= 0 {
+		return nil
+	}
 
 	// Get the top layer of the entry point.
 	// L ← level of ep // top layer for hnsw
@@ -193,7 +291,7 @@ func (h *HNSW) KNN_Search(query []float32, K, ef int) []int {
 	for lc := currentLevel; lc > 0; lc-- {
 		// Perform SEARCH-LAYER(q, ep, ef=1, lc)
 		// Greedy search with ef=1 to find the closest element at the current level.
-		newEntry := h.greedySearchLayer(query, entry, lc)
+		newEntry := h.greedySearchLayer(query, entry, lc, trace.layer(lc, entry.ID))
 		if newEntry == nil {
 			break
 		}
@@ -205,9 +303,22 @@ func (h *HNSW) KNN_Search(query []float32, K, ef int) []int {
 	// Perform beam search at level 0 with ef size.
 	// W ← SEARCH-LAYER(q, ep, ef, lc=0)
 
-	candidates := h.searchLayer(query, entry, ef, 0)
+	// Deleted nodes are traversed but never returned
+	if hasDeleted {
+		if accept := filter; accept != nil {
+			filter = func(id int) bool { return h.isLive(id) && accept(id) }
+		} else {
+			filter = h.isLive
+		}
+	}
+	candidates := h.searchLayer(query, entry, ef, 0, filter, trace.layer(0, entry.ID))
 
 	// Extract the top K nearest elements from W.
 	// return K nearest elements from W to q
-	return candidates[:K]
+	return candidates[:min(K, len(candidates))]
+}
+
+// isLive reports whether the node with the given ID has not been deleted
+func (h *HNSW) isLive(id int) bool {
+	return !h.Nodes[id].Deleted
 }
@@ -1,6 +1,9 @@
 package hnsw
 
 import (
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 )
 
@@ -29,6 +32,44 @@ func TestKNNSearchEmptyGraph(t *testing.T) {
 	}
 }
 
+// TestKNNSearchInvalidK verifies that a K that is not positive returns no
+// results instead of panicking
+func TestKNNSearchInvalidK(t *testing.T) {
+	h := buildRandomIndex(t, 50, 4)
+	query := []float32{0.5, 0.5, 0.5, 0.5}
+
+	for _, K := range []int{0, -1} {
+		if results := h.KNN_Search(query, K, 10); results != nil {
+			t.Errorf("K=%d: expected nil results, got %v", K, results)
+		}
+		if results := h.KNN_Search(query, K, -5); results != nil {
+			t.Errorf("K=%d, negative ef: expected nil results, got %v", K, results)
+		}
+	}
+
+	// The read-only indexes follow suit
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := h.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+	m, err := OpenMapped(path, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("OpenMapped failed: %v", err)
+	}
+	defer m.Close()
+	if results := m.KNN_Search(query, -1, 10); results != nil {
+		t.Errorf("Expected nil results from the mapped index, got %v", results)
+	}
+	d, err := OpenDisk(path, EuclideanDistance, 0)
+	if err != nil {
+		t.Fatalf("OpenDisk failed: %v", err)
+	}
+	defer d.Close()
+	if results, err := d.KNN_Search(query, -1, 10); results != nil || err != nil {
+		t.Errorf("Expected nil results from the disk index, got %v, %v", results, err)
+	}
+}
+
 // TestKNNSearchSingleElement verifies correct behavior with only one element
 func TestKNNSearchSingleElement(t *testing.T) {
 	config := Config{
@@ -273,3 +314,71 @@ func TestSearchWithDifferentEfValues(t *testing.T) {
 		previousResults = results
 	}
 }
+
+// TestKNNSearchFilter verifies that only accepted nodes are returned, even
+// when few nodes pass the filter
+func TestKNNSearchFilter(t *testing.T) {
+	h := buildRandomIndex(t, 1000, 8)
+	if err := h.Delete(10); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	query := h.Nodes[10].Vector
+
+	tests := []struct {
+		name   string
+		filter func(id int) bool
+		count  int
+	}{
+		{"even", func(id int) bool { return id%2 == 0 }, 10},
+		{"sparse", func(id int) bool { return id%100 == 0 }, 10},
+		{"fewer than K", func(id int) bool { return id < 5 || id == 10 }, 5},
+		{"none", func(id int) bool { return false }, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := h.KNN_SearchFilter(query, 10, 20, tt.filter)
+			if len(results) != tt.count {
+				t.Fatalf("Expected %d results, got %d", tt.count, len(results))
+			}
+			for _, id := range results {
+				if !tt.filter(id) || id == 10 {
+					t.Errorf("Result %d is rejected by the filter or deleted", id)
+				}
+			}
+		})
+	}
+
+	// With every node accepted, the results match KNN_Search
+	all := h.KNN_SearchFilter(query, 10, 20, func(int) bool { return true })
+	if fmt.Sprint(all) != fmt.Sprint(h.KNN_Search(query, 10, 20)) {
+		t.Errorf("Expected the same results as KNN_Search, got %v", all)
+	}
+}
+
+// TestKNNSearchConcurrent runs searches concurrently with insertions; run
+// with -race to check that searches do not share state
+func TestKNNSearchConcurrent(t *testing.T) {
+	h := buildRandomIndex(t, 500, 8)
+	query := []float32{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if results := h.KNN_Search(query, 10, 50); len(results) != 10 {
+					t.Errorf("Expected 10 results, got %d", len(results))
+					return
+				}
+			}
+		}()
+	}
+
+	// Insertions grow the index, and the visited lists, during the searches
+	for i := 0; i < 100; i++ {
+		h.Insert([]float32{100, 100, 100, 100, 100, 100, 100, float32(i)}, len(h.Nodes))
+	}
+	wg.Wait()
+}
@@ -0,0 +1,463 @@
+package hnsw
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// Serialized index layout (all integers little endian)
+//
+//	header        64 bytes, see indexHeader
+//	vectors       count*dim float32
+//	levels        count uint32, the top bit marks deleted nodes
+//	level0 links  count blocks of (1+Mmax0) uint32: neighbor count, then IDs
+//	upper offsets count uint32, index of the first upper block of each node
+//	upper links   upperBlocks blocks of (1+Mmax) uint32, one block per level ≥ 1
+//
+// Every section is made of 4-byte elements and the header is 64 bytes long,
+// so a memory-mapped file can be read in place without any decoding step.
+const (
+	indexMagic      = "hnsw-go\x00"
+	indexVersion    = 1
+	indexHeaderSize = 64
+
+	// noEntryPoint marks an empty index in the header
+	noEntryPoint = ^uint32(0)
+
+	// deletedFlag is set in the level word of deleted nodes
+	deletedFlag = uint32(1) << 31
+)
+
+// ErrInvalidIndex is returned when a serialized index is malformed.
+var ErrInvalidIndex = errors.New("invalid index file")
+
+// indexHeader is the fixed-size header of a serialized index
+type indexHeader struct {
+	Dim            uint32
+	Count          uint64
+	M              uint32
+	Mmax           uint32
+	Mmax0          uint32
+	EfConstruction uint32
+	MaxLevel       uint32
+	EntryPoint     uint32
+	UpperBlocks    uint64
+	Deleted        uint64
+}
+
+func (hd *indexHeader) encode() []byte {
+	b := make([]byte, indexHeaderSize)
+	copy(b[0:8], indexMagic)
+	binary.LittleEndian.PutUint32(b[8:], indexVersion)
+	binary.LittleEndian.PutUint32(b[12:], hd.Dim)
+	binary.LittleEndian.PutUint64(b[16:], hd.Count)
+	binary.LittleEndian.PutUint32(b[24:], hd.M)
+	binary.LittleEndian.PutUint32(b[28:], hd.Mmax)
+	binary.LittleEndian.PutUint32(b[32:], hd.Mmax0)
+	binary.LittleEndian.PutUint32(b[36:], hd.EfConstruction)
+	binary.LittleEndian.PutUint32(b[40:], hd.MaxLevel)
+	binary.LittleEndian.PutUint32(b[44:], hd.EntryPoint)
+	binary.LittleEndian.PutUint64(b[48:], hd.UpperBlocks)
+	binary.LittleEndian.PutUint64(b[56:], hd.Deleted)
+	return b
+}
+
+func (hd *indexHeader) decode(b []byte) error {
+	if len(b) < indexHeaderSize || string(b[0:8]) != indexMagic {
+		return ErrInvalidIndex
+	}
+	if v := binary.LittleEndian.Uint32(b[8:]); v != indexVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidIndex, v)
+	}
+	hd.Dim = binary.LittleEndian.Uint32(b[12:])
+	hd.Count = binary.LittleEndian.Uint64(b[16:])
+	hd.M = binary.LittleEndian.Uint32(b[24:])
+	hd.Mmax = binary.LittleEndian.Uint32(b[28:])
+	hd.Mmax0 = binary.LittleEndian.Uint32(b[32:])
+	hd.EfConstruction = binary.LittleEndian.Uint32(b[36:])
+	hd.MaxLevel = binary.LittleEndian.Uint32(b[40:])
+	hd.EntryPoint = binary.LittleEndian.Uint32(b[44:])
+	hd.UpperBlocks = binary.LittleEndian.Uint64(b[48:])
+	hd.Deleted = binary.LittleEndian.Uint64(b[56:])
+
+	if hd.M == 0 || hd.Mmax == 0 || hd.Mmax0 == 0 || hd.EfConstruction == 0 || hd.MaxLevel == 0 {
+		return fmt.Errorf("%w: invalid configuration", ErrInvalidIndex)
+	}
+	if hd.Count > uint64(noEntryPoint) {
+		return fmt.Errorf("%w: too many nodes", ErrInvalidIndex)
+	}
+	if (hd.Count == 0) != (hd.EntryPoint == noEntryPoint) || (hd.Count > 0 && uint64(hd.EntryPoint) >= hd.Count) {
+		return fmt.Errorf("%w: invalid entry point", ErrInvalidIndex)
+	}
+	if _, ok := sectionWords(
+		[2]uint64{hd.Count, uint64(hd.Dim)},
+		[2]uint64{hd.Count, 2 + uint64(hd.Mmax0)},
+		[2]uint64{hd.Count, 1},
+		[2]uint64{hd.UpperBlocks, 1 + uint64(hd.Mmax)},
+	); !ok {
+		return fmt.Errorf("%w: sections too large", ErrInvalidIndex)
+	}
+	return nil
+}
+
+// size returns the total size in bytes of a serialized index with this
+// header. decode guarantees that it does not overflow.
+func (hd *indexHeader) size() uint64 {
+	return indexHeaderSize + 4*(hd.Count*uint64(hd.Dim)+
+		hd.Count+
+		hd.Count*uint64(1+hd.Mmax0)+
+		hd.Count+
+		hd.UpperBlocks*uint64(1+hd.Mmax))
+}
+
+// config rebuilds the Config stored in the header
+func (hd *indexHeader) config(distanceFunc func([]float32, []float32) float32) Config {
+	return Config{
+		M:              int(hd.M),
+		Mmax:           int(hd.Mmax),
+		Mmax0:          int(hd.Mmax0),
+		EfConstruction: int(hd.EfConstruction),
+		MaxLevel:       int(hd.MaxLevel),
+		DistanceFunc:   distanceFunc,
+	}
+}
+
+// Save writes the index to w in the binary format described above.
+// The graph is read-locked for the duration of the write, so concurrent
+// searches can proceed while inserts wait.
+//
+// Save starts a new checkpoint epoch: the following Checkpoint only writes
+// the nodes changed after it.
+func (h *HNSW) Save(w io.Writer) (err error) {
+	span := h.startSpan(context.Background(), "hnsw.Save")
+	if span != nil {
+		defer func() { span.End(err) }()
+	}
+
+	h.checkpointMutex.Lock()
+	defer h.checkpointMutex.Unlock()
+	h.rlock("save")
+	defer h.mutex.RUnlock()
+
+	if span != nil {
+		span.SetAttributes(Attribute{"nodes", len(h.Nodes)})
+	}
+
+	if err := h.save(w); err != nil {
+		return h.observeError("save", err)
+	}
+	clear(h.dirty)
+	return nil
+}
+
+// save writes the index to w. The caller must hold the read lock.
+func (h *HNSW) save(w io.Writer) error {
+	hd := indexHeader{
+		Count:          uint64(len(h.Nodes)),
+		M:              uint32(h.M),
+		Mmax:           uint32(h.Mmax),
+		Mmax0:          uint32(h.Mmax0),
+		EfConstruction: uint32(h.EfConstruction),
+		MaxLevel:       uint32(h.MaxLevel),
+		EntryPoint:     noEntryPoint,
+		Deleted:        uint64(h.deleted),
+	}
+	if h.EntryPoint != nil {
+		hd.EntryPoint = uint32(h.EntryPoint.ID)
+		hd.Dim = uint32(len(h.EntryPoint.Vector))
+	}
+	for _, node := range h.Nodes {
+		hd.UpperBlocks += uint64(node.Level)
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := &encoder{w: bw}
+	enc.write(hd.encode())
+
+	for _, node := range h.Nodes {
+		enc.float32s(node.Vector)
+	}
+	for _, node := range h.Nodes {
+		level := uint32(node.Level)
+		if node.Deleted {
+			level |= deletedFlag
+		}
+		enc.uint32(level)
+	}
+	for _, node := range h.Nodes {
+		enc.links(node.Neighbors[0], h.Mmax0)
+	}
+	var block uint32
+	for _, node := range h.Nodes {
+		enc.uint32(block)
+		block += uint32(node.Level)
+	}
+	for _, node := range h.Nodes {
+		for lc := 1; lc <= node.Level; lc++ {
+			enc.links(node.Neighbors[lc], h.Mmax)
+		}
+	}
+
+	if enc.err != nil {
+		return enc.err
+	}
+	return bw.Flush()
+}
+
+// Load reads an index written by Save. Since functions cannot be serialized,
+// the distance function must be the same one the index was built with.
+func Load(r io.Reader, distanceFunc func([]float32, []float32) float32) (*HNSW, error) {
+	size, sized := remaining(r)
+	br := bufio.NewReader(r)
+	b := make([]byte, indexHeaderSize)
+	if _, err := io.ReadFull(br, b); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIndex, err)
+	}
+
+	var hd indexHeader
+	if err := hd.decode(b); err != nil {
+		return nil, err
+	}
+
+	if sized && uint64(size) < hd.size() {
+		return nil, fmt.Errorf("%w: file is truncated", ErrInvalidIndex)
+	}
+
+	h, err := NewHNSW(hd.config(distanceFunc))
+	if err != nil {
+		return nil, err
+	}
+
+	count, dim := int(hd.Count), int(hd.Dim)
+	dec := &decoder{r: br}
+	vectors := dec.float32s(count * dim)
+	levels := dec.uint32s(count)
+	level0 := dec.uint32s(count * (1 + h.Mmax0))
+	dec.uint32s(count) // upper offsets are implied by the levels
+	upper := dec.uint32s(int(hd.UpperBlocks) * (1 + h.Mmax))
+	if dec.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIndex, dec.err)
+	}
+
+	h.Nodes = make([]*structs.Node, 0, count)
+	block := 0
+	for i := 0; i < count; i++ {
+		level := int(levels[i] &^ deletedFlag)
+		if level > h.MaxLevel {
+			return nil, fmt.Errorf("%w: node %d level %d exceeds MaxLevel", ErrInvalidIndex, i, level)
+		}
+		if block+level > int(hd.UpperBlocks) {
+			return nil, fmt.Errorf("%w: upper links truncated", ErrInvalidIndex)
+		}
+
+		node := h.storage.NewNode(i, vectors[i*dim:(i+1)*dim], level)
+		if err := readLinks(node, 0, level0[i*(1+h.Mmax0):], h.Mmax0, count); err != nil {
+			return nil, err
+		}
+		for lc := 1; lc <= level; lc++ {
+			if err := readLinks(node, lc, upper[block*(1+h.Mmax):], h.Mmax, count); err != nil {
+				return nil, err
+			}
+			block++
+		}
+		if levels[i]&deletedFlag != 0 {
+			node.Deleted = true
+			h.deleted++
+		}
+		h.Nodes = append(h.Nodes, node)
+	}
+
+	if count > 0 {
+		h.EntryPoint = h.Nodes[hd.EntryPoint]
+	}
+	return h, nil
+}
+
+// SaveFile writes the index to the file at path, replacing it atomically.
+func (h *HNSW) SaveFile(path string) error {
+	return writeFileAtomic(path, h.Save)
+}
+
+// LoadFile reads an index from the file at path.
+func LoadFile(path string, distanceFunc func([]float32, []float32) float32) (*HNSW, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f, distanceFunc)
+}
+
+// writeFileAtomic writes a file through a temporary file and a rename, so
+// that readers never see a partially written file. Every write gets its own
+// temporary file, so concurrent writes to the same path each publish a
+// complete file.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// CreateTemp makes the file readable by its owner only
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// sectionWords returns the total number of words of sections made of
+// count×width words each, or false if the total does not fit in an int as
+// a number of bytes
+func sectionWords(sections ...[2]uint64) (uint64, bool) {
+	var total uint64
+	for _, section := range sections {
+		hi, lo := bits.Mul64(section[0], section[1])
+		var carry uint64
+		total, carry = bits.Add64(total, lo, 0)
+		if hi != 0 || carry != 0 {
+			return 0, false
+		}
+	}
+	return total, total <= (math.MaxInt-indexHeaderSize)/4
+}
+
+// remaining returns the number of bytes left to read in r when it is known,
+// which is the case for files and in-memory readers, so that a header
+// announcing more data than there is can be rejected before allocating
+func remaining(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return 0, false
+		}
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return info.Size() - pos, true
+	}
+	return 0, false
+}
+
+// readLinks copies a serialized (count, IDs...) block into the node's neighbor list at level
+func readLinks(node *structs.Node, level int, block []uint32, maxConn, nodeCount int) error {
+	n := int(block[0])
+	if n > maxConn {
+		return fmt.Errorf("%w: node %d has %d neighbors at level %d", ErrInvalidIndex, node.ID, n, level)
+	}
+	for _, id := range block[1 : 1+n] {
+		if int(id) >= nodeCount {
+			return fmt.Errorf("%w: node %d links to unknown node %d", ErrInvalidIndex, node.ID, id)
+		}
+		node.Neighbors[level] = append(node.Neighbors[level], id)
+	}
+	return nil
+}
+
+// encoder writes little endian values, remembering the first error
+type encoder struct {
+	w   io.Writer
+	buf [4]byte
+	err error
+}
+
+func (e *encoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) uint32(v uint32) {
+	binary.LittleEndian.PutUint32(e.buf[:], v)
+	e.write(e.buf[:])
+}
+
+func (e *encoder) float32s(v []float32) {
+	for _, f := range v {
+		e.uint32(math.Float32bits(f))
+	}
+}
+
+// links writes a neighbor list as a fixed-size block of 1+maxConn uint32
+func (e *encoder) links(neighbors []uint32, maxConn int) {
+	e.uint32(uint32(len(neighbors)))
+	for _, id := range neighbors {
+		e.uint32(id)
+	}
+	for i := len(neighbors); i < maxConn; i++ {
+		e.uint32(0)
+	}
+}
+
+// decoder reads little endian values, remembering the first error
+type decoder struct {
+	r   io.Reader
+	err error
+}
+
+// decodeChunk is the number of words uint32s reads at once: memory grows
+// with the data actually read, so a corrupt count fails at the end of the
+// input instead of allocating for it up front
+const decodeChunk = 1 << 16
+
+func (d *decoder) uint32s(n int) []uint32 {
+	if d.err != nil {
+		return nil
+	}
+	b := make([]byte, 4*min(n, decodeChunk))
+	v := make([]uint32, 0, min(n, decodeChunk))
+	for len(v) < n {
+		chunk := b[:4*min(n-len(v), decodeChunk)]
+		if _, d.err = io.ReadFull(d.r, chunk); d.err != nil {
+			return nil
+		}
+		for i := 0; i < len(chunk); i += 4 {
+			v = append(v, binary.LittleEndian.Uint32(chunk[i:]))
+		}
+	}
+	return v
+}
+
+func (d *decoder) float32s(n int) []float32 {
+	u := d.uint32s(n)
+	if u == nil {
+		return nil
+	}
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = math.Float32frombits(u[i])
+	}
+	return v
+}
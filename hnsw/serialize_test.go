@@ -0,0 +1,241 @@
+package hnsw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// buildRandomIndex inserts n random vectors of the given dimension into a new index
+func buildRandomIndex(t testing.TB, n, dim int) *HNSW {
+	t.Helper()
+
+	h, err := NewHNSW(Config{
+		M:              8,
+		Mmax:           8,
+		Mmax0:          16,
+		EfConstruction: 64,
+		MaxLevel:       4,
+		DistanceFunc:   EuclideanDistance,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	h.RandFunc = rng.Float64
+	for i := 0; i < n; i++ {
+		vector := make([]float32, dim)
+		for j := range vector {
+			vector[j] = rng.Float32()
+		}
+		h.Insert(vector, i)
+	}
+	return h
+}
+
+// TestSaveLoadRoundTrip verifies that a loaded index has the same
+// configuration, vectors and neighbor lists as the saved one
+func TestSaveLoadRoundTrip(t *testing.T) {
+	h := buildRandomIndex(t, 300, 8)
+
+	var buf bytes.Buffer
+	if err := h.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(bytes.NewReader(buf.Bytes()), EuclideanDistance)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if loaded.M != h.M || loaded.Mmax != h.Mmax || loaded.Mmax0 != h.Mmax0 ||
+		loaded.EfConstruction != h.EfConstruction || loaded.MaxLevel != h.MaxLevel {
+		t.Errorf("Configuration mismatch after load")
+	}
+	if loaded.EntryPoint.ID != h.EntryPoint.ID {
+		t.Errorf("Expected entry point %d, got %d", h.EntryPoint.ID, loaded.EntryPoint.ID)
+	}
+	if len(loaded.Nodes) != len(h.Nodes) {
+		t.Fatalf("Expected %d nodes, got %d", len(h.Nodes), len(loaded.Nodes))
+	}
+	for i, node := range h.Nodes {
+		got := loaded.Nodes[i]
+		if got.ID != node.ID || got.Level != node.Level {
+			t.Errorf("Node %d: expected ID %d level %d, got ID %d level %d", i, node.ID, node.Level, got.ID, got.Level)
+		}
+		if !reflect.DeepEqual(got.Vector, node.Vector) {
+			t.Errorf("Node %d: vector mismatch", i)
+		}
+		if !reflect.DeepEqual(got.Neighbors, node.Neighbors) {
+			t.Errorf("Node %d: neighbors mismatch, expected %v, got %v", i, node.Neighbors, got.Neighbors)
+		}
+	}
+
+	query := []float32{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+	if !reflect.DeepEqual(loaded.KNN_Search(query, 10, 50), h.KNN_Search(query, 10, 50)) {
+		t.Errorf("Search results differ after load")
+	}
+
+	// The loaded index must accept new inserts
+	loaded.Insert(query, len(loaded.Nodes))
+	if len(loaded.Nodes) != len(h.Nodes)+1 {
+		t.Errorf("Insert after load failed")
+	}
+}
+
+// TestSaveLoadEmpty verifies that an empty index survives a round trip
+func TestSaveLoadEmpty(t *testing.T) {
+	h, err := NewHNSW(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := h.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := Load(&buf, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.EntryPoint != nil || len(loaded.Nodes) != 0 {
+		t.Errorf("Expected an empty index")
+	}
+}
+
+// TestLoadInvalid verifies that malformed input is rejected
+func TestLoadInvalid(t *testing.T) {
+	h := buildRandomIndex(t, 50, 4)
+	var buf bytes.Buffer
+	if err := h.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	badMagic := bytes.Clone(data)
+	badMagic[0] = 'X'
+
+	badLink := bytes.Clone(data)
+	// First neighbor of node 0 at level 0 points past the end of the index
+	offset := indexHeaderSize + 4*(50*4+50) + 4
+	badLink[offset], badLink[offset+1], badLink[offset+2], badLink[offset+3] = 0xff, 0xff, 0, 0
+
+	// Section sizes overflowing an int, or far larger than the data
+	overflow := bytes.Clone(data)
+	binary.LittleEndian.PutUint32(overflow[12:], math.MaxUint32)
+	binary.LittleEndian.PutUint64(overflow[16:], math.MaxUint32)
+	oversized := bytes.Clone(data)
+	binary.LittleEndian.PutUint32(oversized[32:], math.MaxUint32)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"bad magic", badMagic},
+		{"truncated", data[:len(data)-10]},
+		{"dangling link", badLink},
+		{"overflowing sections", overflow},
+		{"oversized sections", oversized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(bytes.NewReader(tt.data), EuclideanDistance)
+			if !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex, got %v", err)
+			}
+
+			// Without a known size, the data runs out before the sections
+			_, err = Load(io.MultiReader(bytes.NewReader(tt.data)), EuclideanDistance)
+			if !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("Expected ErrInvalidIndex from a stream, got %v", err)
+			}
+		})
+	}
+}
+
+// FuzzLoad verifies that Load rejects corrupt input with an error rather
+// than a panic
+func FuzzLoad(f *testing.F) {
+	h := buildRandomIndex(f, 20, 4)
+	var buf bytes.Buffer
+	if err := h.Save(&buf); err != nil {
+		f.Fatalf("Save failed: %v", err)
+	}
+	f.Add(buf.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		loaded, err := Load(bytes.NewReader(data), EuclideanDistance)
+		if err == nil && len(loaded.Nodes) > 0 {
+			loaded.KNN_Search(loaded.Nodes[0].Vector, 3, 10)
+		}
+	})
+}
+
+// TestWriteFileAtomicConcurrent verifies that concurrent saves to the same
+// path do not share a temporary file, and that failed saves leave nothing
+// behind
+func TestWriteFileAtomicConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.hnsw")
+	indexes := []*HNSW{buildRandomIndex(t, 200, 4), buildRandomIndex(t, 300, 4)}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := indexes[i%2].SaveFile(path); err != nil {
+				t.Errorf("SaveFile failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	loaded, err := LoadFile(path, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if n := len(loaded.Nodes); n != 200 && n != 300 {
+		t.Errorf("Expected 200 or 300 nodes, got %d", n)
+	}
+
+	failed := errors.New("write failed")
+	if err := writeFileAtomic(path, func(io.Writer) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the index in the directory, got %d entries", len(entries))
+	}
+}
+
+// TestSaveLoadFile verifies the file helpers
+func TestSaveLoadFile(t *testing.T) {
+	h := buildRandomIndex(t, 100, 4)
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+
+	if err := h.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+	loaded, err := LoadFile(path, EuclideanDistance)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if len(loaded.Nodes) != 100 {
+		t.Errorf("Expected 100 nodes, got %d", len(loaded.Nodes))
+	}
+}
@@ -0,0 +1,129 @@
+package hnsw
+
+import (
+	"math"
+	"unsafe"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// Stats describes the shape of the graph. It is meant to be printed or
+// encoded as JSON, to check how the parameters of an index play out.
+type Stats struct {
+	// Nodes is the number of nodes, deleted ones included
+	Nodes   int `json:"nodes"`
+	Deleted int `json:"deleted"`
+	Dim     int `json:"dim"`
+
+	// Levels holds the statistics of every layer, from layer 0 to the top
+	Levels []LevelStats `json:"levels"`
+
+	Memory MemoryStats `json:"memory"`
+}
+
+// LevelStats describes a layer of the graph.
+type LevelStats struct {
+	Level int `json:"level"`
+
+	// Nodes is the number of nodes on the layer, and Expected the number
+	// predicted by the level distribution: a node is on layer l with
+	// probability exp(-l/mL). Expected is zero when a LevelGenerator is
+	// set, since its distribution is not known.
+	Nodes    int     `json:"nodes"`
+	Expected float64 `json:"expected"`
+
+	// MaxDegree is the degree limit of the layer, Mmax0 for layer 0 and
+	// Mmax above
+	MaxDegree  int     `json:"max_degree"`
+	MeanDegree float64 `json:"mean_degree"`
+
+	// Degrees is the degree histogram: Degrees[d] nodes have d neighbors
+	Degrees []int `json:"degrees"`
+
+	// Full is the share of nodes with MaxDegree neighbors or more
+	Full float64 `json:"full"`
+}
+
+// MemoryStats is an estimate of the memory used by the index, in bytes.
+type MemoryStats struct {
+	// Vectors is the size of the vectors
+	Vectors int64 `json:"vectors"`
+	// Neighbors is the size reserved for the neighbor lists, which are
+	// allocated with their degree limit as capacity
+	Neighbors int64 `json:"neighbors"`
+	// Overhead is the size of the node headers and of the bookkeeping of
+	// the index
+	Overhead int64 `json:"overhead"`
+	Total    int64 `json:"total"`
+}
+
+// Stats computes the statistics of the graph. It walks every node, so it
+// takes time linear in the size of the index while holding the read lock.
+func (h *HNSW) Stats() Stats {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	stats := Stats{Nodes: len(h.Nodes), Deleted: h.deleted, Dim: h.storage.Vectors.Dim()}
+	top := -1
+	if h.EntryPoint != nil {
+		top = h.EntryPoint.Level
+	}
+	for _, node := range h.Nodes {
+		top = max(top, node.Level)
+	}
+
+	stats.Levels = make([]LevelStats, top+1)
+	edges := make([]int, top+1)
+	for level := range stats.Levels {
+		maxConn := h.maxConn(level)
+		stats.Levels[level] = LevelStats{
+			Level:     level,
+			MaxDegree: maxConn,
+			Degrees:   make([]int, maxConn+1),
+		}
+		if h.LevelGenerator == nil {
+			stats.Levels[level].Expected = float64(len(h.Nodes)) * math.Exp(-float64(level)/h.mL)
+		}
+	}
+
+	memory := &stats.Memory
+	for _, node := range h.Nodes {
+		memory.Vectors += int64(len(node.Vector)) * int64(unsafe.Sizeof(float32(0)))
+		memory.Overhead += int64(unsafe.Sizeof(structs.Node{})) + int64(unsafe.Sizeof(node))
+		memory.Overhead += int64(len(node.Neighbors)) * int64(unsafe.Sizeof([]uint32(nil)))
+
+		for level, neighbors := range node.Neighbors {
+			memory.Neighbors += int64(cap(neighbors)) * int64(unsafe.Sizeof(uint32(0)))
+
+			// Lists beyond the level of the node, which Verify reports as
+			// LevelMismatch, only count towards memory
+			if level > node.Level {
+				continue
+			}
+			s := &stats.Levels[level]
+			s.Nodes++
+			edges[level] += len(neighbors)
+			for len(neighbors) >= len(s.Degrees) {
+				// Only a corrupted list can exceed the limit
+				s.Degrees = append(s.Degrees, 0)
+			}
+			s.Degrees[len(neighbors)]++
+		}
+	}
+	memory.Overhead += int64(len(h.dirty)) * int64(unsafe.Sizeof(uint64(0)))
+	memory.Total = memory.Vectors + memory.Neighbors + memory.Overhead
+
+	for level := range stats.Levels {
+		s := &stats.Levels[level]
+		if s.Nodes == 0 {
+			continue
+		}
+		full := 0
+		for d := s.MaxDegree; d < len(s.Degrees); d++ {
+			full += s.Degrees[d]
+		}
+		s.MeanDegree = float64(edges[level]) / float64(s.Nodes)
+		s.Full = float64(full) / float64(s.Nodes)
+	}
+	return stats
+}
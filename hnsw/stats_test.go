@@ -0,0 +1,120 @@
+package hnsw
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	h := buildRandomIndex(t, 2000, 8)
+	if err := h.Delete(3); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	stats := h.Stats()
+	if stats.Nodes != 2000 || stats.Deleted != 1 || stats.Dim != 8 {
+		t.Fatalf("Expected 2000 nodes, 1 deleted, dimension 8, got %d, %d, %d", stats.Nodes, stats.Deleted, stats.Dim)
+	}
+	if len(stats.Levels) != h.EntryPoint.Level+1 {
+		t.Fatalf("Expected %d levels, got %d", h.EntryPoint.Level+1, len(stats.Levels))
+	}
+
+	for level, s := range stats.Levels {
+		nodes, edges, full := 0, 0, 0
+		for _, node := range h.Nodes {
+			if node.Level < level {
+				continue
+			}
+			nodes++
+			edges += len(node.Neighbors[level])
+			if len(node.Neighbors[level]) == h.maxConn(level) {
+				full++
+			}
+		}
+		if s.Nodes != nodes {
+			t.Errorf("Level %d: expected %d nodes, got %d", level, nodes, s.Nodes)
+		}
+		if s.MaxDegree != h.maxConn(level) || len(s.Degrees) != s.MaxDegree+1 {
+			t.Errorf("Level %d: expected max degree %d, got %d with %d buckets", level, h.maxConn(level), s.MaxDegree, len(s.Degrees))
+		}
+		if want := float64(edges) / float64(nodes); math.Abs(s.MeanDegree-want) > 1e-9 {
+			t.Errorf("Level %d: expected mean degree %v, got %v", level, want, s.MeanDegree)
+		}
+		if want := float64(full) / float64(nodes); math.Abs(s.Full-want) > 1e-9 {
+			t.Errorf("Level %d: expected full share %v, got %v", level, want, s.Full)
+		}
+
+		total := 0
+		for _, n := range s.Degrees {
+			total += n
+		}
+		if total != nodes {
+			t.Errorf("Level %d: expected the histogram to count %d nodes, got %d", level, nodes, total)
+		}
+	}
+
+	// Level 0 holds every node, and level 1 about a 1/M fraction of them
+	if stats.Levels[0].Expected != 2000 {
+		t.Errorf("Expected 2000 nodes on level 0, got %v", stats.Levels[0].Expected)
+	}
+	if want := 2000.0 / float64(h.M); math.Abs(stats.Levels[1].Expected-want) > 1e-9 {
+		t.Errorf("Expected %v nodes on level 1, got %v", want, stats.Levels[1].Expected)
+	}
+
+	memory := stats.Memory
+	if memory.Vectors != 2000*8*4 {
+		t.Errorf("Expected %d bytes of vectors, got %d", 2000*8*4, memory.Vectors)
+	}
+	if memory.Neighbors < 2000*int64(h.Mmax0)*4 {
+		t.Errorf("Expected at least %d bytes of neighbors, got %d", 2000*h.Mmax0*4, memory.Neighbors)
+	}
+	if memory.Overhead <= 0 || memory.Total != memory.Vectors+memory.Neighbors+memory.Overhead {
+		t.Errorf("Expected a positive overhead and a consistent total, got %+v", memory)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Stats
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Nodes != stats.Nodes || len(decoded.Levels) != len(stats.Levels) || decoded.Memory != stats.Memory {
+		t.Errorf("Expected the JSON to round-trip, got %s", data)
+	}
+}
+
+// TestStatsLevelMismatch verifies that neighbor lists not matching the
+// level of their node do not make Stats panic
+func TestStatsLevelMismatch(t *testing.T) {
+	h := buildRandomIndex(t, 100, 4)
+	top := h.EntryPoint.Level
+	node := h.Nodes[len(h.Nodes)-1]
+	for len(node.Neighbors) <= top+1 {
+		node.Neighbors = append(node.Neighbors, []uint32{0})
+	}
+	if h.Verify().Count(LevelMismatch) == 0 {
+		t.Fatalf("Expected a level mismatch")
+	}
+
+	stats := h.Stats()
+	if len(stats.Levels) != top+1 {
+		t.Errorf("Expected %d levels, got %d", top+1, len(stats.Levels))
+	}
+	if stats.Levels[0].Nodes != 100 {
+		t.Errorf("Expected 100 nodes on level 0, got %d", stats.Levels[0].Nodes)
+	}
+}
+
+func TestStatsEmpty(t *testing.T) {
+	h, err := NewHNSW(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	stats := h.Stats()
+	if stats.Nodes != 0 || len(stats.Levels) != 0 || stats.Memory.Total != 0 {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
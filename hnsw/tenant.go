@@ -0,0 +1,299 @@
+package hnsw
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+var (
+	// ErrTenantNotFound is returned for operations on an unknown tenant.
+	ErrTenantNotFound = errors.New("tenant not found")
+
+	// ErrTenantExists is returned when creating a tenant that already exists.
+	ErrTenantExists = errors.New("tenant already exists")
+)
+
+// PartitionedIndex holds one HNSW graph per tenant in a single index.
+//
+// Every tenant has its own entry point, and a node is only ever linked to
+// nodes of its own tenant, since insertions start from the tenant's entry
+// point. Searches therefore explore the tenant's graph alone, with the
+// recall of a dedicated index, while the tenants share the vector and link
+// arenas, the visited lists and the lock of a single HNSW. A tenant costs a
+// map entry and its list of node IDs, so creating one is O(1).
+//
+// All tenants share the configuration, the metric and the vector dimension.
+// Node IDs are global and assigned by Insert. Deleting a tenant marks its
+// nodes as deleted, like Delete: their memory is reclaimed only by moving
+// the remaining tenants to a new index. Large tenants can be moved into a
+// dedicated HNSW with ExtractTenant.
+//
+// A PartitionedIndex is safe for concurrent use by multiple goroutines.
+type PartitionedIndex struct {
+	// index stores the nodes of every tenant. Its own EntryPoint is unused.
+	index *HNSW
+
+	// tenants and owner are guarded by index.mutex
+	tenants map[string]*tenant
+
+	// owner is the tenant of every node, by ID
+	owner []*tenant
+}
+
+// tenant is the graph of a single tenant inside a PartitionedIndex
+type tenant struct {
+	name  string
+	entry *structs.Node
+
+	// ids are the nodes of the tenant, in insertion order
+	ids     []uint32
+	deleted int
+
+	// dropped is set when the tenant is deleted, for nodes that still
+	// refer to it in owner
+	dropped bool
+}
+
+// TenantStats describes the graph of a tenant.
+type TenantStats struct {
+	Name string
+
+	// Len is the number of live nodes, Deleted the number of deleted ones
+	Len     int
+	Deleted int
+
+	// Levels holds the number of nodes, deleted ones included, whose top
+	// level is at least i, for every level i of the tenant's graph
+	Levels []int
+}
+
+// NewPartitioned creates an empty partitioned index with the specified
+// configuration, shared by every tenant.
+func NewPartitioned(cfg Config) (*PartitionedIndex, error) {
+	h, err := NewHNSW(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &PartitionedIndex{index: h, tenants: make(map[string]*tenant)}, nil
+}
+
+// CreateTenant creates an empty tenant.
+func (p *PartitionedIndex) CreateTenant(name string) error {
+	p.index.mutex.Lock()
+	defer p.index.mutex.Unlock()
+
+	if _, ok := p.tenants[name]; ok {
+		return fmt.Errorf("%w: %q", ErrTenantExists, name)
+	}
+	p.tenants[name] = &tenant{name: name}
+	return nil
+}
+
+// DropTenant deletes a tenant and marks all its nodes as deleted. The name
+// can be reused immediately.
+func (p *PartitionedIndex) DropTenant(name string) error {
+	p.index.mutex.Lock()
+	defer p.index.mutex.Unlock()
+
+	t, err := p.tenant(name)
+	if err != nil {
+		return err
+	}
+	for _, id := range t.ids {
+		if node := p.index.Nodes[id]; !node.Deleted {
+			node.Deleted = true
+			p.index.deleted++
+			p.index.markDirty(int(id))
+		}
+	}
+	t.dropped = true
+	t.ids = nil
+	delete(p.tenants, name)
+	return nil
+}
+
+// Tenants returns the names of the tenants, sorted.
+func (p *PartitionedIndex) Tenants() []string {
+	p.index.mutex.RLock()
+	defer p.index.mutex.RUnlock()
+
+	names := make([]string, 0, len(p.tenants))
+	for name := range p.tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tenant returns the tenant with the given name. The caller must hold the lock.
+func (p *PartitionedIndex) tenant(name string) (*tenant, error) {
+	t, ok := p.tenants[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTenantNotFound, name)
+	}
+	return t, nil
+}
+
+// Insert adds a vector to the graph of a tenant and returns its node ID.
+func (p *PartitionedIndex) Insert(tenant string, vector []float32) (int, error) {
+	p.index.mutex.Lock()
+	defer p.index.mutex.Unlock()
+
+	t, err := p.tenant(tenant)
+	if err != nil {
+		return 0, err
+	}
+	if len(vector) == 0 {
+		return 0, errors.New("vector cannot be empty")
+	}
+	if dim := p.index.storage.Vectors.Dim(); dim != 0 && len(vector) != dim {
+		return 0, fmt.Errorf("vector dimension %d, index dimension %d", len(vector), dim)
+	}
+
+	id := len(p.index.Nodes)
+	p.index.insert(vector, id, &t.entry)
+	t.ids = append(t.ids, uint32(id))
+	p.owner = append(p.owner, t)
+	return id, nil
+}
+
+// Delete marks a node of a tenant as deleted. It returns ErrNodeNotFound if
+// the node does not exist, is already deleted or belongs to another tenant.
+func (p *PartitionedIndex) Delete(tenant string, id int) error {
+	p.index.mutex.Lock()
+	defer p.index.mutex.Unlock()
+
+	t, err := p.tenant(tenant)
+	if err != nil {
+		return err
+	}
+	node, err := p.index.liveNode(id)
+	if err != nil || p.owner[id] != t {
+		return ErrNodeNotFound
+	}
+
+	node.Deleted = true
+	p.index.deleted++
+	p.index.markDirty(id)
+	t.deleted++
+	return nil
+}
+
+// KNN_Search performs a K-nearest neighbor search in the graph of a tenant.
+// The results only ever contain nodes of that tenant.
+func (p *PartitionedIndex) KNN_Search(tenant string, query []float32, K, ef int) ([]int, error) {
+	p.index.mutex.RLock()
+	defer p.index.mutex.RUnlock()
+
+	t, err := p.tenant(tenant)
+	if err != nil {
+		return nil, err
+	}
+	return p.index.search(query, K, ef, t.entry, t.deleted > 0, nil, nil), nil
+}
+
+// Vector returns a copy of the vector of a node, or ErrNodeNotFound if the
+// node does not exist or has been deleted.
+func (p *PartitionedIndex) Vector(id int) ([]float32, error) {
+	return p.index.Vector(id)
+}
+
+// Len returns the number of live nodes over all tenants.
+func (p *PartitionedIndex) Len() int {
+	return p.index.Len()
+}
+
+// Dim returns the dimension of the vectors, or 0 if the index is empty.
+func (p *PartitionedIndex) Dim() int {
+	return p.index.Dim()
+}
+
+// TenantStats returns the size and the level distribution of a tenant.
+func (p *PartitionedIndex) TenantStats(tenant string) (TenantStats, error) {
+	p.index.mutex.RLock()
+	defer p.index.mutex.RUnlock()
+
+	t, err := p.tenant(tenant)
+	if err != nil {
+		return TenantStats{}, err
+	}
+
+	stats := TenantStats{Name: t.name, Len: len(t.ids) - t.deleted, Deleted: t.deleted}
+	if t.entry != nil {
+		stats.Levels = make([]int, t.entry.Level+1)
+	}
+	for _, id := range t.ids {
+		for level := 0; level <= p.index.Nodes[id].Level; level++ {
+			stats.Levels[level]++
+		}
+	}
+	return stats, nil
+}
+
+// ExtractTenant copies the graph of a tenant into a new HNSW with the same
+// configuration, so that a large tenant can be served by a dedicated index.
+// The graph is copied as it is, without searches: node i of the new index is
+// node ids[i] of the partitioned index, and deleted nodes stay deleted. The
+// tenant is left in place; drop it once traffic has moved to the new index.
+//
+// The new index keeps the level policy: the LevelGenerator value and the
+// Seed are copied. A stateful generator such as *DensityAware is then
+// shared by both indexes, which lock separately: set a new one on either
+// index before inserting into both.
+func (p *PartitionedIndex) ExtractTenant(tenant string) (h *HNSW, ids []int, err error) {
+	p.index.mutex.RLock()
+	defer p.index.mutex.RUnlock()
+
+	t, err := p.tenant(tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	src := p.index
+	h, err = NewHNSW(Config{
+		M:              src.M,
+		Mmax:           src.Mmax,
+		Mmax0:          src.Mmax0,
+		EfConstruction: src.EfConstruction,
+		MaxLevel:       src.MaxLevel,
+		DistanceFunc:   src.DistanceFunc,
+		Seed:           src.seed,
+		LevelGenerator: src.LevelGenerator,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	h.mL = src.mL
+
+	// Neighbors always belong to the same tenant, so every edge is remapped
+	newID := make(map[uint32]uint32, len(t.ids))
+	for i, id := range t.ids {
+		newID[id] = uint32(i)
+	}
+
+	ids = make([]int, len(t.ids))
+	h.Nodes = make([]*structs.Node, 0, len(t.ids))
+	for i, id := range t.ids {
+		old := src.Nodes[id]
+		node := h.storage.NewNode(i, old.Vector, old.Level)
+		for level, neighbors := range old.Neighbors {
+			for _, n := range neighbors {
+				node.Neighbors[level] = append(node.Neighbors[level], newID[n])
+			}
+		}
+		if old.Deleted {
+			node.Deleted = true
+			h.deleted++
+		}
+		h.Nodes = append(h.Nodes, node)
+		h.markDirty(i)
+		ids[i] = int(id)
+	}
+	if t.entry != nil {
+		h.EntryPoint = h.Nodes[newID[uint32(t.entry.ID)]]
+	}
+	return h, ids, nil
+}
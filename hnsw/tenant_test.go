@@ -0,0 +1,271 @@
+package hnsw
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// buildPartitioned creates a partitioned index with n random vectors per
+// tenant, inserted in an interleaved order
+func buildPartitioned(t *testing.T, tenants []string, n, dim int) *PartitionedIndex {
+	t.Helper()
+	p, err := NewPartitioned(Config{
+		M:              8,
+		Mmax:           8,
+		Mmax0:          16,
+		EfConstruction: 64,
+		MaxLevel:       4,
+		DistanceFunc:   EuclideanDistance,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create partitioned index: %v", err)
+	}
+	rng := rand.New(rand.NewPCG(3, 4))
+	p.index.RandFunc = rng.Float64
+
+	for _, name := range tenants {
+		if err := p.CreateTenant(name); err != nil {
+			t.Fatalf("CreateTenant(%q) failed: %v", name, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		for _, name := range tenants {
+			vector := make([]float32, dim)
+			for j := range vector {
+				vector[j] = rng.Float32()
+			}
+			if _, err := p.Insert(name, vector); err != nil {
+				t.Fatalf("Insert failed: %v", err)
+			}
+		}
+	}
+	return p
+}
+
+// tenantRecall returns the recall@K of a tenant search against brute force
+// over the live nodes of the tenant
+func tenantRecall(t *testing.T, p *PartitionedIndex, name string, query []float32, K int) float64 {
+	t.Helper()
+	results, err := p.KNN_Search(name, query, K, 50)
+	if err != nil {
+		t.Fatalf("KNN_Search failed: %v", err)
+	}
+
+	tn := p.tenants[name]
+	var live []int
+	for _, id := range tn.ids {
+		if !p.index.Nodes[id].Deleted {
+			live = append(live, int(id))
+		}
+	}
+	sort.Slice(live, func(i, j int) bool {
+		return EuclideanDistance(query, p.index.Nodes[live[i]].Vector) < EuclideanDistance(query, p.index.Nodes[live[j]].Vector)
+	})
+	truth := live[:min(K, len(live))]
+
+	found := 0
+	for _, id := range results {
+		if slices.Contains(truth, id) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(truth))
+}
+
+func TestPartitionedIsolation(t *testing.T) {
+	tenants := []string{"acme", "globex", "initech"}
+	p := buildPartitioned(t, tenants, 300, 8)
+
+	// Every edge stays inside its tenant
+	for id, node := range p.index.Nodes {
+		for _, neighbors := range node.Neighbors {
+			for _, n := range neighbors {
+				if p.owner[n] != p.owner[id] {
+					t.Fatalf("Node %d of %q linked to node %d of %q", id, p.owner[id].name, n, p.owner[n].name)
+				}
+			}
+		}
+	}
+
+	query := []float32{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+	for _, name := range tenants {
+		results, err := p.KNN_Search(name, query, 10, 50)
+		if err != nil {
+			t.Fatalf("KNN_Search failed: %v", err)
+		}
+		for _, id := range results {
+			if p.owner[id].name != name {
+				t.Errorf("Search in %q returned node %d of %q", name, id, p.owner[id].name)
+			}
+		}
+		if r := tenantRecall(t, p, name, query, 10); r < 0.9 {
+			t.Errorf("Tenant %q: recall %v below 0.9", name, r)
+		}
+	}
+
+	if got := p.Tenants(); fmt.Sprint(got) != fmt.Sprint(tenants) {
+		t.Errorf("Expected tenants %v, got %v", tenants, got)
+	}
+	if p.Len() != 900 || p.Dim() != 8 {
+		t.Errorf("Expected 900 nodes of dimension 8, got %d of dimension %d", p.Len(), p.Dim())
+	}
+}
+
+func TestPartitionedDelete(t *testing.T) {
+	p := buildPartitioned(t, []string{"a", "b"}, 100, 4)
+	query := []float32{0.5, 0.5, 0.5, 0.5}
+
+	before, _ := p.KNN_Search("a", query, 5, 50)
+	if err := p.Delete("b", before[0]); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound deleting a node of another tenant, got %v", err)
+	}
+	if err := p.Delete("a", before[0]); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := p.Delete("a", before[0]); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound deleting twice, got %v", err)
+	}
+	after, _ := p.KNN_Search("a", query, 5, 50)
+	if slices.Contains(after, before[0]) {
+		t.Errorf("Deleted node %d returned by search", before[0])
+	}
+
+	stats, err := p.TenantStats("a")
+	if err != nil {
+		t.Fatalf("TenantStats failed: %v", err)
+	}
+	if stats.Len != 99 || stats.Deleted != 1 || stats.Levels[0] != 100 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	for i := 1; i < len(stats.Levels); i++ {
+		if stats.Levels[i] > stats.Levels[i-1] {
+			t.Errorf("Level counts must not grow with the level: %v", stats.Levels)
+		}
+	}
+
+	// Dropping a tenant deletes its nodes and frees its name
+	if err := p.DropTenant("b"); err != nil {
+		t.Fatalf("DropTenant failed: %v", err)
+	}
+	if _, err := p.KNN_Search("b", query, 5, 50); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("Expected ErrTenantNotFound after DropTenant, got %v", err)
+	}
+	if p.Len() != 99 {
+		t.Errorf("Expected 99 live nodes after DropTenant, got %d", p.Len())
+	}
+	if err := p.CreateTenant("b"); err != nil {
+		t.Fatalf("CreateTenant after drop failed: %v", err)
+	}
+	if results, _ := p.KNN_Search("b", query, 5, 50); len(results) != 0 {
+		t.Errorf("Expected a recreated tenant to be empty, got %v", results)
+	}
+	if r := tenantRecall(t, p, "a", query, 5); r < 0.8 {
+		t.Errorf("Recall of the remaining tenant %v below 0.8", r)
+	}
+}
+
+func TestPartitionedErrors(t *testing.T) {
+	p := buildPartitioned(t, []string{"a"}, 10, 4)
+
+	if err := p.CreateTenant("a"); !errors.Is(err, ErrTenantExists) {
+		t.Errorf("Expected ErrTenantExists, got %v", err)
+	}
+	if _, err := p.Insert("missing", []float32{1, 2, 3, 4}); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("Expected ErrTenantNotFound, got %v", err)
+	}
+	if _, err := p.Insert("a", []float32{1, 2}); err == nil {
+		t.Errorf("Expected an error for a dimension mismatch")
+	}
+	if _, err := p.Insert("a", nil); err == nil {
+		t.Errorf("Expected an error for an empty vector")
+	}
+	if err := p.DropTenant("missing"); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("Expected ErrTenantNotFound, got %v", err)
+	}
+	if _, err := p.TenantStats("missing"); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("Expected ErrTenantNotFound, got %v", err)
+	}
+	if err := p.Delete("a", 100); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+func TestExtractTenant(t *testing.T) {
+	p := buildPartitioned(t, []string{"small", "large"}, 200, 8)
+	if err := p.Delete("large", 3); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	h, ids, err := p.ExtractTenant("large")
+	if err != nil {
+		t.Fatalf("ExtractTenant failed: %v", err)
+	}
+	if len(h.Nodes) != 200 || h.Len() != 199 || len(ids) != 200 {
+		t.Fatalf("Expected 200 nodes with 199 live, got %d with %d live", len(h.Nodes), h.Len())
+	}
+
+	// The copied graph answers exactly like the tenant graph
+	rng := rand.New(rand.NewPCG(7, 8))
+	for q := 0; q < 20; q++ {
+		query := make([]float32, 8)
+		for j := range query {
+			query[j] = rng.Float32()
+		}
+		expected, _ := p.KNN_Search("large", query, 10, 40)
+		got := h.KNN_Search(query, 10, 40)
+		for i := range got {
+			got[i] = ids[got[i]]
+		}
+		if !slices.Equal(expected, got) {
+			t.Fatalf("Query %d: expected %v, got %v", q, expected, got)
+		}
+	}
+
+	// The extracted index is independent and can grow on its own
+	h.Insert(make([]float32, 8), len(h.Nodes))
+	if h.Len() != 200 || p.Len() != 399 {
+		t.Errorf("Expected the indexes to be independent, got %d and %d nodes", h.Len(), p.Len())
+	}
+}
+
+// TestExtractTenantLevelPolicy verifies that the extracted index keeps the
+// level generator and the seed of the partitioned index
+func TestExtractTenantLevelPolicy(t *testing.T) {
+	generator := InsertionOrder{Base: 4}
+	p, err := NewPartitioned(Config{
+		M:              8,
+		Mmax:           8,
+		Mmax0:          16,
+		EfConstruction: 64,
+		MaxLevel:       4,
+		DistanceFunc:   EuclideanDistance,
+		Seed:           42,
+		LevelGenerator: generator,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create partitioned index: %v", err)
+	}
+	if err := p.CreateTenant("a"); err != nil {
+		t.Fatalf("CreateTenant failed: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := p.Insert("a", []float32{float32(i), 1}); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	h, _, err := p.ExtractTenant("a")
+	if err != nil {
+		t.Fatalf("ExtractTenant failed: %v", err)
+	}
+	if h.LevelGenerator != generator {
+		t.Errorf("Expected the level generator %v, got %v", generator, h.LevelGenerator)
+	}
+	if got, want := h.RandFunc(), rand.New(rand.NewPCG(42, 42)).Float64(); got != want {
+		t.Errorf("Expected the levels to be drawn from seed 42, got %v instead of %v", got, want)
+	}
+}
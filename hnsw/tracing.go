@@ -0,0 +1,59 @@
+package hnsw
+
+import "context"
+
+// Tracer creates spans around the operations of an index, so that they show
+// up in distributed traces. Set it in the Tracer field of HNSW before the
+// index is used; a nil Tracer behaves like NoopTracer. An OpenTelemetry
+// adapter only needs to wrap a trace.Tracer.
+//
+// The spans are:
+//
+//	hnsw.Insert      attributes id and level
+//	hnsw.KNN_Search  attributes k, ef, visited, distances and results
+//	hnsw.Save        attribute nodes
+//	hnsw.Checkpoint  attribute nodes, the number of nodes written
+//
+// Insert, KNN_Search and KNN_SearchFilter start root spans; InsertContext
+// and KNN_SearchContext start children of the span in their context.
+type Tracer interface {
+	// Start starts a span named op, child of the span in ctx if any, and
+	// returns a context holding the new span
+	Start(ctx context.Context, op string) (context.Context, Span)
+}
+
+// Span is an operation in progress, created by a Tracer.
+type Span interface {
+	// SetAttributes adds attributes describing the operation
+	SetAttributes(attrs ...Attribute)
+
+	// End ends the span, with the error returned by the operation, if any
+	End(err error)
+}
+
+// Attribute is a numeric property of a span.
+type Attribute struct {
+	Key   string
+	Value int
+}
+
+// NoopTracer is a Tracer whose spans do nothing.
+type NoopTracer struct{}
+
+func (NoopTracer) Start(ctx context.Context, op string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) End(error)                  {}
+
+// startSpan starts a span for op, or returns nil when tracing is disabled
+func (h *HNSW) startSpan(ctx context.Context, op string) Span {
+	if h.Tracer == nil {
+		return nil
+	}
+	_, span := h.Tracer.Start(ctx, op)
+	return span
+}
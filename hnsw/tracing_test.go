@@ -0,0 +1,125 @@
+package hnsw
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// recordedSpan is a span kept by recordingTracer
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  map[string]int
+	ended  bool
+	err    error
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) End(err error) {
+	s.ended, s.err = true, err
+}
+
+type spanKey struct{}
+
+// recordingTracer keeps every span in memory
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, op string) (context.Context, Span) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	span := &recordedSpan{name: op, attrs: make(map[string]int)}
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		span.parent = parent.name
+	}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// errWriter fails every write
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestTracer(t *testing.T) {
+	h := buildRandomIndex(t, 100, 4)
+	tracer := &recordingTracer{}
+	h.Tracer = tracer
+
+	h.Insert([]float32{0.5, 0.5, 0.5, 0.5}, 100)
+	ctx, parent := tracer.Start(context.Background(), "request")
+	results := h.KNN_SearchContext(ctx, []float32{0.1, 0.2, 0.3, 0.4}, 5, 20, nil)
+	parent.End(nil)
+	if err := h.Save(&bytes.Buffer{}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := h.Checkpoint(&bytes.Buffer{}); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	saveErr := h.Save(errWriter{})
+
+	trace := h.SearchExplain([]float32{0.1, 0.2, 0.3, 0.4}, 5, 20)
+	tests := []struct {
+		name   string
+		parent string
+		attrs  map[string]int
+		err    error
+	}{
+		{"hnsw.Insert", "", map[string]int{"id": 100, "level": h.Nodes[100].Level}, nil},
+		{"request", "", map[string]int{}, nil},
+		{"hnsw.KNN_Search", "request", map[string]int{"k": 5, "ef": 20, "visited": trace.Visited, "distances": trace.Distances, "results": len(results)}, nil},
+		{"hnsw.Save", "", map[string]int{"nodes": 101}, nil},
+		{"hnsw.Checkpoint", "", map[string]int{"nodes": 0}, nil},
+		{"hnsw.Save", "", map[string]int{"nodes": 101}, saveErr},
+	}
+
+	if len(tracer.spans) != len(tests) {
+		t.Fatalf("Expected %d spans, got %d", len(tests), len(tracer.spans))
+	}
+	for i, tt := range tests {
+		span := tracer.spans[i]
+		if span.name != tt.name || span.parent != tt.parent {
+			t.Errorf("Span %d: expected %s with parent %q, got %s with parent %q", i, tt.name, tt.parent, span.name, span.parent)
+		}
+		if !span.ended || span.err != tt.err {
+			t.Errorf("Span %d: expected to end with error %v, got %v (ended %v)", i, tt.err, span.err, span.ended)
+		}
+		if len(span.attrs) != len(tt.attrs) {
+			t.Errorf("Span %d: expected attributes %v, got %v", i, tt.attrs, span.attrs)
+		}
+		for k, v := range tt.attrs {
+			if span.attrs[k] != v {
+				t.Errorf("Span %d: expected %s=%d, got %d", i, k, v, span.attrs[k])
+			}
+		}
+	}
+	if saveErr == nil {
+		t.Errorf("Expected Save to fail")
+	}
+}
+
+func TestNoopTracer(t *testing.T) {
+	h := buildRandomIndex(t, 50, 4)
+	h.Tracer = NoopTracer{}
+
+	h.Insert([]float32{0.5, 0.5, 0.5, 0.5}, 50)
+	if h.Len() != 51 {
+		t.Errorf("Expected 51 nodes, got %d", h.Len())
+	}
+	if results := h.KNN_Search([]float32{0.5, 0.5, 0.5, 0.5}, 5, 10); len(results) != 5 {
+		t.Errorf("Expected 5 results, got %v", results)
+	}
+}
@@ -0,0 +1,260 @@
+package hnsw
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"sync"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// DefaultTuneKs are the values of K tuned by AutoTune when none are given.
+var DefaultTuneKs = []int{1, 10, 100}
+
+// DefaultTuneSamples is the number of indexed vectors AutoTune samples as
+// queries when no sample queries are given.
+const DefaultTuneSamples = 100
+
+// ErrNotTuned is returned by Search when AutoTune has not been run for the
+// requested recall target and K.
+var ErrNotTuned = errors.New("index not tuned for this recall target")
+
+// TuneResult is the ef selected by AutoTune for a value of K.
+type TuneResult struct {
+	K  int
+	Ef int
+
+	// Recall is the mean recall@K measured on the sample with Ef
+	Recall float64
+}
+
+// tuneSample is a query used by AutoTune with its exact neighbors. Queries
+// taken from the index hold out their own node, which is excluded from both
+// the exact neighbors and the search results.
+type tuneSample struct {
+	query   []float32
+	exclude int
+	truth   []int
+}
+
+// AutoTune finds, for every K in Ks (DefaultTuneKs if empty), the smallest
+// ef for which KNN_Search reaches targetRecall on average over the sample
+// queries. The exact neighbors of the samples are computed by brute force.
+//
+// If sampleQueries is empty, DefaultTuneSamples indexed vectors are used as
+// held-out queries: each one is excluded from its own exact neighbors and
+// search results, so the sample behaves like queries outside the index.
+//
+// The results are stored with the target, so that Search can be called with
+// just K and a recall target. When even an exhaustive ef cannot reach the
+// target on the sample, the largest ef tried is stored and the returned
+// Recall is below the target. The tuning is not serialized, and should be
+// repeated when the index changes significantly.
+//
+// AutoTune runs its searches one at a time; the index can be used
+// concurrently, but modifications affect the measured recall.
+func (h *HNSW) AutoTune(targetRecall float64, sampleQueries [][]float32, Ks ...int) ([]TuneResult, error) {
+	if targetRecall <= 0 || targetRecall > 1 {
+		return nil, errors.New("target recall must be in (0, 1]")
+	}
+	if len(Ks) == 0 {
+		Ks = DefaultTuneKs
+	}
+	for _, K := range Ks {
+		if K <= 0 {
+			return nil, errors.New("K must be positive")
+		}
+	}
+	maxK := slices.Max(Ks)
+
+	samples, live, err := h.tuneSamples(sampleQueries, maxK)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TuneResult, 0, len(Ks))
+	for _, K := range Ks {
+		results = append(results, h.tuneEf(samples, targetRecall, K, live))
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.tuning == nil {
+		h.tuning = make(map[float64]map[int]int)
+	}
+	if h.tuning[targetRecall] == nil {
+		h.tuning[targetRecall] = make(map[int]int)
+	}
+	for _, res := range results {
+		h.tuning[targetRecall][res.K] = res.Ef
+	}
+	return results, nil
+}
+
+// tuneSamples prepares the sample queries with their exact maxK nearest
+// neighbors. It also returns the number of live nodes.
+func (h *HNSW) tuneSamples(sampleQueries [][]float32, maxK int) ([]tuneSample, int, error) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	live := len(h.Nodes) - h.deleted
+	if live == 0 {
+		return nil, 0, errors.New("cannot tune an empty index")
+	}
+
+	var samples []tuneSample
+	if len(sampleQueries) > 0 {
+		dim := h.storage.Vectors.Dim()
+		for _, q := range sampleQueries {
+			if len(q) != dim {
+				return nil, 0, fmt.Errorf("sample query dimension %d, index dimension %d", len(q), dim)
+			}
+			samples = append(samples, tuneSample{query: q, exclude: -1})
+		}
+	} else {
+		// A fixed seed keeps the tuning reproducible for a given index
+		rng := rand.New(rand.NewPCG(uint64(len(h.Nodes)), uint64(live)))
+		for _, i := range rng.Perm(len(h.Nodes)) {
+			if len(samples) == DefaultTuneSamples {
+				break
+			}
+			if node := h.Nodes[i]; !node.Deleted {
+				samples = append(samples, tuneSample{query: node.Vector, exclude: node.ID})
+			}
+		}
+	}
+
+	// Exact neighbors, with the queries split among the available CPUs
+	var wg sync.WaitGroup
+	workers := runtime.GOMAXPROCS(0)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < len(samples); i += workers {
+				samples[i].truth = h.exactNeighbors(samples[i].query, maxK, samples[i].exclude)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return samples, live, nil
+}
+
+// exactNeighbors returns the K nearest live nodes to query, closest first,
+// skipping the node exclude. The caller must hold the read lock.
+func (h *HNSW) exactNeighbors(query []float32, K, exclude int) []int {
+	nearest := structs.NewMaxHeap()
+	for _, node := range h.Nodes {
+		if node.Deleted || node.ID == exclude {
+			continue
+		}
+		dist := h.DistanceFunc(query, node.Vector)
+		if nearest.Len() < K {
+			nearest.Push(structs.NewNodeHeap(dist, node.ID))
+		} else if dist < nearest.Peek().Dist {
+			nearest.Pop()
+			nearest.Push(structs.NewNodeHeap(dist, node.ID))
+		}
+	}
+
+	ids := make([]int, nearest.Len())
+	for i := len(ids) - 1; i >= 0; i-- {
+		ids[i] = nearest.Pop().Id
+	}
+	return ids
+}
+
+// tuneEf binary-searches the smallest ef reaching targetRecall for K.
+// Recall grows with ef, so an upper bound is first found by doubling ef,
+// up to the number of live nodes.
+func (h *HNSW) tuneEf(samples []tuneSample, targetRecall float64, K, live int) TuneResult {
+	lo, hi := K, K
+	recall := h.sampleRecall(samples, K, hi)
+	for recall < targetRecall && hi < live {
+		lo = hi + 1
+		hi = min(2*hi, live)
+		recall = h.sampleRecall(samples, K, hi)
+	}
+	if recall < targetRecall {
+		return TuneResult{K: K, Ef: hi, Recall: recall}
+	}
+
+	// The smallest ef meeting the target is in [lo, hi]
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if r := h.sampleRecall(samples, K, mid); r >= targetRecall {
+			hi, recall = mid, r
+		} else {
+			lo = mid + 1
+		}
+	}
+	return TuneResult{K: K, Ef: hi, Recall: recall}
+}
+
+// sampleRecall returns the mean recall@K of KNN_Search with ef over samples
+func (h *HNSW) sampleRecall(samples []tuneSample, K, ef int) float64 {
+	var total float64
+	for _, s := range samples {
+		truth := s.truth[:min(K, len(s.truth))]
+		if len(truth) == 0 {
+			total++
+			continue
+		}
+
+		var results []int
+		if s.exclude >= 0 {
+			// Ask for one more result, in case the held-out node is found
+			results = slices.DeleteFunc(h.KNN_Search(s.query, K+1, max(ef, K+1)), func(id int) bool {
+				return id == s.exclude
+			})
+		} else {
+			results = h.KNN_Search(s.query, K, ef)
+		}
+		results = results[:min(K, len(results))]
+
+		found := 0
+		for _, id := range results {
+			if slices.Contains(truth, id) {
+				found++
+			}
+		}
+		total += float64(found) / float64(len(truth))
+	}
+	return total / float64(len(samples))
+}
+
+// Search performs a K-nearest neighbor search with the ef selected by
+// AutoTune for targetRecall. If K was not tuned, the ef of the smallest
+// tuned K above it is used; if the target was not tuned, the smallest tuned
+// target above it is used. It returns ErrNotTuned when neither exists.
+func (h *HNSW) Search(query []float32, K int, targetRecall float64) ([]int, error) {
+	ef, ok := h.tunedEf(K, targetRecall)
+	if !ok {
+		return nil, ErrNotTuned
+	}
+	return h.KNN_Search(query, K, ef), nil
+}
+
+// tunedEf looks up the ef stored by AutoTune for K and targetRecall
+func (h *HNSW) tunedEf(K int, targetRecall float64) (int, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	bestTarget, bestK, ef := 2.0, 0, 0
+	for target, table := range h.tuning {
+		if target < targetRecall || target > bestTarget {
+			continue
+		}
+		for k, e := range table {
+			if k < K || (target == bestTarget && bestK != 0 && k >= bestK) {
+				continue
+			}
+			bestTarget, bestK, ef = target, k, e
+		}
+	}
+	return ef, bestK != 0
+}
@@ -0,0 +1,179 @@
+package hnsw
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestAutoTune(t *testing.T) {
+	h := buildRandomIndex(t, 2000, 16)
+
+	rng := rand.New(rand.NewPCG(5, 6))
+	queries := make([][]float32, 50)
+	for i := range queries {
+		queries[i] = make([]float32, 16)
+		for j := range queries[i] {
+			queries[i][j] = rng.Float32()
+		}
+	}
+
+	results, err := h.AutoTune(0.9, queries, 1, 10)
+	if err != nil {
+		t.Fatalf("AutoTune failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	for _, res := range results {
+		if res.Ef < res.K {
+			t.Errorf("K=%d: ef %d below K", res.K, res.Ef)
+		}
+		if res.Recall < 0.9 {
+			t.Errorf("K=%d: recall %v below target", res.K, res.Recall)
+		}
+
+		// ef is the smallest one meeting the target
+		if res.Ef > res.K {
+			samples, _, err := h.tuneSamples(queries, res.K)
+			if err != nil {
+				t.Fatalf("tuneSamples failed: %v", err)
+			}
+			if r := h.sampleRecall(samples, res.K, res.Ef-1); r >= 0.9 {
+				t.Errorf("K=%d: ef %d also meets the target with recall %v", res.K, res.Ef-1, r)
+			}
+		}
+	}
+
+	// A higher target never needs a smaller ef
+	higher, err := h.AutoTune(0.99, queries, 10)
+	if err != nil {
+		t.Fatalf("AutoTune failed: %v", err)
+	}
+	if higher[0].Ef < results[1].Ef {
+		t.Errorf("Expected ef for 0.99 (%d) ≥ ef for 0.9 (%d)", higher[0].Ef, results[1].Ef)
+	}
+}
+
+func TestAutoTuneHeldOut(t *testing.T) {
+	h := buildRandomIndex(t, 1000, 8)
+
+	results, err := h.AutoTune(0.95, nil)
+	if err != nil {
+		t.Fatalf("AutoTune failed: %v", err)
+	}
+	if len(results) != len(DefaultTuneKs) {
+		t.Fatalf("Expected %d results, got %d", len(DefaultTuneKs), len(results))
+	}
+	for _, res := range results {
+		if res.Recall < 0.95 {
+			t.Errorf("K=%d: recall %v below target with ef %d", res.K, res.Recall, res.Ef)
+		}
+	}
+
+	// Held-out queries never count themselves as neighbors
+	samples, _, err := h.tuneSamples(nil, 10)
+	if err != nil {
+		t.Fatalf("tuneSamples failed: %v", err)
+	}
+	if len(samples) != DefaultTuneSamples {
+		t.Errorf("Expected %d samples, got %d", DefaultTuneSamples, len(samples))
+	}
+	for _, s := range samples {
+		for _, id := range s.truth {
+			if id == s.exclude {
+				t.Fatalf("Held-out node %d in its own ground truth", id)
+			}
+		}
+	}
+}
+
+func TestAutoTuneSmallIndex(t *testing.T) {
+	h := buildRandomIndex(t, 30, 4)
+
+	// With K above the index size, ef = K already visits every node
+	results, err := h.AutoTune(1, nil, 50)
+	if err != nil {
+		t.Fatalf("AutoTune failed: %v", err)
+	}
+	if results[0].Ef != 50 || results[0].Recall != 1 {
+		t.Errorf("Expected ef 50 with recall 1, got ef %d with recall %v", results[0].Ef, results[0].Recall)
+	}
+}
+
+func TestAutoTuneInvalid(t *testing.T) {
+	h := buildRandomIndex(t, 50, 4)
+	empty, err := NewHNSW(DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		index   *HNSW
+		target  float64
+		queries [][]float32
+		Ks      []int
+	}{
+		{"zero target", h, 0, nil, nil},
+		{"target above one", h, 1.5, nil, nil},
+		{"zero K", h, 0.9, nil, []int{0}},
+		{"dimension mismatch", h, 0.9, [][]float32{{1, 2}}, nil},
+		{"empty index", empty, 0.9, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.index.AutoTune(tt.target, tt.queries, tt.Ks...); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestSearchTuned(t *testing.T) {
+	h := buildRandomIndex(t, 500, 8)
+	query := []float32{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+
+	if _, err := h.Search(query, 10, 0.9); !errors.Is(err, ErrNotTuned) {
+		t.Fatalf("Expected ErrNotTuned before AutoTune, got %v", err)
+	}
+
+	// Fixed tuning tables make the lookup rules easy to check
+	h.tuning = map[float64]map[int]int{
+		0.9:  {1: 11, 10: 20, 100: 150},
+		0.95: {10: 40},
+	}
+	tests := []struct {
+		name   string
+		K      int
+		target float64
+		ef     int
+		ok     bool
+	}{
+		{"exact match", 10, 0.9, 20, true},
+		{"K rounded up", 5, 0.9, 20, true},
+		{"target rounded up", 10, 0.92, 40, true},
+		{"lowest sufficient target", 1, 0.5, 11, true},
+		{"K rounded up within the target", 50, 0.95, 0, false},
+		{"K too large", 200, 0.9, 0, false},
+		{"target too high", 10, 0.99, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ef, ok := h.tunedEf(tt.K, tt.target)
+			if ok != tt.ok || ef != tt.ef {
+				t.Errorf("Expected ef %d (%v), got %d (%v)", tt.ef, tt.ok, ef, ok)
+			}
+		})
+	}
+
+	results, err := h.Search(query, 10, 0.9)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 10 {
+		t.Errorf("Expected 10 results, got %d", len(results))
+	}
+}
@@ -0,0 +1,64 @@
+package hnsw
+
+import (
+	"errors"
+	"math"
+)
+
+// Update replaces the vector of an existing node and re-links it.
+//
+// The node keeps its ID and level. Its neighbor lists are rebuilt as if it
+// were inserted again: the new position is located by descending from the
+// entry point, and at each layer the node is connected to the closest
+// elements found by SEARCH-LAYER, with the usual bidirectional connections
+// and pruning. Edges pointing to the node from elsewhere are left in place
+// and are pruned naturally as the graph evolves.
+func (h *HNSW) Update(id int, vector []float32) error {
+	h.lock("update")
+	defer h.mutex.Unlock()
+
+	node, err := h.liveNode(id)
+	if err != nil {
+		return h.observeError("update", err)
+	}
+	if len(vector) != len(node.Vector) {
+		return h.observeError("update", errors.New("vector dimension mismatch"))
+	}
+
+	// The vector lives in the arena, so it is overwritten in place
+	copy(node.Vector, vector)
+	h.markDirty(id)
+	if len(h.Nodes) == 1 {
+		return nil
+	}
+
+	// The node itself is traversed but never selected as its own neighbor
+	notSelf := func(n int) bool { return n != id }
+
+	// Phase 1: descend from the entry point down to the node's level
+	ep := h.EntryPoint
+	L := ep.Level
+	for lc := L; lc > node.Level; lc-- {
+		ep = h.greedySearchLayer(node.Vector, ep, lc, nil)
+	}
+
+	// Phase 2: rebuild the connections from min(L, level) down to layer 0
+	maxLayer := int(math.Min(float64(L), float64(node.Level)))
+	for lc := maxLayer; lc >= 0; lc-- {
+		nearestNeighbors := h.searchLayer(node.Vector, ep, h.EfConstruction, lc, notSelf, nil)
+
+		maxConn := h.Mmax
+		if lc == 0 {
+			maxConn = h.Mmax0
+		}
+
+		neighbors := nearestNeighbors[:min(len(nearestNeighbors), maxConn)]
+		h.updateBidirectionalConnections(node, neighbors, lc, maxConn)
+
+		if len(nearestNeighbors) > 0 {
+			ep = h.Nodes[nearestNeighbors[0]]
+		}
+	}
+
+	return nil
+}
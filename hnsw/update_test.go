@@ -0,0 +1,75 @@
+package hnsw
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestUpdate verifies that an updated node is found at its new position
+func TestUpdate(t *testing.T) {
+	h := buildRandomIndex(t, 300, 4)
+
+	// Move node 7 far away from the unit cube where all the other nodes are
+	target := []float32{10, 10, 10, 10}
+	if err := h.Update(7, target); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if !slices.Equal(h.Nodes[7].Vector, target) {
+		t.Errorf("Expected vector %v, got %v", target, h.Nodes[7].Vector)
+	}
+	if results := h.KNN_Search(target, 1, 20); len(results) != 1 || results[0] != 7 {
+		t.Errorf("Expected node 7 to be nearest to its new vector, got %v", results)
+	}
+
+	// Move it back inside the cube next to a known query
+	query := []float32{0.5, 0.5, 0.5, 0.5}
+	if err := h.Update(7, []float32{0.5, 0.5, 0.5, 0.501}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if results := h.KNN_Search(query, 1, 20); len(results) != 1 || results[0] != 7 {
+		t.Errorf("Expected node 7 to be nearest to %v, got %v", query, results)
+	}
+
+	// The graph must stay free of self-loops, duplicates and oversized lists
+	for _, node := range h.Nodes {
+		for lc, neighbors := range node.Neighbors {
+			maxConn := h.Mmax
+			if lc == 0 {
+				maxConn = h.Mmax0
+			}
+			if len(neighbors) > maxConn {
+				t.Errorf("Node %d level %d has %d neighbors, exceeding %d", node.ID, lc, len(neighbors), maxConn)
+			}
+			seen := make(map[uint32]bool)
+			for _, n := range neighbors {
+				if int(n) == node.ID {
+					t.Errorf("Node %d links to itself at level %d", node.ID, lc)
+				}
+				if seen[n] {
+					t.Errorf("Node %d links twice to %d at level %d", node.ID, n, lc)
+				}
+				seen[n] = true
+			}
+		}
+	}
+}
+
+// TestUpdateErrors verifies that invalid updates are rejected
+func TestUpdateErrors(t *testing.T) {
+	h := buildRandomIndex(t, 10, 2)
+	if err := h.Delete(4); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	if err := h.Update(4, []float32{1, 1}); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Update of deleted node: expected ErrNodeNotFound, got %v", err)
+	}
+	if err := h.Update(10, []float32{1, 1}); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("Update of unknown node: expected ErrNodeNotFound, got %v", err)
+	}
+	if err := h.Update(0, []float32{1, 1, 1}); err == nil {
+		t.Errorf("Update with wrong dimension should fail")
+	}
+}
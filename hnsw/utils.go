@@ -1,5 +1,7 @@
 package hnsw
 
+import "math"
+
 func EuclideanDistance(a, b []float32) float32 {
 	var sum0, sum1, sum2, sum3 float32
 	i := 0
@@ -26,3 +28,39 @@ func EuclideanDistance(a, b []float32) float32 {
 
 	return sum + sum0 + sum1 + sum2 + sum3
 }
+
+// InnerProductDistance returns 1 minus the dot product of a and b, the
+// distance hnswlib uses for maximum inner product search.
+func InnerProductDistance(a, b []float32) float32 {
+	var sum0, sum1, sum2, sum3 float32
+	i := 0
+
+	for ; i <= len(a)-4; i += 4 {
+		sum0 += a[i] * b[i]
+		sum1 += a[i+1] * b[i+1]
+		sum2 += a[i+2] * b[i+2]
+		sum3 += a[i+3] * b[i+3]
+	}
+
+	var sum float32
+	for ; i < len(a); i++ {
+		sum += a[i] * b[i]
+	}
+
+	return 1 - (sum + sum0 + sum1 + sum2 + sum3)
+}
+
+// CosineDistance returns 1 minus the cosine similarity of a and b. The
+// distance to a zero vector is 1.
+func CosineDistance(a, b []float32) float32 {
+	var dot, normA, normB float32
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	return 1 - dot/float32(math.Sqrt(float64(normA)*float64(normB)))
+}
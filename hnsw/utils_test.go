@@ -61,3 +61,48 @@ func BenchmarkEuclideanDistance(b *testing.B) {
 		EuclideanDistance(vec1, vec2)
 	}
 }
+
+func TestInnerProductDistance(t *testing.T) {
+	tests := []struct {
+		name     string
+		vec1     []float32
+		vec2     []float32
+		expected float32
+	}{
+		{"Orthogonal", []float32{1, 0}, []float32{0, 1}, 1},
+		{"Same unit vectors", []float32{0.6, 0.8}, []float32{0.6, 0.8}, 0},
+		{"Longer than four", []float32{1, 2, 3, 4, 5}, []float32{1, 1, 1, 1, 1}, -14},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := InnerProductDistance(tt.vec1, tt.vec2)
+			if math.Abs(float64(result-tt.expected)) > 1e-6 {
+				t.Errorf("InnerProductDistance() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCosineDistance(t *testing.T) {
+	tests := []struct {
+		name     string
+		vec1     []float32
+		vec2     []float32
+		expected float32
+	}{
+		{"Same direction", []float32{1, 2, 3}, []float32{2, 4, 6}, 0},
+		{"Orthogonal", []float32{1, 0}, []float32{0, 3}, 1},
+		{"Opposite", []float32{1, 1}, []float32{-1, -1}, 2},
+		{"Zero vector", []float32{0, 0}, []float32{1, 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CosineDistance(tt.vec1, tt.vec2)
+			if math.Abs(float64(result-tt.expected)) > 1e-6 {
+				t.Errorf("CosineDistance() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
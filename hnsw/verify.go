@@ -0,0 +1,287 @@
+package hnsw
+
+import (
+	"fmt"
+	"slices"
+
+	"dmarro89.github.com/hnsw-go/structs"
+)
+
+// IssueKind identifies a structural problem of the graph found by Verify.
+type IssueKind int
+
+const (
+	// DanglingEdge is an edge to an ID that is not in the index
+	DanglingEdge IssueKind = iota + 1
+	// SelfLoop is an edge from a node to itself
+	SelfLoop
+	// DuplicateEdge is an edge listed more than once in a neighbor list
+	DuplicateEdge
+	// DegreeExceeded is a neighbor list longer than Mmax, or Mmax0 on layer 0
+	DegreeExceeded
+	// LevelMismatch is an edge on a layer above the Level of its target,
+	// or a node whose neighbor lists do not match its Level
+	LevelMismatch
+	// Unreachable is a live node that cannot be reached from the entry
+	// point on layer 0, so searches can never return it
+	Unreachable
+	// BadEntryPoint is an entry point that is missing or not on the top level
+	BadEntryPoint
+)
+
+func (k IssueKind) String() string {
+	switch k {
+	case DanglingEdge:
+		return "dangling edge"
+	case SelfLoop:
+		return "self-loop"
+	case DuplicateEdge:
+		return "duplicate edge"
+	case DegreeExceeded:
+		return "degree exceeded"
+	case LevelMismatch:
+		return "level mismatch"
+	case Unreachable:
+		return "unreachable"
+	case BadEntryPoint:
+		return "bad entry point"
+	}
+	return fmt.Sprintf("IssueKind(%d)", int(k))
+}
+
+// Issue is a structural problem of the graph.
+type Issue struct {
+	Kind IssueKind
+
+	// Node and Level locate the problem; Level is -1 when it concerns the
+	// node as a whole
+	Node  int
+	Level int
+
+	// Neighbor is the target of the faulty edge, or -1
+	Neighbor int
+}
+
+func (i Issue) String() string {
+	switch {
+	case i.Neighbor >= 0:
+		return fmt.Sprintf("node %d level %d: %v to %d", i.Node, i.Level, i.Kind, i.Neighbor)
+	case i.Level >= 0:
+		return fmt.Sprintf("node %d level %d: %v", i.Node, i.Level, i.Kind)
+	}
+	return fmt.Sprintf("node %d: %v", i.Node, i.Kind)
+}
+
+// VerifyReport is the outcome of Verify.
+type VerifyReport struct {
+	// Nodes is the number of nodes checked, deleted ones included
+	Nodes  int
+	Issues []Issue
+}
+
+// OK reports whether no issue was found.
+func (r *VerifyReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Count returns the number of issues of the given kind.
+func (r *VerifyReport) Count(kind IssueKind) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Verify checks the structural invariants of the graph: every edge points
+// to an existing node other than its source, at most once per list, on a
+// layer the target belongs to; neighbor lists match the node levels and the
+// degree limits; the entry point is on the top level; and every live node
+// can be reached from the entry point on layer 0.
+//
+// Deleted nodes are checked like live ones, since searches still traverse
+// them, but are not required to be reachable.
+func (h *HNSW) Verify() *VerifyReport {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	report := &VerifyReport{Nodes: len(h.Nodes)}
+	add := func(kind IssueKind, node, level, neighbor int) {
+		report.Issues = append(report.Issues, Issue{Kind: kind, Node: node, Level: level, Neighbor: neighbor})
+	}
+
+	top := -1
+	for id, node := range h.Nodes {
+		top = max(top, node.Level)
+		if len(node.Neighbors) != node.Level+1 {
+			add(LevelMismatch, id, -1, -1)
+		}
+
+		for level, neighbors := range node.Neighbors {
+			if len(neighbors) > h.maxConn(level) {
+				add(DegreeExceeded, id, level, -1)
+			}
+			for i, n := range neighbors {
+				switch {
+				case int(n) >= len(h.Nodes):
+					add(DanglingEdge, id, level, int(n))
+				case int(n) == id:
+					add(SelfLoop, id, level, int(n))
+				case slices.Contains(neighbors[:i], n):
+					add(DuplicateEdge, id, level, int(n))
+				case h.Nodes[n].Level < level:
+					add(LevelMismatch, id, level, int(n))
+				}
+			}
+		}
+	}
+
+	if len(h.Nodes) == 0 {
+		return report
+	}
+	if h.EntryPoint == nil {
+		add(BadEntryPoint, -1, -1, -1)
+		return report
+	}
+	if h.EntryPoint.Level != top {
+		add(BadEntryPoint, h.EntryPoint.ID, h.EntryPoint.Level, -1)
+	}
+
+	reached := h.reachable()
+	for id, node := range h.Nodes {
+		if !reached[id] && !node.Deleted {
+			add(Unreachable, id, 0, -1)
+		}
+	}
+	return report
+}
+
+// maxConn returns the maximum degree of a layer
+func (h *HNSW) maxConn(level int) int {
+	if level == 0 {
+		return h.Mmax0
+	}
+	return h.Mmax
+}
+
+// reachable returns the nodes reachable from the entry point on layer 0,
+// following valid edges only. The caller must hold the lock.
+func (h *HNSW) reachable() []bool {
+	reached := make([]bool, len(h.Nodes))
+	if h.EntryPoint == nil {
+		return reached
+	}
+
+	queue := []int{h.EntryPoint.ID}
+	reached[h.EntryPoint.ID] = true
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if len(h.Nodes[id].Neighbors) == 0 {
+			continue
+		}
+		for _, n := range h.Nodes[id].Neighbors[0] {
+			if int(n) < len(h.Nodes) && !reached[n] {
+				reached[n] = true
+				queue = append(queue, int(n))
+			}
+		}
+	}
+	return reached
+}
+
+// Repair fixes the issues Verify can report and returns the issues it fixed:
+//   - dangling edges, self-loops, duplicate edges and edges to nodes not on
+//     the layer are removed
+//   - missing neighbor lists are added empty and extra ones are dropped
+//   - lists above the degree limit keep their closest neighbors
+//   - a bad entry point is replaced by a node of the top level
+//   - unreachable nodes are re-linked as by Reconnect
+//
+// Run Verify afterwards to find the issues that could not be fixed.
+func (h *HNSW) Repair() []Issue {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	var fixed []Issue
+	add := func(kind IssueKind, node, level, neighbor int) {
+		fixed = append(fixed, Issue{Kind: kind, Node: node, Level: level, Neighbor: neighbor})
+		h.markDirty(node)
+	}
+
+	for id, node := range h.Nodes {
+		if len(node.Neighbors) != node.Level+1 {
+			for len(node.Neighbors) < node.Level+1 {
+				node.Neighbors = append(node.Neighbors, make([]uint32, 0, h.maxConn(len(node.Neighbors))))
+			}
+			node.Neighbors = node.Neighbors[:node.Level+1]
+			add(LevelMismatch, id, -1, -1)
+		}
+
+		for level, neighbors := range node.Neighbors {
+			// Filter the list in place, keeping the first copy of every edge
+			kept := neighbors[:0]
+			for i, n := range neighbors {
+				switch {
+				case int(n) >= len(h.Nodes):
+					add(DanglingEdge, id, level, int(n))
+				case int(n) == id:
+					add(SelfLoop, id, level, int(n))
+				case slices.Contains(neighbors[:i], n):
+					add(DuplicateEdge, id, level, int(n))
+				case h.Nodes[n].Level < level:
+					add(LevelMismatch, id, level, int(n))
+				default:
+					kept = append(kept, n)
+				}
+			}
+			node.Neighbors[level] = kept
+
+			if maxConn := h.maxConn(level); len(kept) > maxConn {
+				node.Neighbors[level] = h.closest(node, kept, maxConn)
+				add(DegreeExceeded, id, level, -1)
+			}
+		}
+	}
+
+	if len(h.Nodes) > 0 {
+		top := h.Nodes[0]
+		for _, node := range h.Nodes {
+			if node.Level > top.Level || (node.Level == top.Level && top.Deleted && !node.Deleted) {
+				top = node
+			}
+		}
+		if h.EntryPoint == nil || h.EntryPoint.Level != top.Level {
+			id := -1
+			if h.EntryPoint != nil {
+				id = h.EntryPoint.ID
+			}
+			fixed = append(fixed, Issue{Kind: BadEntryPoint, Node: id, Level: -1, Neighbor: -1})
+			h.EntryPoint = top
+		}
+	}
+
+	// Removing edges can leave nodes unreachable, so this comes last
+	for _, id := range h.reconnect() {
+		fixed = append(fixed, Issue{Kind: Unreachable, Node: id, Level: 0, Neighbor: -1})
+	}
+	return fixed
+}
+
+// closest returns the n neighbors closest to node, in place of neighbors
+func (h *HNSW) closest(node *structs.Node, neighbors []uint32, n int) []uint32 {
+	slices.SortFunc(neighbors, func(a, b uint32) int {
+		da := h.DistanceFunc(node.Vector, h.Nodes[a].Vector)
+		db := h.DistanceFunc(node.Vector, h.Nodes[b].Vector)
+		switch {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+		return 0
+	})
+	return neighbors[:n]
+}
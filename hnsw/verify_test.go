@@ -0,0 +1,104 @@
+package hnsw
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	h := buildRandomIndex(t, 500, 8)
+	if report := h.Verify(); !report.OK() || report.Nodes != 500 {
+		t.Fatalf("Expected a clean report for 500 nodes, got %d nodes and %v", report.Nodes, report.Issues)
+	}
+
+	// A node on level 0 only, and a node with a neighbor on every layer
+	low, high := -1, -1
+	for _, node := range h.Nodes {
+		if node.Level == 0 && low < 0 && node != h.EntryPoint {
+			low = node.ID
+		}
+		if node.Level > 0 && high < 0 && node != h.EntryPoint && len(node.Neighbors[1]) > 0 {
+			high = node.ID
+		}
+	}
+	if low < 0 || high < 0 {
+		t.Fatalf("Expected nodes on level 0 and above")
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(h *HNSW)
+		kind    IssueKind
+	}{
+		{"dangling edge", func(h *HNSW) { h.Nodes[high].Neighbors[0][0] = 10000 }, DanglingEdge},
+		{"self-loop", func(h *HNSW) { h.Nodes[high].Neighbors[0][0] = uint32(high) }, SelfLoop},
+		{"duplicate edge", func(h *HNSW) {
+			n := h.Nodes[high].Neighbors[0]
+			n[1] = n[0]
+		}, DuplicateEdge},
+		{"degree exceeded", func(h *HNSW) {
+			neighbors := h.Nodes[high].Neighbors[0][:0]
+			for id := 0; len(neighbors) <= h.Mmax0; id++ {
+				if id != high {
+					neighbors = append(neighbors, uint32(id))
+				}
+			}
+			h.Nodes[high].Neighbors[0] = neighbors
+		}, DegreeExceeded},
+		{"edge above the target level", func(h *HNSW) { h.Nodes[high].Neighbors[1][0] = uint32(low) }, LevelMismatch},
+		{"missing neighbor list", func(h *HNSW) { h.Nodes[high].Neighbors = h.Nodes[high].Neighbors[:1] }, LevelMismatch},
+		{"unreachable", func(h *HNSW) {
+			for _, node := range h.Nodes {
+				node.Neighbors[0] = slices.DeleteFunc(node.Neighbors[0], func(id uint32) bool { return int(id) == low })
+			}
+		}, Unreachable},
+		{"bad entry point", func(h *HNSW) { h.EntryPoint = h.Nodes[low] }, BadEntryPoint},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := buildRandomIndex(t, 500, 8)
+			tt.corrupt(h)
+
+			report := h.Verify()
+			if report.Count(tt.kind) == 0 {
+				t.Fatalf("Expected a %v issue, got %v", tt.kind, report.Issues)
+			}
+
+			fixed := h.Repair()
+			if !slices.ContainsFunc(fixed, func(i Issue) bool { return i.Kind == tt.kind }) {
+				t.Errorf("Expected a %v fix, got %v", tt.kind, fixed)
+			}
+			if report := h.Verify(); report.Count(tt.kind) != 0 {
+				t.Errorf("Expected no %v issue after Repair, got %v", tt.kind, report.Issues)
+			}
+
+			// The repaired index can still be searched
+			if results := h.KNN_Search(h.Nodes[high].Vector, 10, 64); len(results) != 10 {
+				t.Errorf("Expected 10 results, got %d", len(results))
+			}
+		})
+	}
+}
+
+func TestRepairDegree(t *testing.T) {
+	h := buildRandomIndex(t, 200, 4)
+
+	// Every other node on layer 0 of node 0: Repair keeps the closest ones
+	node := h.Nodes[0]
+	node.Neighbors[0] = node.Neighbors[0][:0]
+	for id := 1; id < len(h.Nodes); id++ {
+		node.Neighbors[0] = append(node.Neighbors[0], uint32(id))
+	}
+	h.Repair()
+
+	if len(node.Neighbors[0]) != h.Mmax0 {
+		t.Fatalf("Expected %d neighbors, got %d", h.Mmax0, len(node.Neighbors[0]))
+	}
+	exact := h.exactNeighbors(node.Vector, h.Mmax0, node.ID)
+	for _, id := range exact {
+		if !slices.Contains(node.Neighbors[0], uint32(id)) {
+			t.Errorf("Expected neighbor %d among the closest ones", id)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package metrics exports measurements of hnsw indexes in the Prometheus
+// text exposition format, without depending on the Prometheus client
+// libraries.
+//
+// An Exporter implements hnsw.Metrics for every index registered with it,
+// and serves:
+//
+//	hnsw_insert_duration_seconds        histogram of insertion durations
+//	hnsw_search_duration_seconds        histogram of search durations
+//	hnsw_search_visited_nodes           histogram of nodes visited per search
+//	hnsw_search_distance_computations   histogram of distances computed per search
+//	hnsw_lock_wait_seconds{op}          histogram of the wait for the index lock
+//	hnsw_errors_total{op}               operations that returned an error
+//	hnsw_nodes                          live nodes
+//	hnsw_deleted_nodes                  deleted nodes
+//	hnsw_level_nodes{level}             nodes on each layer
+//	hnsw_memory_bytes{kind}             estimated memory of vectors, neighbors and overhead
+//
+// Every sample has an index label with the name given to Register. The
+// gauges are computed from hnsw.Stats when the metrics are scraped, which
+// takes time linear in the size of the index.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter collects the measurements of a set of indexes. It is an
+// http.Handler serving them in the text format.
+type Exporter struct {
+	mutex   sync.Mutex
+	indexes map[string]*indexMetrics
+}
+
+// NewExporter creates an Exporter with no index.
+func NewExporter() *Exporter {
+	return &Exporter{indexes: make(map[string]*indexMetrics)}
+}
+
+// Register starts measuring h under the given name, by setting h.Metrics.
+// It must be called before h is used concurrently.
+func (e *Exporter) Register(name string, h *hnsw.HNSW) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, ok := e.indexes[name]; ok {
+		return fmt.Errorf("metrics: index %q already registered", name)
+	}
+	m := &indexMetrics{
+		name:            name,
+		index:           h,
+		insertDuration:  newHistogram(DurationBuckets),
+		searchDuration:  newHistogram(DurationBuckets),
+		searchVisited:   newHistogram(CountBuckets),
+		searchDistances: newHistogram(CountBuckets),
+		lockWait:        make(map[string]*histogram),
+		errors:          make(map[string]uint64),
+	}
+	e.indexes[name] = m
+	h.Metrics = m
+	return nil
+}
+
+// Unregister stops measuring the index registered under name. Like
+// Register, it must not be called while the index is in use.
+func (e *Exporter) Unregister(name string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if m, ok := e.indexes[name]; ok {
+		delete(e.indexes, name)
+		if m.index.Metrics == m {
+			m.index.Metrics = nil
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in the text format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	e.Write(w)
+}
+
+// Write writes the metrics of every registered index in the text format.
+func (e *Exporter) Write(w io.Writer) error {
+	e.mutex.Lock()
+	indexes := make([]*indexMetrics, 0, len(e.indexes))
+	for _, m := range e.indexes {
+		indexes = append(indexes, m)
+	}
+	e.mutex.Unlock()
+	slices.SortFunc(indexes, func(a, b *indexMetrics) int { return strings.Compare(a.name, b.name) })
+
+	stats := make([]hnsw.Stats, len(indexes))
+	for i, m := range indexes {
+		stats[i] = m.index.Stats()
+	}
+
+	bw := bufio.NewWriter(w)
+	histograms := []struct {
+		name, help string
+		get        func(m *indexMetrics) *histogram
+	}{
+		{"hnsw_insert_duration_seconds", "Duration of insertions, lock wait excluded.", func(m *indexMetrics) *histogram { return m.insertDuration }},
+		{"hnsw_search_duration_seconds", "Duration of searches, lock wait excluded.", func(m *indexMetrics) *histogram { return m.searchDuration }},
+		{"hnsw_search_visited_nodes", "Nodes visited per search.", func(m *indexMetrics) *histogram { return m.searchVisited }},
+		{"hnsw_search_distance_computations", "Distances computed per search.", func(m *indexMetrics) *histogram { return m.searchDistances }},
+	}
+	for _, family := range histograms {
+		header(bw, family.name, "histogram", family.help)
+		for _, m := range indexes {
+			family.get(m).write(bw, family.name, labels("index", m.name))
+		}
+	}
+
+	header(bw, "hnsw_lock_wait_seconds", "histogram", "Time waited for the index lock.")
+	for _, m := range indexes {
+		m.mutex.Lock()
+		lockWait := maps.Clone(m.lockWait)
+		m.mutex.Unlock()
+		for _, op := range slices.Sorted(maps.Keys(lockWait)) {
+			lockWait[op].write(bw, "hnsw_lock_wait_seconds", labels("index", m.name, "op", op))
+		}
+	}
+
+	header(bw, "hnsw_errors_total", "counter", "Operations that returned an error.")
+	for _, m := range indexes {
+		m.mutex.Lock()
+		errors := maps.Clone(m.errors)
+		m.mutex.Unlock()
+		for _, op := range slices.Sorted(maps.Keys(errors)) {
+			fmt.Fprintf(bw, "hnsw_errors_total{%s} %d\n", labels("index", m.name, "op", op), errors[op])
+		}
+	}
+
+	header(bw, "hnsw_nodes", "gauge", "Live nodes in the index.")
+	for i, m := range indexes {
+		fmt.Fprintf(bw, "hnsw_nodes{%s} %d\n", labels("index", m.name), stats[i].Nodes-stats[i].Deleted)
+	}
+	header(bw, "hnsw_deleted_nodes", "gauge", "Deleted nodes still in the graph.")
+	for i, m := range indexes {
+		fmt.Fprintf(bw, "hnsw_deleted_nodes{%s} %d\n", labels("index", m.name), stats[i].Deleted)
+	}
+	header(bw, "hnsw_level_nodes", "gauge", "Nodes on each layer of the graph, deleted ones included.")
+	for i, m := range indexes {
+		for _, level := range stats[i].Levels {
+			fmt.Fprintf(bw, "hnsw_level_nodes{%s} %d\n", labels("index", m.name, "level", fmt.Sprint(level.Level)), level.Nodes)
+		}
+	}
+	header(bw, "hnsw_memory_bytes", "gauge", "Estimated memory used by the index.")
+	for i, m := range indexes {
+		memory := stats[i].Memory
+		fmt.Fprintf(bw, "hnsw_memory_bytes{%s} %d\n", labels("index", m.name, "kind", "vectors"), memory.Vectors)
+		fmt.Fprintf(bw, "hnsw_memory_bytes{%s} %d\n", labels("index", m.name, "kind", "neighbors"), memory.Neighbors)
+		fmt.Fprintf(bw, "hnsw_memory_bytes{%s} %d\n", labels("index", m.name, "kind", "overhead"), memory.Overhead)
+	}
+	return bw.Flush()
+}
+
+// indexMetrics implements hnsw.Metrics for one index
+type indexMetrics struct {
+	name  string
+	index *hnsw.HNSW
+
+	insertDuration  *histogram
+	searchDuration  *histogram
+	searchVisited   *histogram
+	searchDistances *histogram
+
+	// mutex guards the maps, which gain an entry per operation
+	mutex    sync.Mutex
+	lockWait map[string]*histogram
+	errors   map[string]uint64
+}
+
+func (m *indexMetrics) ObserveInsert(elapsed time.Duration, level int) {
+	m.insertDuration.observe(elapsed.Seconds())
+}
+
+func (m *indexMetrics) ObserveSearch(elapsed time.Duration, visited, distances int) {
+	m.searchDuration.observe(elapsed.Seconds())
+	m.searchVisited.observe(float64(visited))
+	m.searchDistances.observe(float64(distances))
+}
+
+func (m *indexMetrics) ObserveLockWait(op string, wait time.Duration) {
+	m.mutex.Lock()
+	hist := m.lockWait[op]
+	if hist == nil {
+		hist = newHistogram(DurationBuckets)
+		m.lockWait[op] = hist
+	}
+	m.mutex.Unlock()
+	hist.observe(wait.Seconds())
+}
+
+func (m *indexMetrics) ObserveError(op string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.errors[op]++
+}
+
+// header writes the HELP and TYPE lines of a metric family
+func header(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// labels formats name and value pairs as a label set, without the braces
+func labels(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+// labelEscaper escapes label values as required by the text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"math/rand/v2"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// newIndex returns an index with n random vectors of dimension 4
+func newIndex(t *testing.T, n int) *hnsw.HNSW {
+	t.Helper()
+	h, err := hnsw.NewHNSW(hnsw.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < n; i++ {
+		h.Insert([]float32{rng.Float32(), rng.Float32(), rng.Float32(), rng.Float32()}, i)
+	}
+	return h
+}
+
+func TestExporter(t *testing.T) {
+	e := NewExporter()
+	a, b := newIndex(t, 0), newIndex(t, 20)
+	if err := e.Register("a", a); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := e.Register(`b"1`, b); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := e.Register("a", b); err == nil {
+		t.Errorf("Expected an error for a duplicate name")
+	}
+
+	for i := 0; i < 30; i++ {
+		a.Insert([]float32{float32(i), 0, 0, 0}, i)
+	}
+	a.KNN_Search([]float32{1, 0, 0, 0}, 5, 10)
+	a.KNN_Search([]float32{2, 0, 0, 0}, 5, 10)
+	if err := a.Delete(3); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	a.Delete(3)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Expected content type %q, got %q", ContentType, ct)
+	}
+	out := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE hnsw_insert_duration_seconds histogram\n",
+		`hnsw_insert_duration_seconds_bucket{index="a",le="+Inf"} 30` + "\n",
+		`hnsw_insert_duration_seconds_count{index="a"} 30` + "\n",
+		`hnsw_search_duration_seconds_count{index="a"} 2` + "\n",
+		`hnsw_search_visited_nodes_count{index="a"} 2` + "\n",
+		`hnsw_lock_wait_seconds_count{index="a",op="delete"} 2` + "\n",
+		`hnsw_lock_wait_seconds_count{index="a",op="insert"} 30` + "\n",
+		`hnsw_errors_total{index="a",op="delete"} 1` + "\n",
+		`hnsw_nodes{index="a"} 29` + "\n",
+		`hnsw_deleted_nodes{index="a"} 1` + "\n",
+		`hnsw_level_nodes{index="a",level="0"} 30` + "\n",
+		`hnsw_memory_bytes{index="a",kind="vectors"} 480` + "\n",
+		`hnsw_nodes{index="b\"1"} 20` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the output to contain %q", want)
+		}
+	}
+
+	// The samples of a family follow its TYPE line
+	if strings.Index(out, "hnsw_nodes{index=\"b") < strings.Index(out, "hnsw_nodes{index=\"a\"}") {
+		t.Errorf("Expected the indexes sorted by name")
+	}
+
+	e.Unregister("a")
+	if a.Metrics != nil {
+		t.Errorf("Expected Unregister to clear the metrics of the index")
+	}
+	var b2 strings.Builder
+	if err := e.Write(&b2); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if strings.Contains(b2.String(), `index="a"`) {
+		t.Errorf("Expected no sample for an unregistered index")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]float64{1, 10})
+	for _, v := range []float64{0.5, 1, 5, 20} {
+		h.observe(v)
+	}
+
+	var b strings.Builder
+	h.write(&b, "x", "")
+	want := `x_bucket{le="1"} 2
+x_bucket{le="10"} 3
+x_bucket{le="+Inf"} 4
+x_sum 26.5
+x_count 4
+`
+	if b.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, b.String())
+	}
+}
+
+func TestLabels(t *testing.T) {
+	if got := labels("a", `x\y`, "b", "line\nbreak"); got != `a="x\\y",b="line\nbreak"` {
+		t.Errorf("Unexpected labels %s", got)
+	}
+}
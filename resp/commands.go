@@ -0,0 +1,310 @@
+package resp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// client is the state of a connection
+type client struct {
+	server *Server
+	r      *reader
+	w      *writer
+}
+
+// commandFunc executes a command, whose name is args[0], and writes its reply
+type commandFunc func(c *client, args []string)
+
+// commandSpec describes a command. arity is the number of arguments,
+// including the name; a negative arity is a minimum.
+type commandSpec struct {
+	fn    commandFunc
+	arity int
+}
+
+var commandTable = map[string]commandSpec{
+	"ping":    {cmdPing, -1},
+	"echo":    {cmdEcho, 2},
+	"hello":   {cmdHello, -1},
+	"command": {cmdCommand, -1},
+	"quit":    {nil, -1},
+	"del":     {cmdDel, -2},
+	"exists":  {cmdExists, -2},
+	"vadd":    {cmdVadd, -4},
+	"vsim":    {cmdVsim, -5},
+	"vrem":    {cmdVrem, 3},
+	"vcard":   {cmdVcard, 2},
+	"vinfo":   {cmdVinfo, 2},
+}
+
+// execute runs a command and reports whether the connection must be closed
+func (c *client) execute(args []string) bool {
+	name := strings.ToLower(args[0])
+	spec, ok := commandTable[name]
+	if !ok {
+		c.w.error(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+		return false
+	}
+	if (spec.arity > 0 && len(args) != spec.arity) || (spec.arity < 0 && len(args) < -spec.arity) {
+		c.w.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+		return false
+	}
+	if name == "quit" {
+		c.w.simple("OK")
+		return true
+	}
+	spec.fn(c, args)
+	return false
+}
+
+func cmdPing(c *client, args []string) {
+	switch len(args) {
+	case 1:
+		c.w.simple("PONG")
+	case 2:
+		c.w.bulk(args[1])
+	default:
+		c.w.error("ERR wrong number of arguments for 'ping' command")
+	}
+}
+
+func cmdEcho(c *client, args []string) {
+	c.w.bulk(args[1])
+}
+
+// cmdHello switches the protocol version and replies with the server info.
+// Authentication is not supported.
+func cmdHello(c *client, args []string) {
+	if len(args) > 1 {
+		proto, err := strconv.Atoi(args[1])
+		if err != nil {
+			c.w.error("ERR Protocol version is not an integer or out of range")
+			return
+		}
+		if proto != 2 && proto != 3 {
+			c.w.error("NOPROTO unsupported protocol version")
+			return
+		}
+		if len(args) > 2 {
+			c.w.error(fmt.Sprintf("ERR Syntax error in HELLO option '%s'", args[2]))
+			return
+		}
+		c.w.proto = proto
+	}
+
+	c.w.mapHeader(4)
+	c.w.bulk("server")
+	c.w.bulk("hnsw-go")
+	c.w.bulk("proto")
+	c.w.integer(int64(c.w.proto))
+	c.w.bulk("mode")
+	c.w.bulk("standalone")
+	c.w.bulk("role")
+	c.w.bulk("master")
+}
+
+// cmdCommand replies with an empty command list, enough for clients that
+// query it on connection
+func cmdCommand(c *client, args []string) {
+	c.w.array(0)
+}
+
+func cmdDel(c *client, args []string) {
+	s := c.server
+	s.setsMutex.Lock()
+	defer s.setsMutex.Unlock()
+
+	removed := 0
+	for _, key := range args[1:] {
+		if _, ok := s.sets[key]; ok {
+			delete(s.sets, key)
+			removed++
+		}
+	}
+	c.w.integer(int64(removed))
+}
+
+func cmdExists(c *client, args []string) {
+	found := 0
+	for _, key := range args[1:] {
+		if c.server.set(key) != nil {
+			found++
+		}
+	}
+	c.w.integer(int64(found))
+}
+
+// parseVector parses the components of a vector
+func parseVector(args []string) ([]float32, error) {
+	v := make([]float32, len(args))
+	for i, arg := range args {
+		f, err := strconv.ParseFloat(arg, 32)
+		if err != nil {
+			return nil, fmt.Errorf("ERR invalid vector component '%s'", arg)
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
+}
+
+// cmdVadd adds an element, replying 1, or updates the vector of an existing
+// one, replying 0
+func cmdVadd(c *client, args []string) {
+	key, element := args[1], args[2]
+	vector, err := parseVector(args[3:])
+	if err != nil {
+		c.w.error(err.Error())
+		return
+	}
+
+	vs, err := c.server.lockSetOrCreate(key)
+	if err != nil {
+		c.w.error("ERR " + err.Error())
+		return
+	}
+	defer vs.mutex.Unlock()
+	if dim := vs.index.Dim(); dim != 0 && dim != len(vector) {
+		c.w.error(fmt.Sprintf("ERR vector dimension %d, set dimension %d", len(vector), dim))
+		return
+	}
+
+	if id, ok := vs.ids[element]; ok {
+		if err := vs.index.Update(id, vector); err != nil {
+			c.w.error("ERR " + err.Error())
+			return
+		}
+		c.w.integer(0)
+		return
+	}
+
+	id := len(vs.index.Nodes)
+	vs.index.Insert(vector, id)
+	vs.ids[element] = id
+	vs.names = append(vs.names, element)
+	c.w.integer(1)
+}
+
+// cmdVsim replies with the K elements closest to a vector, closest first
+func cmdVsim(c *client, args []string) {
+	K, err := strconv.Atoi(args[2])
+	if err != nil || K <= 0 {
+		c.w.error("ERR K must be a positive integer")
+		return
+	}
+	ef, err := strconv.Atoi(args[3])
+	if err != nil || ef <= 0 {
+		c.w.error("ERR EF must be a positive integer")
+		return
+	}
+	rest := args[4:]
+	withScores := strings.EqualFold(rest[0], "WITHSCORES")
+	if withScores {
+		rest = rest[1:]
+	}
+	if len(rest) == 0 {
+		c.w.error("ERR wrong number of arguments for 'vsim' command")
+		return
+	}
+	query, err := parseVector(rest)
+	if err != nil {
+		c.w.error(err.Error())
+		return
+	}
+
+	vs := c.server.set(args[1])
+	if vs == nil {
+		c.w.array(0)
+		return
+	}
+	if dim := vs.index.Dim(); dim != 0 && dim != len(query) {
+		c.w.error(fmt.Sprintf("ERR vector dimension %d, set dimension %d", len(query), dim))
+		return
+	}
+
+	// Holding the read lock keeps the results valid while they are named
+	vs.mutex.RLock()
+	defer vs.mutex.RUnlock()
+	ids := vs.index.KNN_Search(query, K, ef)
+
+	if !withScores {
+		c.w.array(len(ids))
+		for _, id := range ids {
+			c.w.bulk(vs.names[id])
+		}
+		return
+	}
+	c.w.mapHeader(len(ids))
+	for _, id := range ids {
+		c.w.bulk(vs.names[id])
+		c.w.double(float64(vs.index.DistanceFunc(query, vs.index.Nodes[id].Vector)))
+	}
+}
+
+// cmdVrem removes an element, replying 1 if it existed and 0 otherwise
+func cmdVrem(c *client, args []string) {
+	vs := c.server.set(args[1])
+	if vs == nil {
+		c.w.integer(0)
+		return
+	}
+
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+	id, ok := vs.ids[args[2]]
+	if !ok {
+		c.w.integer(0)
+		return
+	}
+	if err := vs.index.Delete(id); err != nil {
+		c.w.error("ERR " + err.Error())
+		return
+	}
+	delete(vs.ids, args[2])
+	c.w.integer(1)
+}
+
+func cmdVcard(c *client, args []string) {
+	vs := c.server.set(args[1])
+	if vs == nil {
+		c.w.integer(0)
+		return
+	}
+	c.w.integer(int64(vs.index.Len()))
+}
+
+// cmdVinfo replies with the dimension, size and configuration of a set, or
+// null if the key does not exist
+func cmdVinfo(c *client, args []string) {
+	vs := c.server.set(args[1])
+	if vs == nil {
+		c.w.null()
+		return
+	}
+
+	vs.mutex.RLock()
+	nodes := len(vs.index.Nodes)
+	vs.mutex.RUnlock()
+	h := vs.index
+	size := h.Len()
+
+	fields := []struct {
+		name  string
+		value int
+	}{
+		{"dim", h.Dim()},
+		{"size", size},
+		{"nodes", nodes},
+		{"deleted", nodes - size},
+		{"m", h.M},
+		{"mmax", h.Mmax},
+		{"mmax0", h.Mmax0},
+		{"ef-construction", h.EfConstruction},
+		{"max-level", h.MaxLevel},
+	}
+	c.w.mapHeader(len(fields))
+	for _, f := range fields {
+		c.w.bulk(f.name)
+		c.w.integer(int64(f.value))
+	}
+}
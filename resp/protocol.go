@@ -0,0 +1,170 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Limits on incoming commands, to bound the memory used by a client
+const (
+	maxArgs       = 1 << 20
+	maxBulkLength = 512 << 20
+	maxInlineLine = 64 << 10
+)
+
+// errProtocol is returned when a client sends malformed data. The
+// connection is closed after replying with the error, as Redis does.
+var errProtocol = errors.New("Protocol error")
+
+// reader parses commands sent by a client: arrays of bulk strings, or
+// inline commands separated by spaces for interactive use
+type reader struct {
+	r *bufio.Reader
+}
+
+func newReader(r io.Reader) *reader {
+	return &reader{r: bufio.NewReader(r)}
+}
+
+// line reads a line terminated by CRLF, or by LF alone for inline commands
+func (rd *reader) line() (string, error) {
+	line, err := rd.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", fmt.Errorf("%w: line too long", errProtocol)
+	}
+	if err != nil {
+		if err == io.EOF && len(line) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
+
+// command reads the next command. Empty inline lines are skipped.
+func (rd *reader) command() ([]string, error) {
+	for {
+		line, err := rd.line()
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "*") {
+			if len(line) > maxInlineLine {
+				return nil, fmt.Errorf("%w: inline command too long", errProtocol)
+			}
+			if args := strings.Fields(line); len(args) > 0 {
+				return args, nil
+			}
+			continue
+		}
+
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxArgs {
+			return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+		}
+		if n <= 0 {
+			continue
+		}
+		args := make([]string, n)
+		for i := range args {
+			if args[i], err = rd.bulk(); err != nil {
+				return nil, err
+			}
+		}
+		return args, nil
+	}
+}
+
+// bulk reads a bulk string
+func (rd *reader) bulk() (string, error) {
+	line, err := rd.line()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "$") {
+		return "", fmt.Errorf("%w: expected '$', got '%.1s'", errProtocol, line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > maxBulkLength {
+		return "", fmt.Errorf("%w: invalid bulk length", errProtocol)
+	}
+
+	b := make([]byte, n+2)
+	if _, err := io.ReadFull(rd.r, b); err != nil {
+		return "", err
+	}
+	if b[n] != '\r' || b[n+1] != '\n' {
+		return "", fmt.Errorf("%w: bulk string not terminated by CRLF", errProtocol)
+	}
+	return string(b[:n]), nil
+}
+
+// writer encodes replies for the protocol version negotiated with HELLO.
+// RESP3 types are downgraded to their RESP2 equivalent for version 2
+// clients: maps become flat arrays, doubles become bulk strings and null
+// becomes the null bulk string.
+type writer struct {
+	w     *bufio.Writer
+	proto int
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{w: bufio.NewWriter(w), proto: 2}
+}
+
+func (wr *writer) simple(s string) {
+	wr.w.WriteString("+" + s + "\r\n")
+}
+
+func (wr *writer) error(msg string) {
+	// Error messages are a single line
+	msg = strings.NewReplacer("\r", " ", "\n", " ").Replace(msg)
+	wr.w.WriteString("-" + msg + "\r\n")
+}
+
+func (wr *writer) integer(n int64) {
+	wr.w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+func (wr *writer) bulk(s string) {
+	wr.w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func (wr *writer) null() {
+	if wr.proto >= 3 {
+		wr.w.WriteString("_\r\n")
+	} else {
+		wr.w.WriteString("$-1\r\n")
+	}
+}
+
+func (wr *writer) double(f float64) {
+	s := strconv.FormatFloat(f, 'g', 17, 64)
+	if wr.proto >= 3 {
+		wr.w.WriteString("," + s + "\r\n")
+	} else {
+		wr.bulk(s)
+	}
+}
+
+// array starts an array of n elements, which must be written next
+func (wr *writer) array(n int) {
+	wr.w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+// mapHeader starts a map of n key-value pairs, which must be written next
+func (wr *writer) mapHeader(n int) {
+	if wr.proto >= 3 {
+		wr.w.WriteString("%" + strconv.Itoa(n) + "\r\n")
+	} else {
+		wr.array(2 * n)
+	}
+}
+
+func (wr *writer) flush() error {
+	return wr.w.Flush()
+}
@@ -0,0 +1,100 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCommand(t *testing.T) {
+	input := "*3\r\n$4\r\nVADD\r\n$3\r\nkey\r\n$0\r\n\r\n" +
+		"PING hello\r\n" +
+		"\r\n" +
+		"*0\r\n" +
+		"echo  spaced   out\n" +
+		"*1\r\n$4\r\na\r\nb\r\n"
+	r := newReader(strings.NewReader(input))
+
+	expected := [][]string{
+		{"VADD", "key", ""},
+		{"PING", "hello"},
+		{"echo", "spaced", "out"},
+		{"a\r\nb"},
+	}
+	for i, want := range expected {
+		got, err := r.command()
+		if err != nil {
+			t.Fatalf("Command %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Command %d: expected %q, got %q", i, want, got)
+		}
+	}
+	if _, err := r.command(); err != io.EOF {
+		t.Errorf("Expected io.EOF at the end, got %v", err)
+	}
+}
+
+func TestReadCommandInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		protocol bool
+	}{
+		{"invalid length", "*x\r\n", true},
+		{"missing dollar", "*1\r\n:1\r\n", true},
+		{"negative bulk length", "*1\r\n$-1\r\n", true},
+		{"unterminated bulk", "*1\r\n$3\r\nabcde\r\n", true},
+		{"truncated", "*2\r\n$3\r\nabc\r\n", false},
+		{"truncated line", "*1\r\n$3", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newReader(strings.NewReader(tt.input)).command()
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+			if errors.Is(err, errProtocol) != tt.protocol {
+				t.Errorf("Expected protocol error %v, got %v", tt.protocol, err)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	write := func(w *writer) {
+		w.simple("OK")
+		w.error("ERR bad\nthing")
+		w.integer(-7)
+		w.bulk("hi")
+		w.null()
+		w.double(0.5)
+		w.mapHeader(1)
+		w.bulk("k")
+		w.array(0)
+	}
+
+	tests := []struct {
+		proto    int
+		expected string
+	}{
+		{2, "+OK\r\n-ERR bad thing\r\n:-7\r\n$2\r\nhi\r\n$-1\r\n$3\r\n0.5\r\n*2\r\n$1\r\nk\r\n*0\r\n"},
+		{3, "+OK\r\n-ERR bad thing\r\n:-7\r\n$2\r\nhi\r\n_\r\n,0.5\r\n%1\r\n$1\r\nk\r\n*0\r\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		w.proto = tt.proto
+		write(w)
+		if err := w.flush(); err != nil {
+			t.Fatalf("flush failed: %v", err)
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("RESP%d: expected %q, got %q", tt.proto, tt.expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package resp serves HNSW indexes over the Redis serialization protocol,
+// so that existing Redis client libraries can be used for vector search.
+//
+// Both RESP2 and RESP3 are supported: connections start with RESP2 and can
+// switch with HELLO 3. Every key holds a vector set, backed by its own HNSW
+// index and created by the first VADD. The vector commands are:
+//
+//	VADD key element v1 v2 ...          add an element, or update its vector
+//	VSIM key K EF [WITHSCORES] v1 ...   the K elements closest to a vector
+//	VREM key element                    remove an element
+//	VCARD key                           number of elements
+//	VINFO key                           dimension, size and configuration
+//
+// Elements are named by arbitrary strings, mapped to the node IDs of the
+// index. Scores returned by VSIM WITHSCORES are distances: lower is closer.
+// PING, ECHO, HELLO, COMMAND, DEL, EXISTS and QUIT are supported as well.
+package resp
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// Server accepts RESP connections and executes their commands against a set
+// of in-memory vector sets.
+type Server struct {
+	// cfg is the configuration of the indexes created by VADD
+	cfg hnsw.Config
+
+	// setsMutex guards sets
+	setsMutex sync.RWMutex
+	sets      map[string]*vectorSet
+
+	// mutex guards listeners, conns and closed
+	mutex     sync.Mutex
+	listeners map[net.Listener]bool
+	conns     map[net.Conn]bool
+	closed    bool
+
+	// handlers tracks the goroutines serving connections
+	handlers sync.WaitGroup
+}
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("resp: server closed")
+
+// NewServer returns a Server whose vector sets are created with cfg. The
+// configuration is validated when the first set is created.
+func NewServer(cfg hnsw.Config) *Server {
+	return &Server{
+		cfg:       cfg,
+		sets:      make(map[string]*vectorSet),
+		listeners: make(map[net.Listener]bool),
+		conns:     make(map[net.Conn]bool),
+	}
+}
+
+// ListenAndServe listens on the TCP address addr and serves connections
+// until Close is called.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l, serving each one in its own goroutine,
+// until Close is called, in which case it returns ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = true
+	s.mutex.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mutex.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mutex.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = true
+		s.handlers.Add(1)
+		s.mutex.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops the listeners, closes every connection and waits for the
+// commands in progress to complete.
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	s.handlers.Wait()
+	return nil
+}
+
+// serveConn reads and executes the commands of a connection until the
+// client disconnects or sends QUIT
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.handlers.Done()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	c := &client{server: s, r: newReader(conn), w: newWriter(conn)}
+	for {
+		args, err := c.r.command()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.w.error("ERR " + err.Error())
+				c.w.flush()
+			}
+			return
+		}
+
+		quit := c.execute(args)
+
+		// Replies are flushed once the pipelined commands already received
+		// have been executed
+		if c.r.r.Buffered() == 0 || quit {
+			if err := c.w.flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// Set returns the index backing key, or nil if the key does not exist. The
+// index must not be modified directly, since element names would no longer
+// match its nodes.
+func (s *Server) Set(key string) *hnsw.HNSW {
+	if vs := s.set(key); vs != nil {
+		return vs.index
+	}
+	return nil
+}
+
+// set returns the vector set stored at key, or nil
+func (s *Server) set(key string) *vectorSet {
+	s.setsMutex.RLock()
+	defer s.setsMutex.RUnlock()
+	return s.sets[key]
+}
+
+// setOrCreate returns the vector set stored at key, creating it if needed
+func (s *Server) setOrCreate(key string) (*vectorSet, error) {
+	if vs := s.set(key); vs != nil {
+		return vs, nil
+	}
+
+	s.setsMutex.Lock()
+	defer s.setsMutex.Unlock()
+	if vs := s.sets[key]; vs != nil {
+		return vs, nil
+	}
+	index, err := hnsw.NewHNSW(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	vs := &vectorSet{index: index, ids: make(map[string]int)}
+	s.sets[key] = vs
+	return vs, nil
+}
+
+// lockSetOrCreate returns the vector set stored at key, creating it if
+// needed, with its mutex locked. The set is checked to still be stored at
+// key once locked, so that a concurrent DEL cannot leave the caller writing
+// to a set no longer reachable.
+func (s *Server) lockSetOrCreate(key string) (*vectorSet, error) {
+	for {
+		vs, err := s.setOrCreate(key)
+		if err != nil {
+			return nil, err
+		}
+		vs.mutex.Lock()
+		if s.set(key) == vs {
+			return vs, nil
+		}
+		vs.mutex.Unlock()
+	}
+}
+
+// vectorSet is an HNSW index with names for its nodes
+type vectorSet struct {
+	// mutex guards ids and names, and serializes the mutations of index
+	mutex sync.RWMutex
+	index *hnsw.HNSW
+
+	// ids maps the name of every live element to its node ID
+	ids map[string]int
+
+	// names holds the element name of every node, deleted ones included
+	names []string
+}
@@ -0,0 +1,356 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// replyError is an error reply received by testClient
+type replyError string
+
+func (e replyError) Error() string {
+	return string(e)
+}
+
+// testClient is a minimal RESP client. Replies are decoded to string,
+// int64, float64, nil, []any, map[string]any or replyError.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	cfg := hnsw.DefaultConfig()
+	cfg.M, cfg.Mmax, cfg.Mmax0 = 8, 16, 32
+	s := NewServer(cfg)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+	t.Cleanup(func() {
+		s.Close()
+		if err := <-done; !errors.Is(err, ErrServerClosed) {
+			t.Errorf("Expected ErrServerClosed from Serve, got %v", err)
+		}
+	})
+	return s, l.Addr().String()
+}
+
+func dial(t *testing.T, addr string) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+// send writes a command as an array of bulk strings
+func (c *testClient) send(args ...string) {
+	c.t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		c.t.Fatalf("Write failed: %v", err)
+	}
+}
+
+// do sends a command and reads its reply
+func (c *testClient) do(args ...string) any {
+	c.t.Helper()
+	c.send(args...)
+	return c.reply()
+}
+
+func (c *testClient) reply() any {
+	c.t.Helper()
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("Read failed: %v", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	payload := line[1:]
+
+	switch line[0] {
+	case '+':
+		return payload
+	case '-':
+		return replyError(payload)
+	case ':':
+		n, _ := strconv.ParseInt(payload, 10, 64)
+		return n
+	case ',':
+		f, _ := strconv.ParseFloat(payload, 64)
+		return f
+	case '_':
+		return nil
+	case '$':
+		n, _ := strconv.Atoi(payload)
+		if n < 0 {
+			return nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			c.t.Fatalf("Read failed: %v", err)
+		}
+		return string(b[:n])
+	case '*':
+		n, _ := strconv.Atoi(payload)
+		values := make([]any, n)
+		for i := range values {
+			values[i] = c.reply()
+		}
+		return values
+	case '%':
+		n, _ := strconv.Atoi(payload)
+		values := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			key := c.reply().(string)
+			values[key] = c.reply()
+		}
+		return values
+	}
+	c.t.Fatalf("Unexpected reply %q", line)
+	return nil
+}
+
+func TestVectorCommands(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	for i := 0; i < 50; i++ {
+		x := strconv.Itoa(i)
+		if got := c.do("VADD", "points", "p"+x, x, "0"); got != int64(1) {
+			t.Fatalf("VADD p%d: expected 1, got %v", i, got)
+		}
+	}
+	// Adding an existing element updates its vector
+	if got := c.do("vadd", "points", "p0", "24.5", "0"); got != int64(0) {
+		t.Errorf("VADD update: expected 0, got %v", got)
+	}
+
+	if got := c.do("VCARD", "points"); got != int64(50) {
+		t.Errorf("VCARD: expected 50, got %v", got)
+	}
+	got := c.do("VSIM", "points", "3", "20", "10.2", "0")
+	if fmt.Sprint(got) != "[p10 p11 p9]" {
+		t.Errorf("VSIM: expected [p10 p11 p9], got %v", got)
+	}
+	got = c.do("VSIM", "points", "1", "20", "24.4", "0")
+	if fmt.Sprint(got) != "[p0]" {
+		t.Errorf("VSIM after update: expected [p0], got %v", got)
+	}
+
+	// RESP2 scores are a flat array of name and distance bulk strings
+	got = c.do("VSIM", "points", "2", "20", "WITHSCORES", "20", "0")
+	if fmt.Sprint(got) != "[p20 0 p19 1]" && fmt.Sprint(got) != "[p20 0 p21 1]" {
+		t.Errorf("VSIM WITHSCORES: unexpected reply %v", got)
+	}
+
+	if got := c.do("VREM", "points", "p10"); got != int64(1) {
+		t.Errorf("VREM: expected 1, got %v", got)
+	}
+	if got := c.do("VREM", "points", "p10"); got != int64(0) {
+		t.Errorf("VREM twice: expected 0, got %v", got)
+	}
+	got = c.do("VSIM", "points", "1", "20", "10", "0")
+	if fmt.Sprint(got) == "[p10]" {
+		t.Errorf("VSIM returned a removed element")
+	}
+
+	info, ok := c.do("VINFO", "points").([]any)
+	if !ok || len(info) != 18 || info[0] != "dim" || info[1] != int64(2) || info[2] != "size" || info[3] != int64(49) {
+		t.Errorf("VINFO: unexpected reply %v", info)
+	}
+
+	// Missing keys
+	if got := c.do("VCARD", "missing"); got != int64(0) {
+		t.Errorf("VCARD missing: expected 0, got %v", got)
+	}
+	if got := c.do("VSIM", "missing", "1", "1", "0", "0"); fmt.Sprint(got) != "[]" {
+		t.Errorf("VSIM missing: expected [], got %v", got)
+	}
+	if got := c.do("VINFO", "missing"); got != nil {
+		t.Errorf("VINFO missing: expected nil, got %v", got)
+	}
+
+	if got := c.do("EXISTS", "points", "missing"); got != int64(1) {
+		t.Errorf("EXISTS: expected 1, got %v", got)
+	}
+	if got := c.do("DEL", "points"); got != int64(1) {
+		t.Errorf("DEL: expected 1, got %v", got)
+	}
+	if got := c.do("VCARD", "points"); got != int64(0) {
+		t.Errorf("VCARD after DEL: expected 0, got %v", got)
+	}
+}
+
+func TestHello(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+
+	if got := c.do("PING"); got != "PONG" {
+		t.Errorf("PING: expected PONG, got %v", got)
+	}
+
+	hello, ok := c.do("HELLO", "3").(map[string]any)
+	if !ok || hello["proto"] != int64(3) || hello["server"] != "hnsw-go" {
+		t.Fatalf("HELLO 3: unexpected reply %v", hello)
+	}
+
+	// RESP3 replies use maps, doubles and null
+	c.do("VADD", "s", "a", "0", "0")
+	c.do("VADD", "s", "b", "3", "4")
+	scores, ok := c.do("VSIM", "s", "2", "10", "WITHSCORES", "0", "0").(map[string]any)
+	if !ok || scores["a"] != float64(0) || scores["b"] != float64(25) {
+		t.Errorf("VSIM WITHSCORES: unexpected reply %v", scores)
+	}
+	info, ok := c.do("VINFO", "s").(map[string]any)
+	if !ok || info["size"] != int64(2) || info["m"] != int64(8) {
+		t.Errorf("VINFO: unexpected reply %v", info)
+	}
+	if got := c.do("VINFO", "missing"); got != nil {
+		t.Errorf("VINFO missing: expected nil, got %v", got)
+	}
+
+	if _, ok := c.do("HELLO", "4").(replyError); !ok {
+		t.Errorf("HELLO 4: expected an error")
+	}
+	if got := c.do("QUIT"); got != "OK" {
+		t.Errorf("QUIT: expected OK, got %v", got)
+	}
+	if _, err := c.r.ReadByte(); err == nil {
+		t.Errorf("Expected the connection to be closed after QUIT")
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	_, addr := newTestServer(t)
+	c := dial(t, addr)
+	c.do("VADD", "s", "a", "1", "2")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"unknown command", []string{"GET", "s"}},
+		{"arity", []string{"VCARD"}},
+		{"invalid component", []string{"VADD", "s", "b", "1", "x"}},
+		{"dimension mismatch", []string{"VADD", "s", "b", "1", "2", "3"}},
+		{"invalid K", []string{"VSIM", "s", "0", "10", "1", "2"}},
+		{"invalid EF", []string{"VSIM", "s", "1", "x", "1", "2"}},
+		{"no vector", []string{"VSIM", "s", "1", "10", "WITHSCORES"}},
+		{"query dimension mismatch", []string{"VSIM", "s", "1", "10", "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := c.do(tt.args...).(replyError)
+			if !ok || !strings.HasPrefix(string(got), "ERR ") {
+				t.Errorf("Expected an ERR reply, got %v", got)
+			}
+		})
+	}
+
+	// Errors do not affect the connection
+	if got := c.do("VCARD", "s"); got != int64(1) {
+		t.Errorf("VCARD: expected 1, got %v", got)
+	}
+
+	// Malformed input closes the connection after an error
+	c.conn.Write([]byte("*1\r\n:1\r\n"))
+	if _, ok := c.reply().(replyError); !ok {
+		t.Errorf("Expected a protocol error")
+	}
+	if _, err := c.r.ReadByte(); err == nil {
+		t.Errorf("Expected the connection to be closed after a protocol error")
+	}
+}
+
+func TestPipelineConcurrent(t *testing.T) {
+	s, addr := newTestServer(t)
+
+	// Pipelined commands from several clients, with inline commands mixed in
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := dial(t, addr)
+			for i := 0; i < 100; i++ {
+				c.send("VADD", "shared", fmt.Sprintf("w%d-%d", w, i), strconv.Itoa(w), strconv.Itoa(i))
+			}
+			c.conn.Write([]byte("VCARD shared\r\n"))
+			for i := 0; i < 100; i++ {
+				if got := c.reply(); got != int64(1) {
+					t.Errorf("VADD: expected 1, got %v", got)
+					return
+				}
+			}
+			if n, ok := c.reply().(int64); !ok || n < 100 {
+				t.Errorf("VCARD: expected at least 100, got %v", n)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if h := s.Set("shared"); h == nil || h.Len() != 400 {
+		t.Fatalf("Expected 400 elements in the shared set")
+	}
+}
+
+// TestLockSetOrCreateDeleted verifies that a set deleted while a VADD waits
+// for its lock is replaced by a new set stored at the key, rather than
+// written to after it is gone
+func TestLockSetOrCreateDeleted(t *testing.T) {
+	cfg := hnsw.DefaultConfig()
+	s := NewServer(cfg)
+
+	old, err := s.setOrCreate("points")
+	if err != nil {
+		t.Fatalf("setOrCreate failed: %v", err)
+	}
+	old.mutex.Lock()
+
+	locked := make(chan *vectorSet)
+	go func() {
+		vs, err := s.lockSetOrCreate("points")
+		if err != nil {
+			t.Errorf("lockSetOrCreate failed: %v", err)
+		}
+		locked <- vs
+	}()
+
+	// DEL runs while the set is locked
+	s.setsMutex.Lock()
+	delete(s.sets, "points")
+	s.setsMutex.Unlock()
+	old.mutex.Unlock()
+
+	vs := <-locked
+	defer vs.mutex.Unlock()
+	if vs == old {
+		t.Fatalf("Expected a new set after DEL, got the deleted one")
+	}
+	if s.set("points") != vs {
+		t.Errorf("Expected the locked set to be stored at the key")
+	}
+}
@@ -0,0 +1,355 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// insertRequest is the body of POST /vectors
+type insertRequest struct {
+	Vector []float32 `json:"vector"`
+}
+
+// idResponse is the response to POST /vectors and DELETE /vectors/{id}
+type idResponse struct {
+	ID int `json:"id"`
+}
+
+// batchRequest is the body of POST /vectors/batch
+type batchRequest struct {
+	Vectors [][]float32 `json:"vectors"`
+}
+
+// batchResponse is the response to POST /vectors/batch
+type batchResponse struct {
+	IDs []int `json:"ids"`
+}
+
+// vectorResponse is the response to GET /vectors/{id}
+type vectorResponse struct {
+	ID     int       `json:"id"`
+	Vector []float32 `json:"vector"`
+}
+
+// Filter restricts the nodes a search may return.
+type Filter struct {
+	// IDs, if not empty, are the only nodes that may be returned
+	IDs []int `json:"ids,omitempty"`
+
+	// ExcludeIDs are never returned
+	ExcludeIDs []int `json:"exclude_ids,omitempty"`
+}
+
+// accept returns the filter function of a search, or nil if f accepts
+// every node
+func (f *Filter) accept() func(id int) bool {
+	if f == nil || (len(f.IDs) == 0 && len(f.ExcludeIDs) == 0) {
+		return nil
+	}
+
+	var allowed map[int]bool
+	if len(f.IDs) > 0 {
+		allowed = make(map[int]bool, len(f.IDs))
+		for _, id := range f.IDs {
+			allowed[id] = true
+		}
+	}
+	excluded := make(map[int]bool, len(f.ExcludeIDs))
+	for _, id := range f.ExcludeIDs {
+		excluded[id] = true
+	}
+	return func(id int) bool {
+		return (allowed == nil || allowed[id]) && !excluded[id]
+	}
+}
+
+// searchRequest is the body of POST /search
+type searchRequest struct {
+	Vector []float32 `json:"vector"`
+	K      int       `json:"k"`
+	Ef     int       `json:"ef,omitempty"`
+	Filter *Filter   `json:"filter,omitempty"`
+}
+
+// Match is a search result.
+type Match struct {
+	ID       int     `json:"id"`
+	Distance float32 `json:"distance"`
+}
+
+// searchResponse is the response to POST /search
+type searchResponse struct {
+	Results []Match `json:"results"`
+}
+
+// statsResponse is the response to GET /stats
+type statsResponse struct {
+	Nodes          int `json:"nodes"`
+	Live           int `json:"live"`
+	Dim            int `json:"dim"`
+	M              int `json:"m"`
+	Mmax           int `json:"mmax"`
+	Mmax0          int `json:"mmax0"`
+	EfConstruction int `json:"ef_construction"`
+	MaxLevel       int `json:"max_level"`
+}
+
+// errorResponse is the body of every error response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// httpError is an error with the status code to report it with
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+// badRequest returns a 400 error
+func badRequest(format string, args ...any) error {
+	return &httpError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("POST /vectors", s.handle(s.insert))
+	s.mux.HandleFunc("POST /vectors/batch", s.handle(s.insertBatch))
+	s.mux.HandleFunc("GET /vectors/{id}", s.handle(s.get))
+	s.mux.HandleFunc("DELETE /vectors/{id}", s.handle(s.delete))
+	s.mux.HandleFunc("POST /search", s.handle(s.search))
+	s.mux.HandleFunc("GET /stats", s.handle(s.stats))
+	s.mux.HandleFunc("GET /healthz", s.healthz)
+	if s.opts.Metrics != nil {
+		s.mux.Handle("GET /metrics", s.opts.Metrics)
+	}
+}
+
+// handle adapts an API method to an http.HandlerFunc: the request body is
+// limited to MaxBodyBytes, and the result is written as JSON
+func (s *Server) handle(fn func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBodyBytes)
+		res, err := fn(r)
+		if err != nil {
+			writeJSON(w, statusOf(err), errorResponse{err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+// statusOf returns the HTTP status code for an error
+func statusOf(err error) int {
+	var httpErr *httpError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.status
+	case errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, hnsw.ErrNodeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrDraining):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// decode reads the JSON body of a request into v
+func decode(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return err
+		}
+		return badRequest("invalid JSON body: %v", err)
+	}
+	if dec.More() {
+		return badRequest("invalid JSON body: trailing data")
+	}
+	// Read to the end, so that an oversized body is reported
+	if _, err := io.Copy(io.Discard, r.Body); err != nil {
+		return err
+	}
+	return nil
+}
+
+// pathID parses the {id} path parameter
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 0 {
+		return 0, badRequest("invalid id %q", r.PathValue("id"))
+	}
+	return id, nil
+}
+
+// checkVector validates a vector against the dimension of the index, or
+// against dim if the index is empty and dim is not zero
+func (s *Server) checkVector(v []float32, dim int) error {
+	if len(v) == 0 {
+		return badRequest("vector cannot be empty")
+	}
+	if d := s.index.Dim(); d != 0 {
+		dim = d
+	}
+	if dim != 0 && len(v) != dim {
+		return badRequest("vector dimension %d, index dimension %d", len(v), dim)
+	}
+	return nil
+}
+
+func (s *Server) insert(r *http.Request) (any, error) {
+	var req insertRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	if s.draining.Load() {
+		return nil, ErrDraining
+	}
+	if err := s.checkVector(req.Vector, 0); err != nil {
+		return nil, err
+	}
+
+	id := len(s.index.Nodes)
+	s.index.Insert(req.Vector, id)
+	return idResponse{ID: id}, nil
+}
+
+func (s *Server) insertBatch(r *http.Request) (any, error) {
+	var req batchRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if len(req.Vectors) > s.opts.MaxBatchSize {
+		return nil, &httpError{http.StatusRequestEntityTooLarge,
+			fmt.Errorf("batch of %d vectors exceeds the limit of %d", len(req.Vectors), s.opts.MaxBatchSize)}
+	}
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	if s.draining.Load() {
+		return nil, ErrDraining
+	}
+
+	// The whole batch is validated first, so that it is inserted entirely
+	// or not at all
+	for i, v := range req.Vectors {
+		if err := s.checkVector(v, len(req.Vectors[0])); err != nil {
+			return nil, badRequest("vector %d: %v", i, err)
+		}
+	}
+
+	ids := make([]int, len(req.Vectors))
+	for i, v := range req.Vectors {
+		ids[i] = len(s.index.Nodes)
+		s.index.Insert(v, ids[i])
+	}
+	return batchResponse{IDs: ids}, nil
+}
+
+func (s *Server) get(r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.index.Vector(id)
+	if err != nil {
+		return nil, err
+	}
+	return vectorResponse{ID: id, Vector: v}, nil
+}
+
+func (s *Server) delete(r *http.Request) (any, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	if s.draining.Load() {
+		return nil, ErrDraining
+	}
+	if err := s.index.Delete(id); err != nil {
+		return nil, err
+	}
+	return idResponse{ID: id}, nil
+}
+
+func (s *Server) search(r *http.Request) (any, error) {
+	var req searchRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.K <= 0 || req.K > s.opts.MaxK {
+		return nil, badRequest("k must be in [1, %d]", s.opts.MaxK)
+	}
+	if req.Ef < 0 || req.Ef > s.opts.MaxEf {
+		return nil, badRequest("ef must be in [0, %d]", s.opts.MaxEf)
+	}
+	if req.Ef == 0 {
+		req.Ef = s.opts.DefaultEf
+	}
+	if dim := s.index.Dim(); dim != 0 && len(req.Vector) != dim {
+		return nil, badRequest("vector dimension %d, index dimension %d", len(req.Vector), dim)
+	}
+
+	ids := s.index.KNN_SearchFilter(req.Vector, req.K, req.Ef, req.Filter.accept())
+	results := make([]Match, 0, len(ids))
+	for _, id := range ids {
+		// Nodes deleted since the search are dropped
+		v, err := s.index.Vector(id)
+		if err != nil {
+			continue
+		}
+		results = append(results, Match{ID: id, Distance: s.index.DistanceFunc(req.Vector, v)})
+	}
+	return searchResponse{Results: results}, nil
+}
+
+func (s *Server) stats(r *http.Request) (any, error) {
+	s.writeMutex.Lock()
+	nodes := len(s.index.Nodes)
+	s.writeMutex.Unlock()
+
+	return statsResponse{
+		Nodes:          nodes,
+		Live:           s.index.Len(),
+		Dim:            s.index.Dim(),
+		M:              s.index.M,
+		Mmax:           s.index.Mmax,
+		Mmax0:          s.index.Mmax0,
+		EfConstruction: s.index.EfConstruction,
+		MaxLevel:       s.index.MaxLevel,
+	}, nil
+}
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{ErrDraining.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"ok"})
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+func newTestServer(t *testing.T, opts Options) (*Server, *httptest.Server) {
+	t.Helper()
+	h, err := hnsw.NewHNSW(hnsw.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	s := New(h, opts)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// call sends a request with a JSON body and decodes the JSON response into
+// out, returning the status code
+func call(t *testing.T, ts *httptest.Server, method, path string, body, out any) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if s, ok := body.(string); ok {
+			buf.WriteString(s)
+		} else if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("Failed to encode request: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, ts.URL+path, &buf)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: invalid JSON response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestInsertGetDelete(t *testing.T) {
+	_, ts := newTestServer(t, Options{})
+
+	var inserted idResponse
+	if status := call(t, ts, "POST", "/vectors", insertRequest{Vector: []float32{1, 2, 3}}, &inserted); status != http.StatusOK {
+		t.Fatalf("Insert: expected status 200, got %d", status)
+	}
+	if inserted.ID != 0 {
+		t.Errorf("Expected ID 0, got %d", inserted.ID)
+	}
+
+	var batch batchResponse
+	status := call(t, ts, "POST", "/vectors/batch", batchRequest{Vectors: [][]float32{{4, 5, 6}, {7, 8, 9}}}, &batch)
+	if status != http.StatusOK || fmt.Sprint(batch.IDs) != "[1 2]" {
+		t.Fatalf("Batch insert: expected IDs [1 2] with status 200, got %v with %d", batch.IDs, status)
+	}
+
+	var got vectorResponse
+	if status := call(t, ts, "GET", "/vectors/2", nil, &got); status != http.StatusOK {
+		t.Fatalf("Get: expected status 200, got %d", status)
+	}
+	if got.ID != 2 || fmt.Sprint(got.Vector) != "[7 8 9]" {
+		t.Errorf("Expected vector 2 = [7 8 9], got %d = %v", got.ID, got.Vector)
+	}
+
+	if status := call(t, ts, "DELETE", "/vectors/1", nil, nil); status != http.StatusOK {
+		t.Fatalf("Delete: expected status 200, got %d", status)
+	}
+	var errResp errorResponse
+	if status := call(t, ts, "GET", "/vectors/1", nil, &errResp); status != http.StatusNotFound {
+		t.Errorf("Get deleted: expected status 404, got %d", status)
+	}
+	if errResp.Error == "" {
+		t.Errorf("Expected an error message")
+	}
+	if status := call(t, ts, "DELETE", "/vectors/1", nil, nil); status != http.StatusNotFound {
+		t.Errorf("Delete twice: expected status 404, got %d", status)
+	}
+
+	var stats statsResponse
+	call(t, ts, "GET", "/stats", nil, &stats)
+	if stats.Nodes != 3 || stats.Live != 2 || stats.Dim != 3 || stats.M != 16 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	_, ts := newTestServer(t, Options{})
+
+	vectors := make([][]float32, 100)
+	for i := range vectors {
+		vectors[i] = []float32{float32(i), 0}
+	}
+	call(t, ts, "POST", "/vectors/batch", batchRequest{Vectors: vectors}, nil)
+
+	tests := []struct {
+		name     string
+		request  searchRequest
+		expected string
+	}{
+		{"nearest", searchRequest{Vector: []float32{10.2, 0}, K: 3}, "[10 11 9]"},
+		{"explicit ef", searchRequest{Vector: []float32{50, 0}, K: 1, Ef: 20}, "[50]"},
+		{"allowed ids", searchRequest{Vector: []float32{10, 0}, K: 2, Filter: &Filter{IDs: []int{3, 40, 90}}}, "[3 40]"},
+		{"excluded ids", searchRequest{Vector: []float32{10.4, 0}, K: 2, Filter: &Filter{ExcludeIDs: []int{10}}}, "[11 9]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp searchResponse
+			if status := call(t, ts, "POST", "/search", tt.request, &resp); status != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", status)
+			}
+			ids := make([]int, len(resp.Results))
+			for i, m := range resp.Results {
+				ids[i] = m.ID
+			}
+			if fmt.Sprint(ids) != tt.expected {
+				t.Errorf("Expected %s, got %v", tt.expected, ids)
+			}
+			for _, m := range resp.Results {
+				if want := hnsw.EuclideanDistance(tt.request.Vector, vectors[m.ID]); m.Distance != want {
+					t.Errorf("ID %d: expected distance %v, got %v", m.ID, want, m.Distance)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	_, ts := newTestServer(t, Options{MaxBodyBytes: 1024, MaxBatchSize: 2, MaxK: 10})
+	call(t, ts, "POST", "/vectors", insertRequest{Vector: []float32{1, 2}}, nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+	}{
+		{"invalid JSON", "POST", "/vectors", `{"vector": [1,`, http.StatusBadRequest},
+		{"unknown field", "POST", "/vectors", `{"vector": [1, 2], "label": "x"}`, http.StatusBadRequest},
+		{"empty vector", "POST", "/vectors", insertRequest{}, http.StatusBadRequest},
+		{"dimension mismatch", "POST", "/vectors", insertRequest{Vector: []float32{1, 2, 3}}, http.StatusBadRequest},
+		{"batch dimension mismatch", "POST", "/vectors/batch", batchRequest{Vectors: [][]float32{{1, 2}, {1}}}, http.StatusBadRequest},
+		{"batch too large", "POST", "/vectors/batch", batchRequest{Vectors: [][]float32{{1, 2}, {3, 4}, {5, 6}}}, http.StatusRequestEntityTooLarge},
+		{"body too large", "POST", "/vectors", insertRequest{Vector: make([]float32, 1000)}, http.StatusRequestEntityTooLarge},
+		{"invalid id", "GET", "/vectors/abc", nil, http.StatusBadRequest},
+		{"unknown id", "GET", "/vectors/7", nil, http.StatusNotFound},
+		{"zero k", "POST", "/search", searchRequest{Vector: []float32{1, 2}}, http.StatusBadRequest},
+		{"k too large", "POST", "/search", searchRequest{Vector: []float32{1, 2}, K: 11}, http.StatusBadRequest},
+		{"search dimension mismatch", "POST", "/search", searchRequest{Vector: []float32{1}, K: 1}, http.StatusBadRequest},
+		{"wrong method", "PUT", "/search", nil, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := call(t, ts, tt.method, tt.path, tt.body, nil); status != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, status)
+			}
+		})
+	}
+
+	// Rejected batches insert nothing
+	var stats statsResponse
+	call(t, ts, "GET", "/stats", nil, &stats)
+	if stats.Nodes != 1 {
+		t.Errorf("Expected 1 node after rejected requests, got %d", stats.Nodes)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	_, ts := newTestServer(t, Options{})
+	if status := call(t, ts, "GET", "/metrics", nil, nil); status != http.StatusNotFound {
+		t.Errorf("Expected status 404 without metrics, got %d", status)
+	}
+
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "hnsw_nodes 0")
+	})
+	_, ts = newTestServer(t, Options{Metrics: metrics})
+	resp, err := ts.Client().Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	if resp.StatusCode != http.StatusOK || body.String() != "hnsw_nodes 0\n" {
+		t.Errorf("Expected the metrics handler output, got %d %q", resp.StatusCode, body.String())
+	}
+}
@@ -0,0 +1,172 @@
+// Package server exposes an HNSW index over HTTP with a JSON API.
+//
+// The endpoints are:
+//
+//	POST   /vectors         insert a vector, returns its ID
+//	POST   /vectors/batch   insert several vectors, returns their IDs
+//	GET    /vectors/{id}    return a vector
+//	DELETE /vectors/{id}    delete a vector
+//	POST   /search          K nearest neighbors, with optional ef and filter
+//	GET    /stats           size and configuration of the index
+//	GET    /healthz         200 while serving, 503 while draining
+//	GET    /metrics         Options.Metrics, if set
+//
+// IDs are assigned by the server, in insertion order, as required by HNSW.
+// Errors are returned as {"error": "..."} with a 4xx or 5xx status.
+//
+// Shutdown stops accepting connections, waits for the requests in progress
+// and then writes a snapshot of the index, if a snapshot path is configured.
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// Default limits applied when the corresponding Options field is zero.
+const (
+	DefaultMaxBodyBytes = 32 << 20
+	DefaultMaxBatchSize = 10000
+	DefaultMaxK         = 1000
+	DefaultMaxEf        = 10000
+	DefaultEf           = 100
+)
+
+// Options configures a Server.
+type Options struct {
+	// SnapshotPath is the file the index is saved to on Shutdown. No
+	// snapshot is taken if it is empty.
+	SnapshotPath string
+
+	// MaxBodyBytes is the maximum size of a request body
+	MaxBodyBytes int64
+
+	// MaxBatchSize is the maximum number of vectors in a batch insert
+	MaxBatchSize int
+
+	// MaxK and MaxEf bound the K and ef of a search request
+	MaxK  int
+	MaxEf int
+
+	// DefaultEf is the ef used by searches that do not specify one. It is
+	// raised to K when smaller.
+	DefaultEf int
+
+	// Metrics, if not nil, serves GET /metrics, such as a metrics.Exporter
+	Metrics http.Handler
+}
+
+// withDefaults returns a copy of the options with zero limits replaced by
+// the default ones
+func (o Options) withDefaults() Options {
+	if o.MaxBodyBytes <= 0 {
+		o.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if o.MaxBatchSize <= 0 {
+		o.MaxBatchSize = DefaultMaxBatchSize
+	}
+	if o.MaxK <= 0 {
+		o.MaxK = DefaultMaxK
+	}
+	if o.MaxEf <= 0 {
+		o.MaxEf = DefaultMaxEf
+	}
+	if o.DefaultEf <= 0 {
+		o.DefaultEf = DefaultEf
+	}
+	return o
+}
+
+// ErrDraining is returned by mutations received after Shutdown started.
+var ErrDraining = errors.New("server is shutting down")
+
+// Server serves an HNSW index over HTTP. Its Handler can also be mounted on
+// another mux or used with net/http/httptest.
+type Server struct {
+	index *hnsw.HNSW
+	opts  Options
+	mux   *http.ServeMux
+
+	// writeMutex serializes mutations, so that the ID assigned to an
+	// insertion is the position of the node in index.Nodes. It also guards
+	// http against a concurrent Serve and Shutdown.
+	writeMutex sync.Mutex
+
+	// draining is set when Shutdown starts
+	draining atomic.Bool
+
+	// http is the server started by Serve, if any
+	http *http.Server
+}
+
+// New returns a Server for the index. The index must not be modified
+// other than through the server while it is in use.
+func New(index *hnsw.HNSW, opts Options) *Server {
+	s := &Server{index: index, opts: opts.withDefaults(), mux: http.NewServeMux()}
+	s.routes()
+	return s
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe listens on the TCP address addr and serves the API until
+// Shutdown is called, in which case it returns http.ErrServerClosed.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve serves the API on l until Shutdown is called, in which case it
+// returns http.ErrServerClosed.
+func (s *Server) Serve(l net.Listener) error {
+	s.writeMutex.Lock()
+	if s.draining.Load() {
+		s.writeMutex.Unlock()
+		l.Close()
+		return http.ErrServerClosed
+	}
+	s.http = &http.Server{Handler: s.mux}
+	srv := s.http
+	s.writeMutex.Unlock()
+	return srv.Serve(l)
+}
+
+// Shutdown drains the server: health checks start failing, new mutations
+// are rejected with 503, and the listener is closed. Once the requests in
+// progress have completed, or ctx expires, the index is saved to
+// Options.SnapshotPath. The snapshot is written even if ctx expires, since
+// mutations are already rejected at that point.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.draining.Store(true)
+
+	s.writeMutex.Lock()
+	srv := s.http
+	s.writeMutex.Unlock()
+
+	var err error
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+
+	if s.opts.SnapshotPath != "" {
+		// Mutations in progress finish before the snapshot starts
+		s.writeMutex.Lock()
+		defer s.writeMutex.Unlock()
+		if saveErr := s.index.SaveFile(s.opts.SnapshotPath); saveErr != nil {
+			return saveErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+func TestShutdownSnapshot(t *testing.T) {
+	h, err := hnsw.NewHNSW(hnsw.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create HNSW: %v", err)
+	}
+	snapshot := filepath.Join(t.TempDir(), "index.hnsw")
+	s := New(h, Options{SnapshotPath: snapshot})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+
+	url := "http://" + l.Addr().String()
+	for i := 0; i < 10; i++ {
+		resp, err := http.Post(url+"/vectors", "application/json", strings.NewReader(`{"vector": [1, 2, 3]}`))
+		if err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Expected http.ErrServerClosed from Serve, got %v", err)
+	}
+
+	loaded, err := hnsw.LoadFile(snapshot, hnsw.EuclideanDistance)
+	if err != nil {
+		t.Fatalf("Failed to load snapshot: %v", err)
+	}
+	if loaded.Len() != 10 {
+		t.Errorf("Expected 10 nodes in the snapshot, got %d", loaded.Len())
+	}
+
+	// A stopped server does not start again
+	l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	if err := s.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Expected http.ErrServerClosed after Shutdown, got %v", err)
+	}
+}
+
+func TestDraining(t *testing.T) {
+	s, ts := newTestServer(t, Options{})
+	call(t, ts, "POST", "/vectors", insertRequest{Vector: []float32{1, 2}}, nil)
+
+	if status := call(t, ts, "GET", "/healthz", nil, nil); status != http.StatusOK {
+		t.Errorf("Expected healthy server, got status %d", status)
+	}
+
+	// Without Serve, Shutdown only drains the handler
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+	}{
+		{"health check", "GET", "/healthz", nil, http.StatusServiceUnavailable},
+		{"insert", "POST", "/vectors", insertRequest{Vector: []float32{3, 4}}, http.StatusServiceUnavailable},
+		{"delete", "DELETE", "/vectors/0", nil, http.StatusServiceUnavailable},
+		{"search", "POST", "/search", searchRequest{Vector: []float32{1, 2}, K: 1}, http.StatusOK},
+		{"get", "GET", "/vectors/0", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := call(t, ts, tt.method, tt.path, tt.body, nil); status != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, status)
+			}
+		})
+	}
+}
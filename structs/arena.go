@@ -0,0 +1,163 @@
+package structs
+
+// arenaPageSlots is the number of slots held by a single arena page.
+// Pages are never reallocated once created, so views handed out by an arena
+// stay valid for the whole lifetime of the index, and growing the index never
+// copies the vectors that are already stored.
+const arenaPageSlots = 1024
+
+// VectorArena stores fixed-dimension vectors contiguously in flat []float32
+// pages addressed by slot. Slot i occupies the range [i*dim, (i+1)*dim) of its
+// page, so the garbage collector sees one pointer-free block per page instead
+// of one small slice per vector.
+type VectorArena struct {
+	dim   int
+	pages [][]float32
+	len   int
+}
+
+// NewVectorArena creates an arena for vectors of the given dimension.
+// A dimension of 0 means that the dimension is taken from the first vector
+// appended to the arena.
+func NewVectorArena(dim int) *VectorArena {
+	return &VectorArena{dim: dim}
+}
+
+// Dim returns the dimension of the vectors stored in the arena.
+func (a *VectorArena) Dim() int {
+	return a.dim
+}
+
+// Len returns the number of slots in use.
+func (a *VectorArena) Len() int {
+	return a.len
+}
+
+// Append copies vector into the next free slot and returns a view of the
+// stored copy. The view has its capacity clipped to the vector dimension, so
+// appending to it can never overwrite the neighboring slot.
+func (a *VectorArena) Append(vector []float32) []float32 {
+	if a.dim == 0 {
+		a.dim = len(vector)
+	}
+	if len(vector) != a.dim {
+		panic("vector dimension mismatch")
+	}
+
+	page, offset := a.len/arenaPageSlots, (a.len%arenaPageSlots)*a.dim
+	if page == len(a.pages) {
+		a.pages = append(a.pages, make([]float32, arenaPageSlots*a.dim))
+	}
+	a.len++
+
+	view := a.pages[page][offset : offset+a.dim : offset+a.dim]
+	copy(view, vector)
+	return view
+}
+
+// At returns a view of the vector stored in slot.
+func (a *VectorArena) At(slot int) []float32 {
+	page, offset := slot/arenaPageSlots, (slot%arenaPageSlots)*a.dim
+	return a.pages[page][offset : offset+a.dim : offset+a.dim]
+}
+
+// LinkArena hands out fixed-stride neighbor blocks of uint32 IDs from flat
+// pages. Each block is returned as an empty slice whose capacity equals the
+// stride, so a neighbor list can grow up to its limit without reallocating.
+type LinkArena struct {
+	stride int
+	pages  [][]uint32
+	len    int
+}
+
+// NewLinkArena creates an arena of neighbor blocks holding up to stride IDs each.
+func NewLinkArena(stride int) *LinkArena {
+	return &LinkArena{stride: stride}
+}
+
+// Stride returns the capacity of every block in the arena.
+func (a *LinkArena) Stride() int {
+	return a.stride
+}
+
+// Len returns the number of blocks handed out.
+func (a *LinkArena) Len() int {
+	return a.len
+}
+
+// Append reserves the next block and returns it as an empty slice with
+// capacity stride.
+func (a *LinkArena) Append() []uint32 {
+	page, offset := a.len/arenaPageSlots, (a.len%arenaPageSlots)*a.stride
+	if page == len(a.pages) {
+		a.pages = append(a.pages, make([]uint32, arenaPageSlots*a.stride))
+	}
+	a.len++
+
+	return a.pages[page][offset : offset : offset+a.stride]
+}
+
+// Storage allocates nodes from shared arenas: vectors live in a VectorArena,
+// layer 0 neighbor lists in a LinkArena with stride mMax0, and the Node
+// headers themselves in pages of nodes. Only nodes with a level above 0 need
+// a separate allocation for their upper-layer lists, which on average is a
+// 1/M fraction of the nodes.
+type Storage struct {
+	mMax int
+
+	Vectors *VectorArena
+	Links   *LinkArena
+
+	nodes   [][]Node
+	headers [][][]uint32
+	len     int
+}
+
+// NewStorage creates a Storage for nodes with up to mMax neighbors on upper
+// layers and up to mMax0 neighbors on layer 0.
+func NewStorage(mMax, mMax0 int) *Storage {
+	return &Storage{
+		mMax:    mMax,
+		Vectors: NewVectorArena(0),
+		Links:   NewLinkArena(mMax0),
+	}
+}
+
+// Len returns the number of nodes allocated from the storage.
+func (s *Storage) Len() int {
+	return s.len
+}
+
+// NewNode allocates a node from the storage, copying vector into the vector
+// arena. It has the same semantics as NewNode, except that the node's vector
+// and neighbor lists are views into the shared arenas.
+func (s *Storage) NewNode(id int, vector []float32, level int) *Node {
+	page, offset := s.len/arenaPageSlots, s.len%arenaPageSlots
+	if page == len(s.nodes) {
+		s.nodes = append(s.nodes, make([]Node, arenaPageSlots))
+		s.headers = append(s.headers, make([][]uint32, arenaPageSlots))
+	}
+	s.len++
+
+	node := &s.nodes[page][offset]
+	node.ID = id
+	node.Vector = s.Vectors.Append(vector)
+	node.Level = level
+
+	if level == 0 {
+		// Layer 0 only: the neighbors header comes from the shared page too
+		node.Neighbors = s.headers[page][offset : offset+1 : offset+1]
+		node.Neighbors[0] = s.Links.Append()
+		return node
+	}
+
+	// Upper layers share a single block carved into fixed-stride lists
+	node.Neighbors = make([][]uint32, level+1)
+	node.Neighbors[0] = s.Links.Append()
+	upper := make([]uint32, level*s.mMax)
+	for lc := 1; lc <= level; lc++ {
+		start := (lc - 1) * s.mMax
+		node.Neighbors[lc] = upper[start : start : start+s.mMax]
+	}
+	return node
+}
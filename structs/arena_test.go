@@ -0,0 +1,133 @@
+package structs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVectorArena(t *testing.T) {
+	t.Run("Append copies and At returns the stored vector", func(t *testing.T) {
+		a := NewVectorArena(0)
+		src := []float32{1.0, 2.0, 3.0}
+		view := a.Append(src)
+
+		src[0] = 42.0
+		if view[0] != 1.0 {
+			t.Errorf("Arena should store a copy, got %v", view)
+		}
+		if a.Dim() != 3 {
+			t.Errorf("Expected dimension 3, got %d", a.Dim())
+		}
+		if !reflect.DeepEqual(a.At(0), []float32{1.0, 2.0, 3.0}) {
+			t.Errorf("At(0) = %v, want [1 2 3]", a.At(0))
+		}
+	})
+
+	t.Run("Views are clipped to the vector dimension", func(t *testing.T) {
+		a := NewVectorArena(2)
+		first := a.Append([]float32{1.0, 1.0})
+		a.Append([]float32{2.0, 2.0})
+
+		if cap(first) != 2 {
+			t.Errorf("Expected view capacity 2, got %d", cap(first))
+		}
+		_ = append(first, 9.0)
+		if a.At(1)[0] != 2.0 {
+			t.Errorf("Appending to a view overwrote the next slot: %v", a.At(1))
+		}
+	})
+
+	t.Run("Views stay valid across pages", func(t *testing.T) {
+		a := NewVectorArena(4)
+		views := make([][]float32, 0, arenaPageSlots*2+1)
+		for i := 0; i < arenaPageSlots*2+1; i++ {
+			views = append(views, a.Append([]float32{float32(i), 0, 0, float32(i)}))
+		}
+
+		if a.Len() != len(views) {
+			t.Errorf("Expected %d slots, got %d", len(views), a.Len())
+		}
+		for i, v := range views {
+			if v[0] != float32(i) || a.At(i)[3] != float32(i) {
+				t.Fatalf("Slot %d holds %v, want %d", i, v, i)
+			}
+		}
+	})
+
+	t.Run("Dimension mismatch panics", func(t *testing.T) {
+		a := NewVectorArena(2)
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Append with wrong dimension should panic")
+			}
+		}()
+		a.Append([]float32{1.0, 2.0, 3.0})
+	})
+}
+
+func TestLinkArena(t *testing.T) {
+	a := NewLinkArena(3)
+	first := a.Append()
+	second := a.Append()
+
+	if len(first) != 0 || cap(first) != 3 {
+		t.Errorf("Expected empty block with capacity 3, got len %d cap %d", len(first), cap(first))
+	}
+
+	first = append(first, 1, 2, 3)
+	second = append(second, 4)
+	if !reflect.DeepEqual(first, []uint32{1, 2, 3}) || !reflect.DeepEqual(second, []uint32{4}) {
+		t.Errorf("Blocks overlap: first %v, second %v", first, second)
+	}
+	if a.Len() != 2 || a.Stride() != 3 {
+		t.Errorf("Expected 2 blocks of stride 3, got %d of stride %d", a.Len(), a.Stride())
+	}
+}
+
+func TestStorageNewNode(t *testing.T) {
+	s := NewStorage(2, 4)
+
+	tests := []struct {
+		id    int
+		level int
+	}{
+		{0, 0},
+		{1, 2},
+		{2, 0},
+	}
+
+	for _, tt := range tests {
+		node := s.NewNode(tt.id, []float32{float32(tt.id), 1.0}, tt.level)
+
+		if node.ID != tt.id || node.Level != tt.level {
+			t.Errorf("Node %d: got ID %d level %d", tt.id, node.ID, node.Level)
+		}
+		if len(node.Neighbors) != tt.level+1 {
+			t.Fatalf("Node %d: expected %d neighbor lists, got %d", tt.id, tt.level+1, len(node.Neighbors))
+		}
+		if cap(node.Neighbors[0]) != 4 {
+			t.Errorf("Node %d: expected level 0 capacity 4, got %d", tt.id, cap(node.Neighbors[0]))
+		}
+		for lc := 1; lc <= tt.level; lc++ {
+			if len(node.Neighbors[lc]) != 0 || cap(node.Neighbors[lc]) != 2 {
+				t.Errorf("Node %d level %d: expected empty list with capacity 2, got len %d cap %d",
+					tt.id, lc, len(node.Neighbors[lc]), cap(node.Neighbors[lc]))
+			}
+		}
+	}
+
+	// Upper layer lists of the same node must not overlap
+	node := s.NewNode(3, []float32{3.0, 1.0}, 2)
+	node.Neighbors[1] = append(node.Neighbors[1], 10, 11)
+	node.Neighbors[2] = append(node.Neighbors[2], 20)
+	if !reflect.DeepEqual(node.Neighbors[1], []uint32{10, 11}) {
+		t.Errorf("Level 1 list was overwritten: %v", node.Neighbors[1])
+	}
+
+	if s.Len() != 4 {
+		t.Errorf("Expected 4 nodes, got %d", s.Len())
+	}
+	if !reflect.DeepEqual(s.Vectors.At(3), []float32{3.0, 1.0}) {
+		t.Errorf("Vector of node 3 = %v, want [3 1]", s.Vectors.At(3))
+	}
+}
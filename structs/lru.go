@@ -0,0 +1,87 @@
+package structs
+
+import (
+	"container/list"
+	"sync"
+)
+
+// VectorCache is a bounded least-recently-used cache of vectors keyed by
+// node ID. It is safe for concurrent use by multiple goroutines.
+type VectorCache struct {
+	mutex    sync.Mutex
+	capacity int
+	items    map[int]*list.Element
+	order    *list.List
+
+	hits   uint64
+	misses uint64
+}
+
+// cacheEntry is the value stored in the recency list
+type cacheEntry struct {
+	id     int
+	vector []float32
+}
+
+// NewVectorCache creates a cache holding at most capacity vectors.
+// A capacity of 0 disables caching.
+func NewVectorCache(capacity int) *VectorCache {
+	return &VectorCache{
+		capacity: capacity,
+		items:    make(map[int]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// Get returns the vector cached for id, marking it as recently used.
+func (c *VectorCache) Get(id int) ([]float32, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.items[id]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).vector, true
+}
+
+// Put stores the vector for id, evicting the least recently used entry
+// when the cache is full.
+func (c *VectorCache) Put(id int, vector []float32) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.items[id]; ok {
+		elem.Value.(*cacheEntry).vector = vector
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).id)
+	}
+	c.items[id] = c.order.PushFront(&cacheEntry{id: id, vector: vector})
+}
+
+// Len returns the number of cached vectors.
+func (c *VectorCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *VectorCache) Stats() (hits, misses uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.hits, c.misses
+}
@@ -0,0 +1,64 @@
+package structs
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestVectorCache(t *testing.T) {
+	c := NewVectorCache(2)
+
+	c.Put(1, []float32{1.0})
+	c.Put(2, []float32{2.0})
+
+	// Touch 1 so that 2 becomes the least recently used entry
+	if v, ok := c.Get(1); !ok || v[0] != 1.0 {
+		t.Fatalf("Expected cached vector for 1, got %v %v", v, ok)
+	}
+
+	c.Put(3, []float32{3.0})
+	if _, ok := c.Get(2); ok {
+		t.Errorf("Entry 2 should have been evicted")
+	}
+	if _, ok := c.Get(1); !ok {
+		t.Errorf("Entry 1 should still be cached")
+	}
+	if _, ok := c.Get(3); !ok {
+		t.Errorf("Entry 3 should be cached")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+
+	hits, misses := c.Stats()
+	if hits != 3 || misses != 1 {
+		t.Errorf("Expected 3 hits and 1 miss, got %d and %d", hits, misses)
+	}
+
+	t.Run("Zero capacity disables caching", func(t *testing.T) {
+		c := NewVectorCache(0)
+		c.Put(1, []float32{1.0})
+		if _, ok := c.Get(1); ok || c.Len() != 0 {
+			t.Errorf("Expected an empty cache")
+		}
+	})
+
+	t.Run("Concurrent access", func(t *testing.T) {
+		c := NewVectorCache(16)
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					c.Put(g*100+i, []float32{float32(i)})
+					c.Get(g*100 + i/2)
+				}
+			}(g)
+		}
+		wg.Wait()
+		if c.Len() > 16 {
+			t.Errorf("Cache exceeded its capacity: %d entries", c.Len())
+		}
+	})
+}
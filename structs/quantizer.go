@@ -0,0 +1,87 @@
+package structs
+
+// ScalarQuantizer compresses float32 vectors to one byte per dimension.
+// Each dimension is mapped linearly from its [min, max] range, learned from
+// the training vectors, onto the 256 values of a uint8. Values outside the
+// range are clamped.
+type ScalarQuantizer struct {
+	// Min holds the smallest value seen for each dimension
+	Min []float32
+
+	// Scale holds the width of a quantization step for each dimension
+	Scale []float32
+}
+
+// NewScalarQuantizer creates an untrained quantizer for vectors of dimension dim.
+func NewScalarQuantizer(dim int) *ScalarQuantizer {
+	return &ScalarQuantizer{
+		Min:   make([]float32, dim),
+		Scale: make([]float32, dim),
+	}
+}
+
+// Trainer returns a function that feeds vectors to the quantizer one at a
+// time, so training can stream over data that does not fit in memory.
+// Call the returned finish function once every vector has been observed.
+func (q *ScalarQuantizer) Trainer() (observe func([]float32), finish func()) {
+	dim := len(q.Min)
+	lo := make([]float32, dim)
+	hi := make([]float32, dim)
+	first := true
+
+	observe = func(vector []float32) {
+		if first {
+			copy(lo, vector)
+			copy(hi, vector)
+			first = false
+			return
+		}
+		for i, v := range vector {
+			lo[i] = min(lo[i], v)
+			hi[i] = max(hi[i], v)
+		}
+	}
+
+	finish = func() {
+		for i := range q.Min {
+			q.Min[i] = lo[i]
+			q.Scale[i] = (hi[i] - lo[i]) / 255
+		}
+	}
+	return observe, finish
+}
+
+// Train learns the per-dimension ranges from vectors.
+func (q *ScalarQuantizer) Train(vectors [][]float32) {
+	observe, finish := q.Trainer()
+	for _, v := range vectors {
+		observe(v)
+	}
+	finish()
+}
+
+// Encode quantizes vector into dst, which must have the quantizer's dimension.
+func (q *ScalarQuantizer) Encode(dst []uint8, vector []float32) {
+	for i, v := range vector {
+		if q.Scale[i] == 0 {
+			dst[i] = 0
+			continue
+		}
+		code := (v-q.Min[i])/q.Scale[i] + 0.5
+		switch {
+		case code <= 0:
+			dst[i] = 0
+		case code >= 255:
+			dst[i] = 255
+		default:
+			dst[i] = uint8(code)
+		}
+	}
+}
+
+// Decode reconstructs an approximation of the original vector into dst.
+func (q *ScalarQuantizer) Decode(dst []float32, code []uint8) {
+	for i, c := range code {
+		dst[i] = q.Min[i] + float32(c)*q.Scale[i]
+	}
+}
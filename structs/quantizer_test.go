@@ -0,0 +1,45 @@
+package structs
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScalarQuantizer(t *testing.T) {
+	vectors := [][]float32{
+		{0.0, -1.0, 5.0},
+		{1.0, 1.0, 5.0},
+		{0.5, 0.0, 5.0},
+	}
+
+	q := NewScalarQuantizer(3)
+	q.Train(vectors)
+
+	if q.Min[0] != 0.0 || q.Min[1] != -1.0 || q.Min[2] != 5.0 {
+		t.Errorf("Unexpected minimums %v", q.Min)
+	}
+
+	code := make([]uint8, 3)
+	decoded := make([]float32, 3)
+	for _, v := range vectors {
+		q.Encode(code, v)
+		q.Decode(decoded, code)
+		for i := range v {
+			// Reconstruction error is at most half a quantization step
+			if diff := math.Abs(float64(decoded[i] - v[i])); diff > float64(q.Scale[i])/2+1e-6 {
+				t.Errorf("Decode(Encode(%v)) = %v, error %f too large", v, decoded, diff)
+			}
+		}
+	}
+
+	t.Run("Range endpoints and clamping", func(t *testing.T) {
+		q.Encode(code, []float32{0.0, 1.0, 5.0})
+		if code[0] != 0 || code[1] != 255 || code[2] != 0 {
+			t.Errorf("Expected codes [0 255 0], got %v", code)
+		}
+		q.Encode(code, []float32{-10.0, 10.0, 5.0})
+		if code[0] != 0 || code[1] != 255 {
+			t.Errorf("Out of range values should be clamped, got %v", code)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+// File names inside the data directory of a DurableIndex
+const (
+	SnapshotFile = "index.hnsw"
+	LogFile      = "wal.log"
+)
+
+// DurableIndex is an HNSW index whose mutations are recorded in a
+// write-ahead log before being applied. Its data directory holds the latest
+// snapshot and the log of the operations performed since.
+//
+// Mutations are serialized by the DurableIndex so that the log and the graph
+// see them in the same order. Searches go straight to the underlying index.
+type DurableIndex struct {
+	mutex sync.Mutex
+	dir   string
+	index *hnsw.HNSW
+	log   *Log
+}
+
+// OpenDurable opens the durable index stored in dir, creating the directory
+// if needed. The latest snapshot is loaded, or an empty index is created from
+// cfg if there is none, and the log is replayed on top of it.
+//
+// cfg.DistanceFunc is always used; the other fields of cfg only matter when
+// no snapshot exists yet.
+func OpenDurable(dir string, cfg hnsw.Config, opts Options) (*DurableIndex, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	index, err := hnsw.LoadFile(filepath.Join(dir, SnapshotFile), cfg.DistanceFunc)
+	if errors.Is(err, os.ErrNotExist) {
+		index, err = hnsw.NewHNSW(cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log, err := Open(filepath.Join(dir, LogFile), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DurableIndex{dir: dir, index: index, log: log}
+	if err := log.Replay(d.replay); err != nil {
+		log.Close()
+		return nil, fmt.Errorf("wal: replay failed: %w", err)
+	}
+	return d, nil
+}
+
+// replay applies a logged record to the index.
+//
+// A crash between writing a snapshot and resetting the log leaves records in
+// the log that are already part of the snapshot, so replay must be
+// idempotent: inserts of IDs already present and operations on nodes already
+// deleted are skipped, and updates simply write the same vector again.
+func (d *DurableIndex) replay(rec Record) error {
+	switch rec.Op {
+	case OpInsert:
+		if rec.ID < len(d.index.Nodes) {
+			return nil
+		}
+		if err := d.validateID(rec.ID); err != nil {
+			return err
+		}
+		if err := d.validateVector(rec.Vector); err != nil {
+			return err
+		}
+		d.index.Insert(rec.Vector, rec.ID)
+		return nil
+	case OpDelete:
+		if d.index.IsDeleted(rec.ID) {
+			return nil
+		}
+		return d.index.Delete(rec.ID)
+	case OpUpdate:
+		// Only a later delete, already in the snapshot, can explain this
+		if d.index.IsDeleted(rec.ID) {
+			return nil
+		}
+		return d.index.Update(rec.ID, rec.Vector)
+	}
+	return fmt.Errorf("unknown operation %v", rec.Op)
+}
+
+// Index returns the underlying index, to be used for searches. It must not
+// be mutated directly, or the changes would not be logged.
+func (d *DurableIndex) Index() *hnsw.HNSW {
+	return d.index
+}
+
+// Insert logs and then applies an insertion. As with HNSW.Insert, id must
+// be the number of nodes inserted before, deleted ones included.
+func (d *DurableIndex) Insert(vector []float32, id int) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if err := d.validateID(id); err != nil {
+		return err
+	}
+	if err := d.validateVector(vector); err != nil {
+		return err
+	}
+	if err := d.log.Append(Record{Op: OpInsert, ID: id, Vector: vector}); err != nil {
+		return err
+	}
+	d.index.Insert(vector, id)
+	return nil
+}
+
+// Delete logs and then applies a deletion.
+func (d *DurableIndex) Delete(id int) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if id < 0 || id >= len(d.index.Nodes) || d.index.IsDeleted(id) {
+		return hnsw.ErrNodeNotFound
+	}
+	if err := d.log.Append(Record{Op: OpDelete, ID: id}); err != nil {
+		return err
+	}
+	return d.index.Delete(id)
+}
+
+// Update logs and then applies a vector update.
+func (d *DurableIndex) Update(id int, vector []float32) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if id < 0 || id >= len(d.index.Nodes) || d.index.IsDeleted(id) {
+		return hnsw.ErrNodeNotFound
+	}
+	if err := d.validateVector(vector); err != nil {
+		return err
+	}
+	if err := d.log.Append(Record{Op: OpUpdate, ID: id, Vector: vector}); err != nil {
+		return err
+	}
+	return d.index.Update(id, vector)
+}
+
+// validateID rejects insertions whose ID is not the next node ID, which
+// replay could not tell apart from records already in the snapshot
+func (d *DurableIndex) validateID(id int) error {
+	if next := len(d.index.Nodes); id != next {
+		return fmt.Errorf("id %d inserted as node %d", id, next)
+	}
+	return nil
+}
+
+// validateVector rejects vectors the index would panic on, before they are logged
+func (d *DurableIndex) validateVector(vector []float32) error {
+	if len(vector) == 0 {
+		return errors.New("vector cannot be empty")
+	}
+	if dim := d.index.Dim(); dim != 0 && len(vector) != dim {
+		return errors.New("vector dimension mismatch")
+	}
+	return nil
+}
+
+// Snapshot writes the index to the data directory and empties the log.
+// Mutations wait for the snapshot to complete; searches do not.
+func (d *DurableIndex) Snapshot() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if err := d.index.SaveFile(filepath.Join(d.dir, SnapshotFile)); err != nil {
+		return err
+	}
+	return d.log.Reset()
+}
+
+// Sync flushes the log to stable storage, regardless of the sync policy.
+func (d *DurableIndex) Sync() error {
+	return d.log.Sync()
+}
+
+// Close syncs and closes the log. It does not take a snapshot.
+func (d *DurableIndex) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.log.Close()
+}
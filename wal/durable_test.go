@@ -0,0 +1,246 @@
+package wal
+
+import (
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"dmarro89.github.com/hnsw-go/hnsw"
+)
+
+func testConfig() hnsw.Config {
+	return hnsw.Config{
+		M:              8,
+		Mmax:           8,
+		Mmax0:          16,
+		EfConstruction: 32,
+		MaxLevel:       4,
+		DistanceFunc:   hnsw.EuclideanDistance,
+	}
+}
+
+// populate performs a deterministic mix of inserts, updates and deletes
+func populate(t *testing.T, d *DurableIndex, from, to int) {
+	t.Helper()
+	rng := rand.New(rand.NewPCG(uint64(from), uint64(to)))
+	for i := from; i < to; i++ {
+		if err := d.Insert([]float32{rng.Float32(), rng.Float32(), rng.Float32()}, i); err != nil {
+			t.Fatalf("Insert(%d) failed: %v", i, err)
+		}
+		if i%7 == 3 {
+			if err := d.Update(i-1, []float32{rng.Float32(), rng.Float32(), rng.Float32()}); err != nil {
+				t.Fatalf("Update(%d) failed: %v", i-1, err)
+			}
+		}
+		if i%10 == 5 {
+			if err := d.Delete(i - 2); err != nil {
+				t.Fatalf("Delete(%d) failed: %v", i-2, err)
+			}
+		}
+	}
+}
+
+// assertSameIndex checks that two indexes hold the same vectors and deletions
+func assertSameIndex(t *testing.T, want, got *hnsw.HNSW) {
+	t.Helper()
+	if len(got.Nodes) != len(want.Nodes) {
+		t.Fatalf("Expected %d nodes, got %d", len(want.Nodes), len(got.Nodes))
+	}
+	for i := range want.Nodes {
+		if !reflect.DeepEqual(got.Nodes[i].Vector, want.Nodes[i].Vector) {
+			t.Errorf("Node %d: expected vector %v, got %v", i, want.Nodes[i].Vector, got.Nodes[i].Vector)
+		}
+		if got.Nodes[i].Deleted != want.Nodes[i].Deleted {
+			t.Errorf("Node %d: expected deleted=%v", i, want.Nodes[i].Deleted)
+		}
+	}
+}
+
+// TestDurableReplay verifies that reopening a durable index replays the log
+func TestDurableReplay(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDurable(dir, testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	populate(t, d, 0, 100)
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, err := OpenDurable(dir, testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	defer reopened.Close()
+	assertSameIndex(t, d.Index(), reopened.Index())
+
+	query := []float32{0.5, 0.5, 0.5}
+	if got := reopened.Index().KNN_Search(query, 5, 32); len(got) != 5 {
+		t.Errorf("Expected 5 results, got %v", got)
+	}
+}
+
+// TestDurableSnapshot verifies that the log is replayed on top of the latest snapshot
+func TestDurableSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDurable(dir, testConfig(), Options{Sync: SyncBatch, BatchSize: 16})
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	populate(t, d, 0, 60)
+	if err := d.Snapshot(); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if d.log.Size() != 0 {
+		t.Errorf("Expected an empty log after snapshot, got %d bytes", d.log.Size())
+	}
+	populate(t, d, 60, 120)
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, err := OpenDurable(dir, testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	defer reopened.Close()
+	assertSameIndex(t, d.Index(), reopened.Index())
+}
+
+// TestDurableCrashAfterSnapshot simulates a crash between writing a snapshot
+// and resetting the log: the stale records must be skipped on replay
+func TestDurableCrashAfterSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDurable(dir, testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	populate(t, d, 0, 50)
+
+	// Keep a copy of the log as it was before the snapshot reset it
+	stale := copyFile(t, filepath.Join(dir, LogFile))
+	if err := d.Snapshot(); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	d.Close()
+	if err := os.WriteFile(filepath.Join(dir, LogFile), stale, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	reopened, err := OpenDurable(dir, testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	defer reopened.Close()
+	assertSameIndex(t, d.Index(), reopened.Index())
+}
+
+// TestDurableTornTail simulates a crash in the middle of an append
+func TestDurableTornTail(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDurable(dir, testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	populate(t, d, 0, 20)
+	d.Close()
+
+	// Append half of an insert record
+	frame := encode(Record{Op: OpInsert, ID: 20, Vector: []float32{1, 2, 3}})
+	f, err := os.OpenFile(filepath.Join(dir, LogFile), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	f.Write(frame[:len(frame)/2])
+	f.Close()
+
+	reopened, err := OpenDurable(dir, testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	defer reopened.Close()
+	assertSameIndex(t, d.Index(), reopened.Index())
+
+	if err := reopened.Insert([]float32{1, 2, 3}, 20); err != nil {
+		t.Fatalf("Insert after recovery failed: %v", err)
+	}
+}
+
+// TestDurableValidation verifies that invalid operations are rejected before being logged
+func TestDurableValidation(t *testing.T) {
+	d, err := OpenDurable(t.TempDir(), testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	defer d.Close()
+
+	if err := d.Insert([]float32{1, 2, 3}, 0); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	size := d.log.Size()
+
+	if err := d.Insert(nil, 1); err == nil {
+		t.Errorf("Insert of an empty vector should fail")
+	}
+	if err := d.Insert([]float32{1, 2}, 1); err == nil {
+		t.Errorf("Insert with wrong dimension should fail")
+	}
+	for _, id := range []int{0, 7, -1} {
+		if err := d.Insert([]float32{1, 2, 3}, id); err == nil {
+			t.Errorf("Insert with id %d should fail", id)
+		}
+	}
+	if err := d.Delete(5); err != hnsw.ErrNodeNotFound {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+	if err := d.Update(5, []float32{1, 2, 3}); err != hnsw.ErrNodeNotFound {
+		t.Errorf("Expected ErrNodeNotFound, got %v", err)
+	}
+	if d.log.Size() != size {
+		t.Errorf("Rejected operations must not be logged")
+	}
+}
+
+// TestDurableNonSequentialID verifies that rejected IDs leave the live and
+// the recovered index in agreement
+func TestDurableNonSequentialID(t *testing.T) {
+	dir := t.TempDir()
+	d, err := OpenDurable(dir, testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	for _, id := range []int{0, 0, 7, 1} {
+		d.Insert([]float32{float32(id), 0, 0}, id)
+	}
+	if got := d.Index().Len(); got != 2 {
+		t.Errorf("Expected 2 nodes, got %d", got)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, err := OpenDurable(dir, testConfig(), DefaultOptions())
+	if err != nil {
+		t.Fatalf("OpenDurable failed: %v", err)
+	}
+	defer reopened.Close()
+	assertSameIndex(t, d.Index(), reopened.Index())
+}
+
+func copyFile(t *testing.T, path string) []byte {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	return data
+}
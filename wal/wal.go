@@ -0,0 +1,416 @@
+// Package wal implements an append-only write-ahead log for HNSW indexes.
+//
+// Every Insert, Delete and Update is recorded in the log before it is applied
+// to the graph, so that after a crash the operations performed since the
+// latest snapshot can be replayed on top of it.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// Op identifies the operation stored in a Record.
+type Op uint8
+
+const (
+	// OpInsert records HNSW.Insert(Vector, ID)
+	OpInsert Op = iota + 1
+	// OpDelete records HNSW.Delete(ID)
+	OpDelete
+	// OpUpdate records HNSW.Update(ID, Vector)
+	OpUpdate
+)
+
+// String returns the name of the operation.
+func (op Op) String() string {
+	switch op {
+	case OpInsert:
+		return "insert"
+	case OpDelete:
+		return "delete"
+	case OpUpdate:
+		return "update"
+	}
+	return fmt.Sprintf("op(%d)", uint8(op))
+}
+
+// Record is a single logged operation.
+type Record struct {
+	Op     Op
+	ID     int
+	Vector []float32
+}
+
+// SyncPolicy controls when appended records are flushed to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways calls fsync after every record. No acknowledged operation
+	// is ever lost, at the cost of one fsync per operation.
+	SyncAlways SyncPolicy = iota
+
+	// SyncBatch calls fsync once every BatchSize records. A crash can lose
+	// up to BatchSize-1 acknowledged operations.
+	SyncBatch
+
+	// SyncInterval calls fsync in the background every Interval. A crash can
+	// lose the operations acknowledged during the last interval.
+	SyncInterval
+)
+
+// Options configures a Log.
+type Options struct {
+	// Sync is the fsync policy
+	Sync SyncPolicy
+
+	// BatchSize is the number of records per fsync with SyncBatch
+	BatchSize int
+
+	// Interval is the time between two fsyncs with SyncInterval
+	Interval time.Duration
+}
+
+// DefaultOptions returns Options with an fsync after every record.
+func DefaultOptions() Options {
+	return Options{
+		Sync:      SyncAlways,
+		BatchSize: 128,
+		Interval:  100 * time.Millisecond,
+	}
+}
+
+// ErrClosed is returned when appending to a closed log.
+var ErrClosed = errors.New("wal: log is closed")
+
+// Record framing: a header with the payload length and its CRC-32C,
+// followed by the payload (op, ID, dimension, vector).
+const (
+	frameHeaderSize = 8
+	payloadFixed    = 1 + 8 + 4
+
+	// maxPayload bounds the size of a single record, so that a corrupted
+	// length can never trigger a huge allocation
+	maxPayload = payloadFixed + 4*(1<<24)
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Log is an append-only write-ahead log stored in a single file.
+// It is safe for concurrent use by multiple goroutines.
+type Log struct {
+	mutex   sync.Mutex
+	file    *os.File
+	opts    Options
+	size    int64
+	pending int
+	closed  bool
+
+	// Background fsync for SyncInterval
+	stop chan struct{}
+	done chan struct{}
+	err  error
+}
+
+// Open opens the log at path, creating it if needed.
+//
+// A crash can leave a partially written record at the end of the log. Open
+// scans the log and truncates it after the last complete record whose
+// checksum matches, so appends always start from a clean tail. Everything
+// after the first damaged record is discarded.
+func Open(path string, opts Options) (*Log, error) {
+	if err := validateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := scan(f, nil)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	l := &Log{file: f, opts: opts, size: size}
+	if opts.Sync == SyncInterval {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.syncLoop()
+	}
+	return l, nil
+}
+
+func validateOptions(opts Options) error {
+	switch opts.Sync {
+	case SyncAlways:
+	case SyncBatch:
+		if opts.BatchSize <= 0 {
+			return errors.New("wal: BatchSize must be positive")
+		}
+	case SyncInterval:
+		if opts.Interval <= 0 {
+			return errors.New("wal: Interval must be positive")
+		}
+	default:
+		return fmt.Errorf("wal: unknown sync policy %d", opts.Sync)
+	}
+	return nil
+}
+
+// Replay calls fn for every record in the log, in the order they were
+// appended. It stops at the first error returned by fn.
+func (l *Log) Replay(fn func(Record) error) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := scan(io.LimitReader(l.file, l.size), fn)
+	if _, seekErr := l.file.Seek(l.size, io.SeekStart); err == nil {
+		err = seekErr
+	}
+	return err
+}
+
+// Append writes a record to the log and syncs it according to the policy.
+// The record is durable (for SyncAlways) or at least handed to the operating
+// system (for the other policies) when Append returns.
+func (l *Log) Append(rec Record) error {
+	frame := encode(rec)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	if l.err != nil {
+		return l.err
+	}
+
+	if _, err := l.file.Write(frame); err != nil {
+		// Drop the partial frame so that the next append starts clean
+		l.file.Truncate(l.size)
+		l.file.Seek(l.size, io.SeekStart)
+		return err
+	}
+	l.size += int64(len(frame))
+	l.pending++
+
+	switch l.opts.Sync {
+	case SyncAlways:
+		return l.syncLocked()
+	case SyncBatch:
+		if l.pending >= l.opts.BatchSize {
+			return l.syncLocked()
+		}
+	}
+	return nil
+}
+
+// Sync flushes every appended record to stable storage.
+func (l *Log) Sync() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	return l.syncLocked()
+}
+
+func (l *Log) syncLocked() error {
+	if l.pending == 0 {
+		return nil
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.pending = 0
+	return nil
+}
+
+// syncLoop periodically syncs the log for SyncInterval
+func (l *Log) syncLoop() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mutex.Lock()
+			if err := l.syncLocked(); err != nil && l.err == nil {
+				// Fail the following appends rather than losing data silently
+				l.err = err
+			}
+			l.mutex.Unlock()
+		}
+	}
+}
+
+// Reset empties the log. It is called once the operations it contains are
+// included in a snapshot.
+func (l *Log) Reset() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	l.size = 0
+	l.pending = 1 // force the truncation to be synced
+	return l.syncLocked()
+}
+
+// Size returns the size of the log in bytes.
+func (l *Log) Size() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.size
+}
+
+// Close syncs and closes the log.
+func (l *Log) Close() error {
+	if l.stop != nil {
+		l.mutex.Lock()
+		alreadyClosed := l.closed
+		l.mutex.Unlock()
+		if !alreadyClosed {
+			close(l.stop)
+			<-l.done
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+
+	err := l.syncLocked()
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// encode frames a record
+func encode(rec Record) []byte {
+	frame := make([]byte, frameHeaderSize+payloadFixed+4*len(rec.Vector))
+	payload := frame[frameHeaderSize:]
+
+	payload[0] = byte(rec.Op)
+	binary.LittleEndian.PutUint64(payload[1:], uint64(rec.ID))
+	binary.LittleEndian.PutUint32(payload[9:], uint32(len(rec.Vector)))
+	for i, v := range rec.Vector {
+		binary.LittleEndian.PutUint32(payload[payloadFixed+4*i:], math.Float32bits(v))
+	}
+
+	binary.LittleEndian.PutUint32(frame[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:], crc32.Checksum(payload, crcTable))
+	return frame
+}
+
+// decode parses a payload whose checksum has already been verified
+func decode(payload []byte) (Record, bool) {
+	if len(payload) < payloadFixed {
+		return Record{}, false
+	}
+
+	rec := Record{
+		Op: Op(payload[0]),
+		ID: int(binary.LittleEndian.Uint64(payload[1:])),
+	}
+	dim := int(binary.LittleEndian.Uint32(payload[9:]))
+	if rec.Op < OpInsert || rec.Op > OpUpdate || len(payload) != payloadFixed+4*dim {
+		return Record{}, false
+	}
+	if dim > 0 {
+		rec.Vector = make([]float32, dim)
+		for i := range rec.Vector {
+			rec.Vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(payload[payloadFixed+4*i:]))
+		}
+	}
+	return rec, true
+}
+
+// scan reads records from r until the end of the valid prefix, calling fn
+// (if not nil) for each of them. It returns the length of the valid prefix.
+// A short or damaged record ends the scan without an error: it can only be
+// the torn tail of an interrupted write.
+func scan(r io.Reader, fn func(Record) error) (int64, error) {
+	br := bufio.NewReader(r)
+	var size int64
+	header := make([]byte, frameHeaderSize)
+	var payload []byte
+
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return size, nil
+			}
+			return size, err
+		}
+
+		n := binary.LittleEndian.Uint32(header[0:])
+		if n < payloadFixed || n > maxPayload {
+			return size, nil
+		}
+		if cap(payload) < int(n) {
+			payload = make([]byte, n)
+		}
+		payload = payload[:n]
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return size, nil
+			}
+			return size, err
+		}
+		if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+			return size, nil
+		}
+
+		rec, ok := decode(payload)
+		if !ok {
+			return size, nil
+		}
+		if fn != nil {
+			if err := fn(rec); err != nil {
+				return size, err
+			}
+		}
+		size += int64(frameHeaderSize + len(payload))
+	}
+}
@@ -0,0 +1,219 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, l *Log) []Record {
+	t.Helper()
+	var records []Record
+	if err := l.Replay(func(rec Record) error {
+		records = append(records, rec)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	return records
+}
+
+var testRecords = []Record{
+	{Op: OpInsert, ID: 0, Vector: []float32{1.0, 2.0}},
+	{Op: OpInsert, ID: 1, Vector: []float32{3.0, 4.0}},
+	{Op: OpUpdate, ID: 0, Vector: []float32{5.0, 6.0}},
+	{Op: OpDelete, ID: 1},
+}
+
+// TestLogAppendReplay verifies that appended records are replayed in order,
+// also after reopening the log
+func TestLogAppendReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+
+	l, err := Open(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for _, rec := range testRecords {
+		if err := l.Append(rec); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if got := readAll(t, l); !reflect.DeepEqual(got, testRecords) {
+		t.Errorf("Expected %v, got %v", testRecords, got)
+	}
+
+	// Appends after a replay go to the end of the log
+	extra := Record{Op: OpInsert, ID: 2, Vector: []float32{7.0, 8.0}}
+	if err := l.Append(extra); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := l.Append(extra); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+
+	l, err = Open(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer l.Close()
+
+	want := append(append([]Record{}, testRecords...), extra)
+	if got := readAll(t, l); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+// TestLogTornTail verifies that a partially written or damaged last record
+// is discarded and that appends continue after the last good record
+func TestLogTornTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wal.log")
+
+	l, err := Open(path, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	for _, rec := range testRecords {
+		if err := l.Append(rec); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	goodSize := l.Size()
+	l.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	lastFrame := len(encode(testRecords[len(testRecords)-1]))
+
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)-1] ^= 0xff
+
+	tests := []struct {
+		name string
+		data []byte
+		want []Record
+	}{
+		{"truncated header", append(append([]byte{}, data...), 0x10, 0x00), testRecords},
+		{"truncated payload", data[:len(data)-3], testRecords[:len(testRecords)-1]},
+		{"checksum mismatch", corrupted, testRecords[:len(testRecords)-1]},
+		{"garbage length", append(append([]byte{}, data...), 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0), testRecords},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".log")
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+
+			l, err := Open(path, DefaultOptions())
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer l.Close()
+
+			if got := readAll(t, l); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %d records, got %v", len(tt.want), got)
+			}
+
+			wantSize := goodSize
+			if len(tt.want) < len(testRecords) {
+				wantSize -= int64(lastFrame)
+			}
+			if l.Size() != wantSize {
+				t.Errorf("Expected the log to be truncated to %d bytes, got %d", wantSize, l.Size())
+			}
+
+			extra := Record{Op: OpDelete, ID: 9}
+			if err := l.Append(extra); err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+			got := readAll(t, l)
+			if len(got) != len(tt.want)+1 || !reflect.DeepEqual(got[len(got)-1], extra) {
+				t.Errorf("Append after recovery not replayed correctly: %v", got)
+			}
+		})
+	}
+}
+
+// TestLogSyncPolicies verifies that every policy accepts appends and replays them
+func TestLogSyncPolicies(t *testing.T) {
+	policies := []struct {
+		name string
+		opts Options
+	}{
+		{"always", Options{Sync: SyncAlways}},
+		{"batch", Options{Sync: SyncBatch, BatchSize: 3}},
+		{"interval", Options{Sync: SyncInterval, Interval: time.Millisecond}},
+	}
+
+	for _, p := range policies {
+		t.Run(p.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wal.log")
+			l, err := Open(path, p.opts)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			for _, rec := range testRecords {
+				if err := l.Append(rec); err != nil {
+					t.Fatalf("Append failed: %v", err)
+				}
+			}
+			if p.opts.Sync == SyncInterval {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err := l.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			l, err = Open(path, p.opts)
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+			defer l.Close()
+			if got := readAll(t, l); !reflect.DeepEqual(got, testRecords) {
+				t.Errorf("Expected %v, got %v", testRecords, got)
+			}
+		})
+	}
+
+	invalid := []Options{
+		{Sync: SyncBatch},
+		{Sync: SyncInterval},
+		{Sync: SyncPolicy(42)},
+	}
+	for _, opts := range invalid {
+		if _, err := Open(filepath.Join(t.TempDir(), "wal.log"), opts); err == nil {
+			t.Errorf("Expected an error for options %+v", opts)
+		}
+	}
+}
+
+// TestLogReset verifies that Reset empties the log
+func TestLogReset(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "wal.log"), DefaultOptions())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer l.Close()
+
+	for _, rec := range testRecords {
+		if err := l.Append(rec); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	if err := l.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if got := readAll(t, l); len(got) != 0 || l.Size() != 0 {
+		t.Errorf("Expected an empty log, got %d records and %d bytes", len(got), l.Size())
+	}
+}